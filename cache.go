@@ -0,0 +1,207 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+)
+
+// cacheVersion is the on-disk format version of the metadata cache.
+// Bump it whenever TaskMetadata or the parsing rules change so that
+// stale entries from an older build are thrown away instead of reused.
+const cacheVersion = 7
+
+// cacheEntry holds the parsed metadata for a single file, along with the
+// size and modification time it had when it was parsed
+type cacheEntry struct {
+	Size     int64        `json:"size"`
+	ModTime  int64        `json:"mod_time"` // UnixNano
+	Metadata TaskMetadata `json:"metadata"`
+}
+
+// cacheFile is the layout of the cache file on disk
+type cacheFile struct {
+	Version int                   `json:"version"`
+	Entries map[string]cacheEntry `json:"entries"` // Keyed by absolute file path
+}
+
+// metadataCache remembers parsed frontmatter between runs so that
+// unchanged files don't need to be opened and parsed again.
+// A nil *metadataCache is valid and simply parses every file.
+type metadataCache struct {
+	path    string                // Where the cache is stored on disk
+	entries map[string]cacheEntry // Cached metadata keyed by absolute path
+	seen    map[string]bool       // Paths looked up since the last save
+	dirty   bool                  // Whether entries changed since loading
+}
+
+// getCachePath returns the path to the metadata cache file
+func getCachePath() (string, error) {
+	var cacheDir string
+
+	// Follow the same layout as the config file: XDG on Unix-like
+	// systems (macOS and Linux), the standard location on Windows
+	if runtime.GOOS == "windows" {
+		dir, err := os.UserCacheDir()
+		if err != nil {
+			return "", fmt.Errorf("couldn't get cache directory: %w", err)
+		}
+		cacheDir = dir
+	} else if xdg := os.Getenv("XDG_CACHE_HOME"); xdg != "" {
+		cacheDir = xdg
+	} else {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("couldn't get home directory: %w", err)
+		}
+		cacheDir = filepath.Join(homeDir, ".cache")
+	}
+
+	return filepath.Join(cacheDir, "taskmanager", "metadata.json"), nil
+}
+
+// loadMetadataCache reads the cache from disk.
+// A missing, unreadable, corrupted or outdated cache is not an error -
+// we just start again with an empty one and overwrite it on save.
+func loadMetadataCache() *metadataCache {
+	cachePath, err := getCachePath()
+	if err != nil {
+		return nil
+	}
+	return loadMetadataCacheFrom(cachePath)
+}
+
+// openMetadataCache returns the metadata cache, or nil to parse every
+// file when useCache is false (--no-cache)
+func openMetadataCache(useCache bool) *metadataCache {
+	if !useCache {
+		return nil
+	}
+	return loadMetadataCache()
+}
+
+// loadMetadataCacheFrom reads the cache stored at the given path
+func loadMetadataCacheFrom(cachePath string) *metadataCache {
+	c := &metadataCache{
+		path:    cachePath,
+		entries: make(map[string]cacheEntry),
+		seen:    make(map[string]bool),
+	}
+
+	data, err := os.ReadFile(cachePath)
+	if err != nil {
+		return c
+	}
+
+	var f cacheFile
+	if err := json.Unmarshal(data, &f); err != nil || f.Version != cacheVersion || f.Entries == nil {
+		// Corrupted or written by a different version, start fresh
+		c.dirty = true
+		return c
+	}
+
+	c.entries = f.Entries
+	return c
+}
+
+// metadataFor returns the metadata for a file, parsing it only when the
+// cached entry is missing or the file's size or modification time changed
+func (c *metadataCache) metadataFor(fullPath string, info os.FileInfo) TaskMetadata {
+	if c == nil {
+		metadata, _ := parseFrontmatter(fullPath)
+		return metadata
+	}
+
+	c.seen[fullPath] = true
+
+	size := info.Size()
+	modTime := info.ModTime().UnixNano()
+	if entry, ok := c.entries[fullPath]; ok && entry.Size == size && entry.ModTime == modTime {
+		return entry.Metadata
+	}
+
+	metadata, _ := parseFrontmatter(fullPath)
+
+	// Don't cache files modified in the last couple of seconds: on
+	// filesystems with coarse timestamps a second edit could keep the
+	// same size and mtime and we'd never notice it
+	if touchedWithin(info, racyWindow) {
+		if _, ok := c.entries[fullPath]; ok {
+			delete(c.entries, fullPath)
+			c.dirty = true
+		}
+		return metadata
+	}
+
+	c.entries[fullPath] = cacheEntry{
+		Size:     size,
+		ModTime:  modTime,
+		Metadata: metadata,
+	}
+	c.dirty = true
+	return metadata
+}
+
+// save drops entries for files that weren't seen during the last load
+// (deleted or moved files) and writes the cache back to disk
+func (c *metadataCache) save() error {
+	if c == nil {
+		return nil
+	}
+
+	for path := range c.entries {
+		if !c.seen[path] {
+			delete(c.entries, path)
+			c.dirty = true
+		}
+	}
+	c.seen = make(map[string]bool)
+
+	if !c.dirty {
+		return nil
+	}
+
+	data, err := json.Marshal(cacheFile{Version: cacheVersion, Entries: c.entries})
+	if err != nil {
+		return fmt.Errorf("failed to encode cache: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	// Write to a temporary file and rename it so a crash mid-write
+	// never leaves a half-written cache behind
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), ".metadata-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to create cache file: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), c.path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache: %w", err)
+	}
+
+	c.dirty = false
+	return nil
+}
+
+// racyWindow is how recently a file may have been modified before we
+// stop trusting its size and mtime as a cache key
+const racyWindow = 2 * time.Second
+
+// touchedWithin reports whether a file was modified less than d ago
+func touchedWithin(info os.FileInfo, d time.Duration) bool {
+	return time.Since(info.ModTime()) < d
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeOldTask writes a task file dated outside the racy window, so the
+// cache will keep its entry
+func writeOldTask(t testing.TB, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// taskContent returns a task file with a title and some frontmatter
+func taskContent(title string) string {
+	return fmt.Sprintf("---\ntitle: %s\nstatus: todo\npriority: high\ndue_date: 2025-12-31\ntags:\n  - one\n  - two\ncreated: 2025-01-02T10:00:00Z\n---\n\n# %s\n\n- [x] done\n- [ ] open\n", title, title)
+}
+
+// loadTitles loads a directory through a cache and returns titles by
+// filename
+func loadTitles(t *testing.T, dir string, cache *metadataCache) map[string]string {
+	t.Helper()
+	tasks, err := loadTasksFromDirectories([]string{dir}, cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	titles := map[string]string{}
+	for _, task := range tasks {
+		titles[task.name] = task.metadata.Title
+	}
+	return titles
+}
+
+func TestCacheReusesUnchangedFiles(t *testing.T) {
+	dir, cachePath := t.TempDir(), filepath.Join(t.TempDir(), "metadata.json")
+	path := writeOldTask(t, dir, "a.md", taskContent("First"))
+	info, _ := os.Stat(path)
+
+	loadTitles(t, dir, loadMetadataCacheFrom(cachePath))
+
+	// An entry with the file's size and mtime is used without parsing
+	cache := loadMetadataCacheFrom(cachePath)
+	entry := cache.entries[path]
+	entry.Metadata.Title = "From cache"
+	cache.entries[path] = entry
+	if got := cache.metadataFor(path, info).Title; got != "From cache" {
+		t.Errorf("title = %q, want the cached one", got)
+	}
+}
+
+func TestCacheInvalidatesOnModTime(t *testing.T) {
+	dir, cachePath := t.TempDir(), filepath.Join(t.TempDir(), "metadata.json")
+	path := writeOldTask(t, dir, "a.md", taskContent("First"))
+	loadTitles(t, dir, loadMetadataCacheFrom(cachePath))
+
+	// Same size, different content and mtime
+	writeOldTask(t, dir, "a.md", taskContent("Other"))
+	later := time.Now().Add(-30 * time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if got := loadTitles(t, dir, loadMetadataCacheFrom(cachePath))["a.md"]; got != "Other" {
+		t.Errorf("title = %q, want Other", got)
+	}
+}
+
+func TestCacheInvalidatesOnSize(t *testing.T) {
+	dir, cachePath := t.TempDir(), filepath.Join(t.TempDir(), "metadata.json")
+	path := writeOldTask(t, dir, "a.md", taskContent("First"))
+	info, _ := os.Stat(path)
+	loadTitles(t, dir, loadMetadataCacheFrom(cachePath))
+
+	// Different size, same mtime
+	writeOldTask(t, dir, "a.md", taskContent("A longer title"))
+	if err := os.Chtimes(path, info.ModTime(), info.ModTime()); err != nil {
+		t.Fatal(err)
+	}
+	if got := loadTitles(t, dir, loadMetadataCacheFrom(cachePath))["a.md"]; got != "A longer title" {
+		t.Errorf("title = %q, want A longer title", got)
+	}
+}
+
+func TestCacheSkipsRecentlyModifiedFiles(t *testing.T) {
+	dir, cachePath := t.TempDir(), filepath.Join(t.TempDir(), "metadata.json")
+	path := filepath.Join(dir, "a.md")
+	if err := os.WriteFile(path, []byte(taskContent("First")), 0644); err != nil {
+		t.Fatal(err)
+	}
+	loadTitles(t, dir, loadMetadataCacheFrom(cachePath))
+
+	if _, ok := loadMetadataCacheFrom(cachePath).entries[path]; ok {
+		t.Error("a file modified within the racy window was cached")
+	}
+}
+
+func TestCacheDropsDeletedFiles(t *testing.T) {
+	dir, cachePath := t.TempDir(), filepath.Join(t.TempDir(), "metadata.json")
+	writeOldTask(t, dir, "a.md", taskContent("First"))
+	path := writeOldTask(t, dir, "b.md", taskContent("Second"))
+	loadTitles(t, dir, loadMetadataCacheFrom(cachePath))
+
+	os.Remove(path)
+	loadTitles(t, dir, loadMetadataCacheFrom(cachePath))
+	if _, ok := loadMetadataCacheFrom(cachePath).entries[path]; ok {
+		t.Error("the entry of a deleted file was kept")
+	}
+}
+
+func TestCacheVersionMismatch(t *testing.T) {
+	dir, cachePath := t.TempDir(), filepath.Join(t.TempDir(), "metadata.json")
+	path := writeOldTask(t, dir, "a.md", taskContent("First"))
+	info, _ := os.Stat(path)
+
+	// An entry that matches the file but was written by another version
+	stale := cacheFile{Version: cacheVersion - 1, Entries: map[string]cacheEntry{
+		path: {Size: info.Size(), ModTime: info.ModTime().UnixNano(), Metadata: TaskMetadata{Title: "Stale"}},
+	}}
+	data, _ := json.Marshal(stale)
+	if err := os.WriteFile(cachePath, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := loadTitles(t, dir, loadMetadataCacheFrom(cachePath))["a.md"]; got != "First" {
+		t.Errorf("title = %q, want First from the file", got)
+	}
+	var saved cacheFile
+	data, _ = os.ReadFile(cachePath)
+	if err := json.Unmarshal(data, &saved); err != nil || saved.Version != cacheVersion {
+		t.Errorf("cache wasn't rewritten with version %d: %s", cacheVersion, data)
+	}
+}
+
+func TestCacheRecoversFromCorruption(t *testing.T) {
+	dir, cachePath := t.TempDir(), filepath.Join(t.TempDir(), "metadata.json")
+	writeOldTask(t, dir, "a.md", taskContent("First"))
+	if err := os.WriteFile(cachePath, []byte(`{"version": 6, "entries": {"x": `), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := loadTitles(t, dir, loadMetadataCacheFrom(cachePath))["a.md"]; got != "First" {
+		t.Errorf("title = %q, want First", got)
+	}
+	if cache := loadMetadataCacheFrom(cachePath); len(cache.entries) != 1 {
+		t.Errorf("corrupted cache wasn't replaced; has %d entries", len(cache.entries))
+	}
+}
+
+func TestNilCacheParsesEveryFile(t *testing.T) {
+	dir := t.TempDir()
+	writeOldTask(t, dir, "a.md", taskContent("First"))
+	if got := loadTitles(t, dir, openMetadataCache(false))["a.md"]; got != "First" {
+		t.Errorf("title = %q, want First", got)
+	}
+}
+
+// BenchmarkLoadTasks compares loading 1000 tasks by parsing every file
+// with loading them from a warm cache
+func BenchmarkLoadTasks(b *testing.B) {
+	dir, cachePath := b.TempDir(), filepath.Join(b.TempDir(), "metadata.json")
+	for i := 0; i < 1000; i++ {
+		writeOldTask(b, dir, fmt.Sprintf("task-%04d.md", i), taskContent(fmt.Sprintf("Task %d", i)))
+	}
+	dirs := []string{dir}
+
+	b.Run("no-cache", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := loadTasksFromDirectories(dirs, nil); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("cache", func(b *testing.B) {
+		if _, err := loadTasksFromDirectories(dirs, loadMetadataCacheFrom(cachePath)); err != nil {
+			b.Fatal(err)
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := loadTasksFromDirectories(dirs, loadMetadataCacheFrom(cachePath)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
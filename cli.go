@@ -0,0 +1,906 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// usage prints the command-line help
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage:\n")
+	fmt.Fprintf(out, "  taskmanager [flags]              Start the interactive task manager\n")
+	fmt.Fprintf(out, "  taskmanager [flags] <command>    Run a command and exit\n\n")
+	fmt.Fprintf(out, "Commands:\n")
+	fmt.Fprintf(out, "  list [--view <name>] [--query <query>] [--sort <key>] [--group <key>]\n")
+	fmt.Fprintf(out, "                List tasks, optionally through a saved view or a filter query\n")
+	fmt.Fprintf(out, "  projects      List projects with their open, in-progress, done and overdue counts\n")
+	fmt.Fprintf(out, "  tags          List every tag with its task count\n")
+	fmt.Fprintf(out, "  tags rename|merge|delete [--dry-run] <tag>... [<new tag>]\n")
+	fmt.Fprintf(out, "                Rename a tag, merge tags into one, or remove a tag from every task\n")
+	fmt.Fprintf(out, "  add [--dir <dir>] <text>\n")
+	fmt.Fprintf(out, "                Create a task from quick-add text, e.g.\n")
+	fmt.Fprintf(out, "                add Fix login redirect !high #auth due:fri @project-a\n")
+	fmt.Fprintf(out, "  rename [--dry-run]\n")
+	fmt.Fprintf(out, "                Rename old task-YYYYMMDD-HHMMSS.md files after their titles\n")
+	fmt.Fprintf(out, "  move --dir <dir> <file>\n")
+	fmt.Fprintf(out, "                Move a task to another configured directory, updating links to it\n")
+	fmt.Fprintf(out, "  copy --dir <dir> <file>\n")
+	fmt.Fprintf(out, "                Duplicate a task into another configured directory\n")
+	fmt.Fprintf(out, "  branch [--repo <repo>] <file>\n")
+	fmt.Fprintf(out, "                Create and check out a branch for a task in a linked repository\n")
+	fmt.Fprintf(out, "  sync          Pull and push every git-backed task directory\n")
+	fmt.Fprintf(out, "  status [--note <text>] <file> <status>\n")
+	fmt.Fprintf(out, "                Change a task's status, recording it in the task's history\n")
+	fmt.Fprintf(out, "  report [--format text|markdown|json] [--output <file>]\n")
+	fmt.Fprintf(out, "                Show task counts, overdue tasks and weekly throughput\n")
+	fmt.Fprintf(out, "  export [--format ics|todotxt|taskwarrior] [--query <query>] [--output <file>]\n")
+	fmt.Fprintf(out, "                Export tasks as iCalendar to-dos, todo.txt lines or Taskwarrior JSON\n")
+	fmt.Fprintf(out, "  import [--format ics|todotxt|taskwarrior|trello|jira|github] [--dir <dir>] [--dry-run] <file>\n")
+	fmt.Fprintf(out, "                Create tasks from another tool's export, updating tasks imported before\n")
+	fmt.Fprintf(out, "  todotxt sync [--dir <dir>] [--dry-run] <file>\n")
+	fmt.Fprintf(out, "                Copy changes between a todo.txt file and the tasks, reporting conflicts\n")
+	fmt.Fprintf(out, "  history [<file>]\n")
+	fmt.Fprintf(out, "                Show a task's status timeline, or lead time and time in status for all tasks\n")
+	fmt.Fprintf(out, "  time start <file> | time stop | time status\n")
+	fmt.Fprintf(out, "                Start or stop the timer for a task, or show the running timer\n")
+	fmt.Fprintf(out, "  time report [--by task|tag|dir|day] [--since today|week|month|7d|<date>]\n")
+	fmt.Fprintf(out, "                Show the time logged on tasks\n\n")
+	fmt.Fprintf(out, "Flags:\n")
+	flag.PrintDefaults()
+}
+
+// runCommand runs a command-line subcommand instead of the TUI.
+// useCache is false with --no-cache, to parse every task file.
+func runCommand(args []string, useCache bool) error {
+	switch args[0] {
+	case "list":
+		return runListCommand(args[1:], useCache)
+	case "projects":
+		return runProjectsCommand(useCache)
+	case "tags":
+		return runTagsCommand(args[1:], useCache)
+	case "add":
+		return runAddCommand(args[1:])
+	case "rename":
+		return runRenameCommand(args[1:])
+	case "move", "copy":
+		return runTransferCommand(args[0], args[1:])
+	case "branch":
+		return runBranchCommand(args[1:])
+	case "sync":
+		return runSyncCommand()
+	case "time":
+		return runTimeCommand(args[1:], useCache)
+	case "status":
+		return runStatusCommand(args[1:])
+	case "history":
+		return runHistoryCommand(args[1:], useCache)
+	case "report":
+		return runReportCommand(args[1:], useCache)
+	case "export":
+		return runExportCommand(args[1:], useCache)
+	case "import":
+		return runImportCommand(args[1:], useCache)
+	case "todotxt":
+		return runTodoTxtCommand(args[1:], useCache)
+	default:
+		return fmt.Errorf("unknown command %q (see taskmanager --help)", args[0])
+	}
+}
+
+// runListCommand prints tasks through a saved view, or through a query,
+// sort and grouping given as flags. Flags override the view's settings.
+func runListCommand(args []string, useCache bool) error {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	viewName := fs.String("view", "", "Saved view to list tasks through")
+	query := fs.String("query", "", "Filter query, e.g. \"status:todo #backend\"")
+	sortKey := fs.String("sort", "", "Sort by "+strings.Join(sortKeys, ", ")+" (prefix - to reverse)")
+	group := fs.String("group", "", "Group by "+strings.Join(groupKeys, ", "))
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	var view ViewConfig
+	if *viewName != "" {
+		found, ok := findView(cfg.Views, *viewName)
+		if !ok {
+			var names []string
+			for _, v := range cfg.Views {
+				names = append(names, v.Name)
+			}
+			if len(names) == 0 {
+				return fmt.Errorf("no view named %q (no views are configured)", *viewName)
+			}
+			return fmt.Errorf("no view named %q (have %s)", *viewName, strings.Join(names, ", "))
+		}
+		view = found
+	}
+	if *query != "" {
+		view.Query = strings.TrimSpace(view.Query + " " + *query)
+	}
+	if *sortKey != "" {
+		view.Sort = *sortKey
+	}
+	if *group != "" {
+		view.Group = *group
+	}
+	if err := validateView(view); err != nil {
+		return err
+	}
+
+	dirs := cfg.TaskManager.GetDirectories()
+	tasks, err := loadTasksFromDirectories(dirs, openMetadataCache(useCache))
+	if err != nil {
+		return err
+	}
+	assignProjects(tasks, cfg.Projects)
+	groups, err := applyView(tasks, view, cfg.Display.GetDefaultStatus(), time.Now())
+	if err != nil {
+		return err
+	}
+
+	// Rows are rendered as in the TUI, without colors when piped
+	if err := applyTheme(cfg.Theme); err != nil {
+		return err
+	}
+	if err := cfg.Display.validateColumns(); err != nil {
+		return err
+	}
+	m := model{tasks: tasks, config: cfg.Display, showDirInfo: len(dirs) > 1, projects: cfg.Projects}
+	layout := m.columnLayout(view.Columns, 0)
+	for i, group := range groups {
+		if group.name != "" {
+			if i > 0 {
+				fmt.Println()
+			}
+			fmt.Printf("%s (%d)\n", group.name, len(group.tasks))
+		}
+		for _, task := range group.tasks {
+			fmt.Println(strings.TrimRight(m.renderTaskRow(task, false, layout), " "))
+		}
+	}
+	return nil
+}
+
+// runProjectsCommand prints the projects overview
+func runProjectsCommand(useCache bool) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if len(cfg.Projects) == 0 {
+		return fmt.Errorf("no projects are configured (add [[projects]] to the config)")
+	}
+	tasks, err := loadTasksFromDirectories(cfg.TaskManager.GetDirectories(), openMetadataCache(useCache))
+	if err != nil {
+		return err
+	}
+	assignProjects(tasks, cfg.Projects)
+
+	for _, summary := range summarizeProjects(tasks, cfg.Projects, time.Now()) {
+		project := summary.project
+		fmt.Printf("%-6s %-24s %s\n", project.GetCode(), project.Name, summary.formatCounts())
+		fmt.Printf("       %s\n", project.Path)
+	}
+	return nil
+}
+
+// runTagsCommand lists tags, or renames, merges or deletes them. The
+// changes are printed before they're made, and --dry-run stops there.
+func runTagsCommand(args []string, useCache bool) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	tasks, err := loadTasksFromDirectories(cfg.TaskManager.GetDirectories(), openMetadataCache(useCache))
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 0 {
+		// Nested tags are indented, and counts include them
+		for _, tag := range countTags(tasks) {
+			fmt.Printf("%-40s %d\n", strings.Repeat("  ", tag.depth)+"#"+tag.name, tag.count)
+		}
+		return nil
+	}
+
+	action := args[0]
+	fs := flag.NewFlagSet("tags "+action, flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "Show the changes without making them")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	names := fs.Args()
+	for i, name := range names {
+		names[i] = strings.TrimPrefix(name, "#")
+	}
+
+	var from []string
+	var to string
+	switch action {
+	case "rename":
+		if len(names) != 2 {
+			return fmt.Errorf("usage: taskmanager tags rename [--dry-run] <tag> <new tag>")
+		}
+		from, to = names[:1], names[1]
+	case "merge":
+		if len(names) < 2 {
+			return fmt.Errorf("usage: taskmanager tags merge [--dry-run] <tag>... <into tag>")
+		}
+		from, to = names[:len(names)-1], names[len(names)-1]
+	case "delete":
+		if len(names) == 0 {
+			return fmt.Errorf("usage: taskmanager tags delete [--dry-run] <tag>...")
+		}
+		from = names
+	default:
+		return fmt.Errorf("unknown tags action %q (use rename, merge or delete)", action)
+	}
+	if to != "" {
+		if err := validTagName(to); err != nil {
+			return err
+		}
+	}
+
+	changes := planTagChange(tasks, from, to)
+	if len(changes) == 0 && to != "" && len(taggedWith(tasks, from)) > 0 {
+		return fmt.Errorf("every task tagged %s already uses #%s", formatTags(from), to)
+	}
+	if len(changes) == 0 {
+		return fmt.Errorf("no tasks are tagged %s", formatTags(from))
+	}
+	for _, change := range changes {
+		fmt.Printf("%s: %s -> %s\n", change.task.fullPath, formatTags(change.before), formatTags(change.after))
+	}
+	if *dryRun {
+		return nil
+	}
+
+	paths, err := applyTagChanges(changes)
+	if err != nil {
+		return err
+	}
+	summary := describeTagChange(action, from, to, len(paths))
+	if err := cfg.Git.autoCommit("tags", TaskMetadata{Title: summary}, "", paths...); err != nil {
+		return err
+	}
+	fmt.Printf("Done: %s\n", summary)
+	return nil
+}
+
+// runAddCommand creates a task from quick-add text given on the command line
+func runAddCommand(args []string) error {
+	fs := flag.NewFlagSet("add", flag.ContinueOnError)
+	dirFlag := fs.String("dir", "", "Directory for the new task (a configured path or folder name)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("usage: taskmanager add [--dir <dir>] <text>")
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	dirs := cfg.TaskManager.GetDirectories()
+	defaultDir, err := chooseTaskDir(*dirFlag, dirs)
+	if err != nil {
+		return err
+	}
+
+	taskPath, err := quickAdd(strings.Join(fs.Args(), " "), dirs, cfg.Projects, defaultDir, cfg.Display.GetDefaultStatus(), cfg.TaskManager.GetFilenamePattern())
+	if err != nil {
+		return err
+	}
+
+	meta, _ := parseFrontmatter(taskPath)
+	if err := cfg.Git.autoCommit("create", meta, filepath.Dir(taskPath), taskPath); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stdout, "Created %s\n", taskPath)
+	return nil
+}
+
+// chooseTaskDir picks the directory for new tasks: the --dir flag if
+// given (and remembers it), otherwise the last directory chosen,
+// otherwise the first configured one
+func chooseTaskDir(dirFlag string, dirs []string) (string, error) {
+	if dirFlag != "" {
+		dir, err := resolveTaskDir(dirFlag, dirs)
+		if err != nil {
+			return "", err
+		}
+		_ = rememberLastDir(dir)
+		return dir, nil
+	}
+	if last := loadState().LastDir; last != "" {
+		for _, dir := range dirs {
+			if dir == last {
+				return dir, nil
+			}
+		}
+	}
+	return dirs[0], nil
+}
+
+// runRenameCommand renames timestamp-named task files after their titles
+// using the configured filename pattern
+func runRenameCommand(args []string) error {
+	fs := flag.NewFlagSet("rename", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "Show what would be renamed without changing anything")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	pattern := cfg.TaskManager.GetFilenamePattern()
+
+	plans, err := planTimestampRenames(cfg.TaskManager.GetDirectories())
+	if err != nil {
+		return err
+	}
+	if len(plans) == 0 {
+		fmt.Println("No timestamp-named tasks with titles found")
+		return nil
+	}
+
+	for _, plan := range plans {
+		if *dryRun {
+			fmt.Printf("%s -> %s\n", plan.oldPath, plan.newName(pattern))
+			continue
+		}
+
+		newPath, err := plan.apply(pattern)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s -> %s\n", plan.oldPath, filepath.Base(newPath))
+
+		if err := cfg.Git.autoCommit("rename", plan.meta, filepath.Dir(newPath), plan.oldPath, newPath); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// runTransferCommand moves or copies a task file into another
+// configured directory
+func runTransferCommand(name string, args []string) error {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	dirFlag := fs.String("dir", "", "Target directory (a configured path or folder name)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 || *dirFlag == "" {
+		return fmt.Errorf("usage: taskmanager %s --dir <dir> <file>", name)
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	dirs := cfg.TaskManager.GetDirectories()
+
+	targetDir, err := resolveTaskDir(*dirFlag, dirs)
+	if err != nil {
+		return err
+	}
+
+	srcPath, err := filepath.Abs(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	meta, _ := parseFrontmatter(srcPath)
+
+	var result transferResult
+	if name == "move" {
+		result, err = moveTask(srcPath, targetDir, dirs)
+	} else {
+		result, err = copyTask(srcPath, targetDir)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s -> %s\n", srcPath, result.newPath)
+	if len(result.updatedPaths) > 0 {
+		fmt.Printf("Updated links in %d tasks\n", len(result.updatedPaths))
+	}
+
+	paths := []string{result.newPath}
+	if name == "move" {
+		paths = append(append(paths, srcPath), result.updatedPaths...)
+	}
+	return cfg.Git.autoCommit(name, meta, targetDir, paths...)
+}
+
+// runSyncCommand pulls and pushes every git-backed task directory
+func runSyncCommand() error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	roots := uniqueRoots(gitRepoRoots(cfg.TaskManager.GetDirectories()))
+	if len(roots) == 0 {
+		return fmt.Errorf("none of the configured directories are in a git repository")
+	}
+
+	for _, result := range gitSync(roots) {
+		fmt.Println(result)
+	}
+	return nil
+}
+
+// runBranchCommand creates and checks out a task's branch in one of the
+// repositories listed under [git] repos
+func runBranchCommand(args []string) error {
+	fs := flag.NewFlagSet("branch", flag.ContinueOnError)
+	repoFlag := fs.String("repo", "", "Repository to create the branch in (a configured path or folder name)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: taskmanager branch [--repo <repo>] <file>")
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	repos := cfg.Git.Repos
+
+	var repo string
+	switch {
+	case *repoFlag != "":
+		if repo, err = resolveTaskDir(*repoFlag, repos); err != nil {
+			return err
+		}
+	case len(repos) == 1:
+		repo = repos[0]
+	case len(repos) == 0:
+		return fmt.Errorf("no repositories configured (set repos under [git])")
+	default:
+		return fmt.Errorf("several repositories are configured, choose one with --repo")
+	}
+	if repo, err = expandPath(repo); err != nil {
+		return err
+	}
+
+	taskPath, err := filepath.Abs(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	name, addedID, err := createTaskBranch(repo, taskPath, cfg.Git.GetBranchPattern())
+	if addedID {
+		meta, _ := parseFrontmatter(taskPath)
+		if err := cfg.Git.autoCommit("edit", meta, filepath.Dir(taskPath), taskPath); err != nil {
+			return err
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Checked out %s in %s\n", name, repo)
+	return nil
+}
+
+// runTimeCommand starts, stops and reports on task timers
+func runTimeCommand(args []string, useCache bool) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: taskmanager time start <file> | stop | status | report")
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	dirs := cfg.TaskManager.GetDirectories()
+
+	switch args[0] {
+	case "start":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: taskmanager time start <file>")
+		}
+		taskPath, err := filepath.Abs(args[1])
+		if err != nil {
+			return err
+		}
+		stopped, err := startTimer(taskPath, dirs)
+		if err != nil {
+			return err
+		}
+		if stopped != nil {
+			fmt.Printf("Stopped timer on %s (%s)\n", stopped.Title, formatDuration(time.Since(stopped.Start)))
+		}
+		fmt.Printf("Started timer on %s\n", loadState().Timer.Title)
+		return nil
+
+	case "stop":
+		timer, taskPath, err := stopTimer(dirs)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Logged %s on %s\n", formatDuration(time.Since(timer.Start)), timer.Title)
+		meta, _ := parseFrontmatter(taskPath)
+		return cfg.Git.autoCommit("time", meta, filepath.Dir(taskPath), taskPath)
+
+	case "status":
+		timer := loadState().Timer
+		if timer == nil {
+			fmt.Println("No timer is running")
+			return nil
+		}
+		fmt.Printf("%s: %s (since %s)\n", timer.Title, formatElapsed(time.Since(timer.Start)), timer.Start.Format("15:04"))
+		return nil
+
+	case "report":
+		fs := flag.NewFlagSet("time report", flag.ContinueOnError)
+		by := fs.String("by", "task", "Group by task, tag, dir or day")
+		sinceFlag := fs.String("since", "", "Only count time since today, week, month, a number of days (7d) or a date")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		since, err := parseSince(*sinceFlag, time.Now())
+		if err != nil {
+			return err
+		}
+
+		tasks, err := loadTasksFromDirectories(dirs, openMetadataCache(useCache))
+		if err != nil {
+			return err
+		}
+		rows, err := timeReport(tasks, *by, since)
+		if err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			fmt.Println("No time logged")
+			return nil
+		}
+		for _, row := range rows {
+			fmt.Printf("%8s  %s\n", formatDuration(row.total), row.label)
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unknown time command %q (use start, stop, status or report)", args[0])
+	}
+}
+
+// runStatusCommand changes a task's status from the command line
+func runStatusCommand(args []string) error {
+	fs := flag.NewFlagSet("status", flag.ContinueOnError)
+	note := fs.String("note", "", "Why the status changed")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return fmt.Errorf("usage: taskmanager status [--note <text>] <file> <status>")
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	taskPath, err := filepath.Abs(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	status := fs.Arg(1)
+
+	before, err := parseFrontmatter(taskPath)
+	if err != nil {
+		return err
+	}
+	if before.Status == status {
+		fmt.Printf("Already %s\n", status)
+		return nil
+	}
+
+	if err := setTaskStatus(taskPath, status, *note, time.Now()); err != nil {
+		return err
+	}
+	fmt.Printf("%s: %s -> %s\n", filepath.Base(taskPath), before.Status, status)
+
+	meta, _ := parseFrontmatter(taskPath)
+	return cfg.Git.autoCommit("status", meta, filepath.Dir(taskPath), taskPath)
+}
+
+// runHistoryCommand prints one task's status timeline, or status metrics
+// across every task when no file is given
+func runHistoryCommand(args []string, useCache bool) error {
+	if len(args) > 1 {
+		return fmt.Errorf("usage: taskmanager history [<file>]")
+	}
+
+	if len(args) == 1 {
+		meta, err := parseFrontmatter(args[0])
+		if err != nil {
+			return err
+		}
+		if len(meta.StatusHistory) == 0 {
+			fmt.Println("No status changes recorded")
+			return nil
+		}
+		for _, change := range meta.StatusHistory {
+			fmt.Println(formatStatusChange(change))
+		}
+		if d, ok := meta.leadTime(); ok {
+			fmt.Printf("Lead time: %s\n", formatCycleTime(d))
+		}
+		return nil
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	tasks, err := loadTasksFromDirectories(cfg.TaskManager.GetDirectories(), openMetadataCache(useCache))
+	if err != nil {
+		return err
+	}
+
+	metrics := collectStatusMetrics(tasks, time.Now())
+	if len(metrics.AverageInStatus) == 0 {
+		fmt.Println("No status changes recorded")
+		return nil
+	}
+	fmt.Println("Average time in status:")
+	for _, status := range metrics.statuses() {
+		fmt.Printf("  %-12s %s\n", status, formatCycleTime(metrics.AverageInStatus[status]))
+	}
+	if metrics.LeadTimeTasks > 0 {
+		fmt.Printf("Average lead time: %s (%d tasks)\n", formatCycleTime(metrics.AverageLeadTime), metrics.LeadTimeTasks)
+	}
+	fmt.Printf("Reopened tasks: %d\n", metrics.Reopened)
+	return nil
+}
+
+// runReportCommand prints workload statistics as text, markdown or JSON
+func runReportCommand(args []string, useCache bool) error {
+	fs := flag.NewFlagSet("report", flag.ContinueOnError)
+	format := fs.String("format", "text", "Output format: text, markdown or json")
+	output := fs.String("output", "", "Write the report to a file instead of standard output")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	tasks, err := loadTasksFromDirectories(cfg.TaskManager.GetDirectories(), openMetadataCache(useCache))
+	if err != nil {
+		return err
+	}
+	stats := collectStats(tasks, cfg.Display.GetDefaultStatus(), time.Now())
+
+	var report string
+	switch *format {
+	case "text":
+		report = stats.formatText() + "\n"
+	case "markdown", "md":
+		report = stats.formatMarkdown()
+	case "json":
+		if report, err = stats.formatJSON(); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown format %q (use text, markdown or json)", *format)
+	}
+
+	if *output == "" {
+		fmt.Print(report)
+		return nil
+	}
+	if err := os.WriteFile(*output, []byte(report), 0644); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	fmt.Printf("Wrote %s\n", *output)
+	return nil
+}
+
+// runExportCommand writes tasks in another tool's format
+func runExportCommand(args []string, useCache bool) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := fs.String("format", "ics", "Export format: ics, todotxt or taskwarrior")
+	query := fs.String("query", "", "Only export tasks matching a filter query")
+	output := fs.String("output", "", "Write the export to a file instead of standard output")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	tasks, err := exportedTasks(cfg, *query, useCache)
+	if err != nil {
+		return err
+	}
+
+	var data []byte
+	switch *format {
+	case "ics", "ical":
+		data = exportICal(tasks, time.Now())
+	case "todotxt", "txt":
+		data = exportTodoTxt(tasks, cfg.Display.GetDefaultStatus())
+	case "taskwarrior", "tw":
+		if data, err = exportTaskwarrior(tasks); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown format %q (use ics, todotxt or taskwarrior)", *format)
+	}
+	return writeExport(data, *output)
+}
+
+// runImportCommand creates tasks from another tool's export. Tasks
+// imported before (or exported from here) are updated instead of
+// duplicated.
+func runImportCommand(args []string, useCache bool) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	format := fs.String("format", "", "Import format: "+strings.Join(importerNames(), ", ")+" (default: from the file)")
+	dirFlag := fs.String("dir", "", "Directory for new tasks (a configured path or folder name)")
+	dryRun := fs.Bool("dry-run", false, "Show what would be created and updated without changing anything")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: taskmanager import [--format %s] [--dir <dir>] [--dry-run] <file>", strings.Join(importerNames(), "|"))
+	}
+	path := fs.Arg(0)
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read import: %w", err)
+	}
+	imp, err := findImporter(*format, path, data)
+	if err != nil {
+		return err
+	}
+	dirs := cfg.TaskManager.GetDirectories()
+	tasks, err := loadTasksFromDirectories(dirs, openMetadataCache(useCache))
+	if err != nil {
+		return err
+	}
+	assignProjects(tasks, cfg.Projects)
+
+	items, err := imp.read(data, importInput{tasks: tasks, projects: cfg.Projects, defaultStatus: cfg.Display.GetDefaultStatus()})
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", filepath.Base(path), err)
+	}
+
+	steps := planImport(items, tasks, imp.taskRef, cfg.Display.GetDefaultStatus())
+	for _, step := range steps {
+		fmt.Println(step.describe())
+	}
+	if *dryRun {
+		return nil
+	}
+
+	dir, err := chooseTaskDir(*dirFlag, dirs)
+	if err != nil {
+		return err
+	}
+	paths, err := applyImport(steps, dir, cfg.TaskManager.GetFilenamePattern())
+	if err != nil {
+		return err
+	}
+	created, updated := countSteps(steps)
+	summary := fmt.Sprintf("import %d new and %d updated tasks from %s", created, updated, filepath.Base(path))
+	if err := cfg.Git.autoCommit("import", TaskMetadata{Title: summary}, "", paths...); err != nil {
+		return err
+	}
+	fmt.Printf("Done: %s\n", summary)
+	return nil
+}
+
+// runTodoTxtCommand syncs a todo.txt file with the tasks
+func runTodoTxtCommand(args []string, useCache bool) error {
+	if len(args) == 0 || args[0] != "sync" {
+		return fmt.Errorf("usage: taskmanager todotxt sync [--dir <dir>] [--dry-run] <file>")
+	}
+	fs := flag.NewFlagSet("todotxt sync", flag.ContinueOnError)
+	dirFlag := fs.String("dir", "", "Directory for tasks added to the file (a configured path or folder name)")
+	dryRun := fs.Bool("dry-run", false, "Show what would change without changing anything")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: taskmanager todotxt sync [--dir <dir>] [--dry-run] <file>")
+	}
+	path, err := filepath.Abs(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	// A file that doesn't exist yet is created with every open task
+	content, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read todo.txt: %w", err)
+	}
+	dirs := cfg.TaskManager.GetDirectories()
+	tasks, err := loadTasksFromDirectories(dirs, openMetadataCache(useCache))
+	if err != nil {
+		return err
+	}
+	assignProjects(tasks, cfg.Projects)
+
+	state := loadState()
+	plan := planTodoSync(string(content), tasks, state.TodoTxt[path], cfg.Projects, cfg.Display.GetDefaultStatus())
+	for _, line := range plan.report {
+		fmt.Println(line)
+	}
+	for _, conflict := range plan.conflicts {
+		fmt.Println("conflict " + conflict)
+	}
+	if *dryRun {
+		return nil
+	}
+
+	dir, err := chooseTaskDir(*dirFlag, dirs)
+	if err != nil {
+		return err
+	}
+	paths, err := applyTodoSync(plan, dir, cfg.TaskManager.GetFilenamePattern())
+	if err != nil {
+		return err
+	}
+	var out string
+	if len(plan.lines) > 0 {
+		out = strings.Join(plan.lines, "\n") + "\n"
+	}
+	if out != string(content) {
+		if err := os.WriteFile(path, []byte(out), 0644); err != nil {
+			return fmt.Errorf("failed to write todo.txt: %w", err)
+		}
+	}
+
+	if state.TodoTxt == nil {
+		state.TodoTxt = map[string]map[string]string{}
+	}
+	state.TodoTxt[path] = plan.base
+	if err := saveState(state); err != nil {
+		return err
+	}
+
+	summary := fmt.Sprintf("sync %d changes with %s", len(plan.report), filepath.Base(path))
+	if err := cfg.Git.autoCommit("todotxt", TaskMetadata{Title: summary}, "", paths...); err != nil {
+		return err
+	}
+	fmt.Printf("Done: %s\n", summary)
+	if len(plan.conflicts) > 0 {
+		return fmt.Errorf("%d conflicts left unchanged; edit one side to match the other and sync again", len(plan.conflicts))
+	}
+	return nil
+}
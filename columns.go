@@ -0,0 +1,219 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+// ColumnConfig overrides how a list column is laid out
+type ColumnConfig struct {
+	Width    int    `toml:"width"`    // Width in terminal cells; for the title, the narrowest it shrinks to
+	Align    string `toml:"align"`    // left or right
+	Truncate string `toml:"truncate"` // Cut long values at the end (the default) or the start
+	Hide     int    `toml:"hide"`     // Hide order on narrow terminals: higher hides first, -1 never hides
+}
+
+// columnSpec is a column's layout in the list
+type columnSpec struct {
+	name     string
+	width    int    // Width in terminal cells
+	minWidth int    // Narrowest the title shrinks to before other columns hide
+	maxWidth int    // Widest the column grows to fit its values; 0 for no limit
+	fixed    bool   // Whether the width was configured rather than measured
+	align    string // left or right
+	truncate string // end or start
+	hide     int    // Hide order on narrow terminals; 0 never hides
+}
+
+// fieldColumn prefixes columns that show a custom frontmatter field,
+// e.g. "field:estimate"
+const fieldColumn = "field:"
+
+// Built-in column layouts. Columns with a higher hide order are hidden
+// first when the terminal is too narrow; the status and title always stay.
+var columnDefaults = map[string]columnSpec{
+	"status":   {},
+	"priority": {hide: 2},
+	"project":  {hide: 4},
+	"title":    {minWidth: 20},
+	"git":      {hide: 3},
+	"due":      {hide: 5},
+	"tags":     {maxWidth: 30, hide: 6},
+	"modified": {hide: 7},
+	"created":  {hide: 8},
+	"id":       {hide: 9},
+	"progress": {align: "right", hide: 10},
+	"dir":      {maxWidth: 24, hide: 11},
+}
+
+// fieldDefaults is the layout of custom field columns
+var fieldDefaults = columnSpec{maxWidth: 20, hide: 12}
+
+// isColumn reports whether name is a known column or a custom field
+func isColumn(name string) bool {
+	if field, ok := strings.CutPrefix(strings.ToLower(name), fieldColumn); ok {
+		return field != ""
+	}
+	return containsFold(columnNames, name)
+}
+
+// checkColumn returns an error for an unknown column name
+func checkColumn(name string) error {
+	if !isColumn(name) {
+		return fmt.Errorf("unknown column %q (use %s, or %sNAME for a frontmatter field)", name, strings.Join(columnNames, ", "), fieldColumn)
+	}
+	return nil
+}
+
+// validateColumns checks the columns and column options under [display]
+func (c *DisplayConfig) validateColumns() error {
+	for _, column := range c.Columns {
+		if err := checkColumn(column); err != nil {
+			return err
+		}
+	}
+	for name, options := range c.ColumnOptions {
+		if err := checkColumn(name); err != nil {
+			return fmt.Errorf("column_options: %w", err)
+		}
+		if options.Width < 0 {
+			return fmt.Errorf("column_options.%s: width can't be negative", name)
+		}
+		if options.Align != "" && options.Align != "left" && options.Align != "right" {
+			return fmt.Errorf("column_options.%s: unknown align %q (use left or right)", name, options.Align)
+		}
+		if options.Truncate != "" && options.Truncate != "end" && options.Truncate != "start" {
+			return fmt.Errorf("column_options.%s: unknown truncate %q (use end or start)", name, options.Truncate)
+		}
+	}
+	return nil
+}
+
+// columnSpec returns a column's layout with the configured options
+// applied, before it's measured
+func (c *DisplayConfig) columnSpec(name string) columnSpec {
+	spec, ok := columnDefaults[name]
+	if !ok {
+		spec = fieldDefaults
+	}
+	spec.name = name
+	if spec.align == "" {
+		spec.align = "left"
+	}
+	spec.truncate = "end"
+
+	for key, options := range c.ColumnOptions {
+		if !strings.EqualFold(key, name) {
+			continue
+		}
+		if options.Width > 0 {
+			if name == "title" {
+				spec.minWidth = options.Width
+			} else {
+				spec.width = options.Width
+				spec.fixed = true
+			}
+		}
+		if options.Align != "" {
+			spec.align = options.Align
+		}
+		if options.Truncate != "" {
+			spec.truncate = options.Truncate
+		}
+		if options.Hide != 0 && name != "status" && name != "title" {
+			spec.hide = max(options.Hide, 0)
+		}
+	}
+	return spec
+}
+
+// columnLayout sizes the columns of the list for a width in terminal
+// cells (0 for no limit). Columns are as wide as their widest value
+// across every task, so they line up the same in any view. When the
+// row doesn't fit, columns are hidden by hide order until it does with
+// the title at its minimum, and then the title takes what's left.
+func (m model) columnLayout(columns []string, width int) []columnSpec {
+	if len(columns) == 0 {
+		columns = m.defaultColumns()
+	}
+
+	var layout []columnSpec
+	for _, name := range columns {
+		spec := m.config.columnSpec(strings.ToLower(name))
+		if !spec.fixed {
+			spec.width = 0
+			for _, task := range m.tasks {
+				spec.width = max(spec.width, ansi.StringWidth(m.renderCell(task, spec.name)))
+			}
+			if spec.maxWidth > 0 {
+				spec.width = min(spec.width, spec.maxWidth)
+			}
+		}
+		// Columns no task has a value for take no space
+		if spec.width == 0 {
+			continue
+		}
+		layout = append(layout, spec)
+	}
+	if width <= 0 {
+		return layout
+	}
+
+	for rowWidth(layout, true) > width {
+		hide := -1
+		for i, spec := range layout {
+			if spec.hide > 0 && (hide < 0 || spec.hide >= layout[hide].hide) {
+				hide = i
+			}
+		}
+		if hide < 0 {
+			break
+		}
+		layout = append(layout[:hide], layout[hide+1:]...)
+	}
+
+	if over := rowWidth(layout, false) - width; over > 0 {
+		for i, spec := range layout {
+			if spec.name == "title" {
+				layout[i].width = max(spec.width-over, min(spec.width, spec.minWidth))
+			}
+		}
+	}
+	return layout
+}
+
+// rowWidth is the width of a row with the cursor and a space before
+// each column, optionally with the title shrunk to its minimum
+func rowWidth(layout []columnSpec, shrinkTitle bool) int {
+	width := 1
+	for _, spec := range layout {
+		if shrinkTitle && spec.name == "title" {
+			width += 1 + min(spec.width, spec.minWidth)
+			continue
+		}
+		width += 1 + spec.width
+	}
+	return width
+}
+
+// fitCell pads or truncates a rendered cell to its column's width,
+// measuring display width so wide characters and colors line up
+func fitCell(cell string, spec columnSpec) string {
+	width := ansi.StringWidth(cell)
+	if width > spec.width {
+		if spec.truncate == "start" {
+			cell = "…" + ansi.TruncateLeft(cell, width-spec.width+1, "")
+		} else {
+			cell = ansi.Truncate(cell, spec.width, "…")
+		}
+		width = ansi.StringWidth(cell)
+	}
+
+	padding := strings.Repeat(" ", max(spec.width-width, 0))
+	if spec.align == "right" {
+		return padding + cell
+	}
+	return cell + padding
+}
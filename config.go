@@ -5,26 +5,87 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 
 	"github.com/BurntSushi/toml"
 )
 
 // Config represents the application configuration
 type Config struct {
-	TaskManager TaskManagerConfig `toml:"taskmanager"`
-	Display     DisplayConfig     `toml:"display"`
+	TaskManager TaskManagerConfig     `toml:"taskmanager"`
+	Display     DisplayConfig         `toml:"display"`
+	Git         GitConfig             `toml:"git"`
+	Views       []ViewConfig          `toml:"views"`
+	Projects    []ProjectConfig       `toml:"projects"`
+	Theme       ThemeConfig           `toml:"theme"`
+	Keys        map[string]keyBinding `toml:"keys"`
 }
 
 // TaskManagerConfig holds the task manager specific settings
 type TaskManagerConfig struct {
-	Directory   string   `toml:"directory"`   // Single directory (deprecated, use Directories)
-	Directories []string `toml:"directories"` // Multiple directories containing task markdown files
+	Directory       string   `toml:"directory"`         // Single directory (deprecated, use Directories)
+	Directories     []string `toml:"directories"`       // Multiple directories containing task markdown files
+	FilenamePattern string   `toml:"filename_pattern"`  // Pattern for new task filenames, e.g. "{{date}}-{{slug}}.md"
+	RenameOnRetitle bool     `toml:"rename_on_retitle"` // Rename a task's file when its title is edited
 }
 
 // DisplayConfig holds display customization settings
 type DisplayConfig struct {
-	StatusIndicators map[string]string `toml:"status_indicators"` // Custom status indicators
-	DefaultStatus    string            `toml:"default_status"`    // Default status for tasks without one
+	StatusIndicators map[string]string       `toml:"status_indicators"` // Custom status indicators
+	DefaultStatus    string                  `toml:"default_status"`    // Default status for tasks without one
+	GroupBy          string                  `toml:"group_by"`          // Default list grouping: status, priority, dir, tag or due
+	TagColors        map[string]string       `toml:"tag_colors"`        // Colors by tag, inherited by nested tags
+	Columns          []string                `toml:"columns"`           // List columns when a view doesn't choose its own
+	ColumnOptions    map[string]ColumnConfig `toml:"column_options"`    // Width, alignment and truncation by column
+}
+
+// GitConfig holds settings for task directories inside git repositories
+type GitConfig struct {
+	AutoCommit    bool              `toml:"auto_commit"`    // Commit after every change made through the app
+	Messages      map[string]string `toml:"messages"`       // Commit message templates by action
+	Repos         []string          `toml:"repos"`          // Code repositories to create task branches in and scan for task IDs
+	BranchPattern string            `toml:"branch_pattern"` // Pattern for task branch names, e.g. "{{id}}-{{slug}}"
+	AutoStatus    bool              `toml:"auto_status"`    // Move tasks to in-progress or done when commits mention them
+}
+
+// ViewConfig is a named view of the task list
+type ViewConfig struct {
+	Name    string   `toml:"name"`    // Shown in the picker and used by list --view
+	Query   string   `toml:"query"`   // Filter query, e.g. "due:overdue -status:done"
+	Sort    string   `toml:"sort"`    // modified, created, due, priority, title or status; - reverses
+	Group   string   `toml:"group"`   // status, priority, dir, project, tag or due
+	Columns []string `toml:"columns"` // Columns to show, in order
+}
+
+// findView returns the view with the given name, ignoring case
+func findView(views []ViewConfig, name string) (ViewConfig, bool) {
+	for _, view := range views {
+		if strings.EqualFold(view.Name, name) {
+			return view, true
+		}
+	}
+	return ViewConfig{}, false
+}
+
+// GetBranchPattern returns the configured branch name pattern, or the
+// default "{{id}}-{{slug}}"
+func (c *GitConfig) GetBranchPattern() string {
+	if c.BranchPattern != "" {
+		return c.BranchPattern
+	}
+	return defaultBranchPattern
+}
+
+// GetMessage returns the commit message template for an action
+// (create, edit, status, delete, move, copy, rename)
+func (c *GitConfig) GetMessage(action string) string {
+	if message, ok := c.Messages[action]; ok {
+		return message
+	}
+	if message, ok := defaultCommitMessages[action]; ok {
+		return message
+	}
+	return "task: " + action + " {{title}}"
 }
 
 // GetStatusIndicator returns the indicator for a given status
@@ -78,6 +139,15 @@ func (c *TaskManagerConfig) GetDirectories() []string {
 	return []string{"~/.tasks"}
 }
 
+// GetFilenamePattern returns the configured filename pattern for new
+// tasks, or the default "{{date}}-{{slug}}.md"
+func (c *TaskManagerConfig) GetFilenamePattern() string {
+	if c.FilenamePattern != "" {
+		return c.FilenamePattern
+	}
+	return defaultFilenamePattern
+}
+
 // defaultConfig returns the default configuration
 func defaultConfig() Config {
 	return Config{
@@ -147,6 +217,9 @@ func loadConfig() (Config, error) {
 		return Config{}, fmt.Errorf("failed to parse config file: %w", err)
 	}
 
+	// Project paths are task directories too
+	cfg.addProjectDirs()
+
 	return cfg, nil
 } // saveConfig writes the configuration to file
 func saveConfig(cfg Config) error {
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// taskFilter is a parsed filter query. Every term must match.
+type taskFilter struct {
+	terms []filterTerm
+}
+
+// filterTerm is one condition of a query, such as status:todo or -#web
+type filterTerm struct {
+	negate bool
+	match  func(task taskFile, now time.Time) bool
+}
+
+// parseQuery parses a filter query. Terms are separated by spaces and
+// all of them must match:
+//
+//	status:todo,in-progress    status is one of these
+//	priority:high  (or !high)  priority is one of these
+//	tag:backend    (or #backend, also matching backend/api)
+//	dir:project-a              in a directory whose path contains this folder
+//	project:WEB                in a project, by code
+//	due:overdue|today|week|none|any
+//	is:open|done|overdue
+//	id:k3x9p2
+//	anything else              text in the filename, title, status or tags
+//
+// A leading - negates a term, e.g. -status:done.
+func parseQuery(query string) (taskFilter, error) {
+	var filter taskFilter
+
+	for _, word := range strings.Fields(query) {
+		term := filterTerm{}
+		if len(word) > 1 && word[0] == '-' {
+			term.negate = true
+			word = word[1:]
+		}
+
+		match, err := parseTerm(word)
+		if err != nil {
+			return filter, err
+		}
+		term.match = match
+		filter.terms = append(filter.terms, term)
+	}
+
+	return filter, nil
+}
+
+// parseTerm turns one query word into a matcher
+func parseTerm(word string) (func(taskFile, time.Time) bool, error) {
+	lower := strings.ToLower(word)
+
+	switch {
+	case len(word) > 1 && word[0] == '#':
+		return matchTags(splitValues(lower[1:])), nil
+	case len(word) > 1 && word[0] == '!':
+		priority, ok := parsePriorityToken(lower[1:])
+		if !ok {
+			return nil, fmt.Errorf("unknown priority %q", word)
+		}
+		return matchField(func(meta TaskMetadata) string { return meta.Priority }, []string{priority}), nil
+	}
+
+	key, value, found := strings.Cut(lower, ":")
+	if !found || value == "" {
+		return matchText(lower), nil
+	}
+
+	switch key {
+	case "status":
+		return matchField(func(meta TaskMetadata) string { return meta.Status }, splitValues(value)), nil
+	case "priority":
+		return matchField(func(meta TaskMetadata) string { return meta.Priority }, splitValues(value)), nil
+	case "tag", "tags":
+		return matchTags(splitValues(value)), nil
+	case "id":
+		return matchField(func(meta TaskMetadata) string { return meta.ID }, splitValues(value)), nil
+	case "project":
+		projects := splitValues(value)
+		return func(task taskFile, _ time.Time) bool {
+			return containsFold(projects, task.project)
+		}, nil
+	case "dir":
+		return func(task taskFile, _ time.Time) bool {
+			for _, part := range strings.Split(filepath.ToSlash(task.sourceDir), "/") {
+				if strings.EqualFold(part, value) {
+					return true
+				}
+			}
+			return strings.EqualFold(task.sourceDir, value)
+		}, nil
+	case "due":
+		switch value {
+		case "overdue", "today", "week", "none", "any":
+			return func(task taskFile, now time.Time) bool {
+				return dueMatches(task.metadata, value, now)
+			}, nil
+		}
+		return nil, fmt.Errorf("unknown due filter %q (use overdue, today, week, none or any)", value)
+	case "is":
+		switch value {
+		case "open":
+			return func(task taskFile, _ time.Time) bool { return !isDoneStatus(task.metadata.Status) }, nil
+		case "done":
+			return func(task taskFile, _ time.Time) bool { return isDoneStatus(task.metadata.Status) }, nil
+		case "overdue":
+			return func(task taskFile, now time.Time) bool { return dueMatches(task.metadata, "overdue", now) }, nil
+		}
+		return nil, fmt.Errorf("unknown filter %q (use is:open, is:done or is:overdue)", word)
+	}
+
+	// Not a known key, so search for the text as typed
+	return matchText(lower), nil
+}
+
+// splitValues splits a comma-separated list of values
+func splitValues(value string) []string {
+	var values []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// matchField matches tasks whose field equals one of values, ignoring case
+func matchField(field func(TaskMetadata) string, values []string) func(taskFile, time.Time) bool {
+	return func(task taskFile, _ time.Time) bool {
+		return containsFold(values, field(task.metadata))
+	}
+}
+
+// matchTags matches tasks that have any of the tags, or a tag nested
+// below one of them
+func matchTags(tags []string) func(taskFile, time.Time) bool {
+	return func(task taskFile, _ time.Time) bool {
+		for _, tag := range task.metadata.Tags {
+			for _, filter := range tags {
+				if tagMatches(tag, filter) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+}
+
+// matchText matches tasks with the text in their filename, title, status
+// or tags
+func matchText(text string) func(taskFile, time.Time) bool {
+	return func(task taskFile, _ time.Time) bool {
+		if strings.Contains(strings.ToLower(task.name), text) ||
+			strings.Contains(strings.ToLower(task.metadata.Title), text) ||
+			strings.Contains(strings.ToLower(task.metadata.Status), text) {
+			return true
+		}
+		for _, tag := range task.metadata.Tags {
+			if strings.Contains(strings.ToLower(tag), text) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// dueMatches checks a task's due date against a due: filter value. Done
+// tasks are never overdue.
+func dueMatches(meta TaskMetadata, value string, now time.Time) bool {
+	today, _ := parseSince("today", now)
+	due := meta.DueDate
+
+	switch value {
+	case "none":
+		return due.IsZero()
+	case "any":
+		return !due.IsZero()
+	case "overdue":
+		return !due.IsZero() && due.Before(today) && !isDoneStatus(meta.Status)
+	case "today":
+		return !due.IsZero() && !due.Before(today) && due.Before(today.AddDate(0, 0, 1))
+	case "week":
+		return !due.IsZero() && !due.Before(today) && due.Before(today.AddDate(0, 0, 7))
+	}
+	return false
+}
+
+// matches reports whether a task satisfies every term of the filter
+func (f taskFilter) matches(task taskFile, now time.Time) bool {
+	for _, term := range f.terms {
+		if term.match(task, now) == term.negate {
+			return false
+		}
+	}
+	return true
+}
+
+// apply returns the tasks that match the filter
+func (f taskFilter) apply(tasks []taskFile, now time.Time) []taskFile {
+	if len(f.terms) == 0 {
+		return tasks
+	}
+	matched := []taskFile{}
+	for _, task := range tasks {
+		if f.matches(task, now) {
+			matched = append(matched, task)
+		}
+	}
+	return matched
+}
+
+// Sort keys for views and the list command
+var sortKeys = []string{"modified", "created", "due", "priority", "title", "status"}
+
+// sortTasks sorts tasks in place by key: modified and created sort newest
+// first, due soonest first (tasks without a due date last), priority
+// highest first, title alphabetically and status in workflow order. A
+// leading - reverses the order. Ties keep their existing order.
+func sortTasks(tasks []taskFile, key string) error {
+	reverse := strings.HasPrefix(key, "-")
+	key = strings.TrimPrefix(key, "-")
+
+	var less func(a, b taskFile) bool
+	switch key {
+	case "", "modified":
+		less = func(a, b taskFile) bool { return a.modTime.After(b.modTime) }
+	case "created":
+		less = func(a, b taskFile) bool { return a.metadata.Created.After(b.metadata.Created) }
+	case "due":
+		less = func(a, b taskFile) bool {
+			da, db := a.metadata.DueDate, b.metadata.DueDate
+			if da.IsZero() != db.IsZero() {
+				return db.IsZero()
+			}
+			return da.Before(db)
+		}
+	case "priority":
+		less = func(a, b taskFile) bool { return priorityRank(a.metadata.Priority) < priorityRank(b.metadata.Priority) }
+	case "title":
+		less = func(a, b taskFile) bool { return strings.ToLower(displayTitle(a)) < strings.ToLower(displayTitle(b)) }
+	case "status":
+		less = func(a, b taskFile) bool { return statusRank(a.metadata.Status) < statusRank(b.metadata.Status) }
+	default:
+		return fmt.Errorf("unknown sort %q (use %s)", key, strings.Join(sortKeys, ", "))
+	}
+
+	sort.SliceStable(tasks, func(i, j int) bool {
+		if reverse {
+			return less(tasks[j], tasks[i])
+		}
+		return less(tasks[i], tasks[j])
+	})
+	return nil
+}
+
+// priorityRank orders priorities from high to none
+func priorityRank(priority string) int {
+	switch strings.ToLower(priority) {
+	case "high":
+		return 0
+	case "medium":
+		return 1
+	case "low":
+		return 2
+	}
+	return 3
+}
+
+// statusRank orders statuses by the workflow, unknown ones last
+func statusRank(status string) int {
+	switch {
+	case isDoneStatus(status):
+		return 2
+	case isInProgressStatus(status):
+		return 1
+	case status == "" || status == "todo":
+		return 0
+	}
+	return 3
+}
+
+// displayTitle returns a task's title, or its filename if it has none
+func displayTitle(task taskFile) string {
+	if task.metadata.Title != "" {
+		return task.metadata.Title
+	}
+	return task.name
+}
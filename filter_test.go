@@ -0,0 +1,127 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// filterTestTasks returns tasks for the filter and sort tests, due around
+// Wednesday 2025-01-08, named a.md, b.md, ...
+func filterTestTasks() []taskFile {
+	day := func(d int) time.Time { return time.Date(2025, 1, d, 0, 0, 0, 0, time.Local) }
+	tasks := []taskFile{
+		{sourceDir: "~/Projects/project-a/tasks", project: "WEB", metadata: TaskMetadata{ID: "k3x9p2", Title: "Fix login", Status: "todo", Priority: "high", Tags: []string{"backend/api"}, DueDate: day(7), Created: day(1)}},
+		{sourceDir: "~/notes", metadata: TaskMetadata{Title: "Write docs", Status: "done", Priority: "low", Tags: []string{"docs"}, DueDate: day(5), Created: day(3)}},
+		{sourceDir: "~/notes", metadata: TaskMetadata{Title: "Plan sprint", Status: "in-progress", Tags: []string{"Backend"}, DueDate: day(8).Add(17 * time.Hour), Created: day(2)}},
+		{sourceDir: "~/notes", metadata: TaskMetadata{Title: "call bank", Status: "todo", Priority: "medium"}},
+		{sourceDir: "~/notes", metadata: TaskMetadata{Status: "waiting", DueDate: day(14), Created: day(2)}},
+		{sourceDir: "~/notes", metadata: TaskMetadata{Title: "Next month", DueDate: day(15)}},
+	}
+	for i := range tasks {
+		tasks[i].name = string(rune('a'+i)) + ".md"
+		tasks[i].modTime = day(20 - i)
+	}
+	return tasks
+}
+
+// taskNames lists the names of tasks, in order
+func taskNames(tasks []taskFile) string {
+	var names []string
+	for _, task := range tasks {
+		names = append(names, strings.TrimSuffix(task.name, ".md"))
+	}
+	return strings.Join(names, " ")
+}
+
+func TestParseQuery(t *testing.T) {
+	now := time.Date(2025, 1, 8, 15, 30, 0, 0, time.Local)
+	tasks := filterTestTasks()
+	for _, test := range []struct {
+		query string
+		want  string // Names of the matching tasks, or "error"
+	}{
+		{"", "a b c d e f"},
+		{"status:todo", "a d"},
+		{"status:TODO,in-progress", "a c d"},
+		{"-status:done", "a c d e f"},
+		{"#backend", "a c"},
+		{"tag:backend/api", "a"},
+		{"-#backend", "b d e f"},
+		{"!high", "a"},
+		{"priority:high,medium", "a d"},
+		{"-priority:high,medium", "b c e f"},
+		{"due:overdue", "a"},
+		{"due:today", "c"},
+		{"due:week", "c e"},
+		{"due:none", "d"},
+		{"due:any", "a b c e f"},
+		{"-due:any", "d"},
+		{"-due:overdue -due:none", "b c e f"},
+		{"is:open", "a c d e f"},
+		{"is:done", "b"},
+		{"is:overdue", "a"},
+		{"-is:overdue is:open", "c d e f"},
+		{"dir:project-a", "a"},
+		{"dir:notes", "b c d e f"},
+		{"project:web", "a"},
+		{"id:K3X9P2", "a"},
+		{"docs", "b"},
+		{"Login -is:done", "a"},
+		{"e.md", "e"},
+		{"wait", "e"},
+		{"key:value", ""},
+		{"due:tomorrow", "error"},
+		{"is:later", "error"},
+		{"!urgent", "error"},
+	} {
+		filter, err := parseQuery(test.query)
+		if test.want == "error" {
+			if err == nil {
+				t.Errorf("parseQuery(%q) succeeded, want an error", test.query)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseQuery(%q): %v", test.query, err)
+			continue
+		}
+		if got := taskNames(filter.apply(tasks, now)); got != test.want {
+			t.Errorf("%q matched %q, want %q", test.query, got, test.want)
+		}
+	}
+}
+
+func TestSortTasks(t *testing.T) {
+	for _, test := range []struct {
+		key  string
+		want string // Names of the tasks in order, or "error"
+	}{
+		{"", "a b c d e f"},
+		{"-modified", "f e d c b a"},
+		{"created", "b c e a d f"},
+		{"due", "b a c e f d"},
+		{"-due", "d f e c a b"},
+		{"priority", "a d b c e f"},
+		{"title", "d e a f c b"},
+		{"status", "a d f c b e"},
+		{"-status", "e b c a d f"},
+		{"size", "error"},
+	} {
+		tasks := filterTestTasks()
+		err := sortTasks(tasks, test.key)
+		if test.want == "error" {
+			if err == nil {
+				t.Errorf("sortTasks(%q) succeeded, want an error", test.key)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("sortTasks(%q): %v", test.key, err)
+			continue
+		}
+		if got := taskNames(tasks); got != test.want {
+			t.Errorf("sorted by %q: %q, want %q", test.key, got, test.want)
+		}
+	}
+}
@@ -1,42 +1,293 @@
 package main
 
 import (
+	"bytes"
+	"fmt"
 	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/adrg/frontmatter"
+	"gopkg.in/yaml.v2"
 )
 
 // TaskMetadata represents the frontmatter fields we care about
 type TaskMetadata struct {
-	Title    string    `yaml:"title"`
-	Status   string    `yaml:"status"`   // todo, in-progress, done
-	Priority string    `yaml:"priority"` // low, medium, high
-	DueDate  time.Time `yaml:"due_date"`
-	Tags     []string  `yaml:"tags"`
-	Created  time.Time `yaml:"created"`
+	ID            string         `yaml:"id"` // Short stable ID, kept across renames and moves
+	Title         string         `yaml:"title"`
+	Status        string         `yaml:"status"`   // todo, in-progress, done
+	Priority      string         `yaml:"priority"` // low, medium, high
+	DueDate       time.Time      `yaml:"due_date"`
+	Tags          []string       `yaml:"tags"`
+	Created       time.Time      `yaml:"created"`
+	Completed     time.Time      `yaml:"completed"`      // Set when the task enters a done status
+	TimeLog       []timeEntry    `yaml:"time_log"`       // Time spent, appended by the timer
+	StatusHistory []statusChange `yaml:"status_history"` // Status changes made through the app
+
+	// Read from the rest of the file for list columns
+	Fields     map[string]string `yaml:"-"` // Other frontmatter fields, as text
+	Checked    int               `yaml:"-"` // Ticked checkboxes ("- [x]") in the body
+	Checkboxes int               `yaml:"-"` // All checkboxes in the body
 }
 
+// knownFields are the frontmatter keys TaskMetadata has fields for
+var knownFields = []string{"id", "title", "status", "priority", "due_date", "tags", "created", "completed", "time_log", "status_history"}
+
 // parseFrontmatter extracts metadata from a markdown file's frontmatter
 func parseFrontmatter(filePath string) (TaskMetadata, error) {
-	var meta TaskMetadata
-
-	// Open the file
-	file, err := os.Open(filePath)
+	content, err := os.ReadFile(filePath)
 	if err != nil {
-		return meta, err
+		return TaskMetadata{}, err
 	}
-	defer file.Close()
+	return parseFrontmatterBytes(content), nil
+}
 
-	// Parse frontmatter (ignore the content body for now)
-	_, err = frontmatter.Parse(file, &meta)
+// parseFrontmatterBytes extracts metadata from a task file's contents
+func parseFrontmatterBytes(content []byte) TaskMetadata {
+	var meta TaskMetadata
+	body, err := frontmatter.Parse(bytes.NewReader(content), &meta)
+	if err != nil {
+		// Older versions quoted dates when updating a file, which don't
+		// parse as times; read those files with the quotes removed
+		if front, rest, ok := splitFrontmatter(content); ok {
+			if unquoted := unquoteTimestamps([]byte(front)); !bytes.Equal(unquoted, []byte(front)) {
+				content = []byte("---\n" + string(unquoted) + "---\n" + rest)
+				meta = TaskMetadata{}
+				body, err = frontmatter.Parse(bytes.NewReader(content), &meta)
+			}
+		}
+	}
 	if err != nil {
 		// If there's no frontmatter or it's malformed, return empty metadata
 		// This is not an error - files without frontmatter are valid
-		return TaskMetadata{}, nil
+		return TaskMetadata{}
+	}
+
+	meta.Fields = otherFields(content)
+	meta.Checked, meta.Checkboxes = countCheckboxes(body)
+	return meta
+}
+
+// otherFields returns the frontmatter fields TaskMetadata doesn't have
+// a field for, formatted as text. Lists are joined with commas and
+// nested maps are left out.
+func otherFields(content []byte) map[string]string {
+	var raw map[string]interface{}
+	if _, err := frontmatter.Parse(bytes.NewReader(content), &raw); err != nil {
+		return nil
+	}
+
+	var fields map[string]string
+	for key, value := range raw {
+		if containsFold(knownFields, key) {
+			continue
+		}
+		text, ok := fieldText(value)
+		if !ok {
+			continue
+		}
+		if fields == nil {
+			fields = map[string]string{}
+		}
+		fields[strings.ToLower(key)] = text
+	}
+	return fields
+}
+
+// fieldText formats a frontmatter value for display
+func fieldText(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case nil:
+		return "", true
+	case time.Time:
+		if isMidnight(v) {
+			return v.Format("2006-01-02"), true
+		}
+		return v.Format("2006-01-02 15:04"), true
+	case []interface{}:
+		var items []string
+		for _, item := range v {
+			text, ok := fieldText(item)
+			if !ok {
+				return "", false
+			}
+			items = append(items, text)
+		}
+		return strings.Join(items, ", "), true
+	case map[interface{}]interface{}, map[string]interface{}:
+		return "", false
+	}
+	return fmt.Sprint(value), true
+}
+
+// checkbox matches a markdown task list item, capturing its mark
+var checkbox = regexp.MustCompile(`(?m)^\s*[-*+] \[([ xX])\]`)
+
+// countCheckboxes counts the ticked and total checkboxes in a body
+func countCheckboxes(body []byte) (checked, total int) {
+	for _, match := range checkbox.FindAllSubmatch(body, -1) {
+		total++
+		if match[1][0] != ' ' {
+			checked++
+		}
+	}
+	return checked, total
+}
+
+// renderTaskFile builds the contents of a new task file from metadata
+// and a markdown body. Only fields that are set are written, in the same
+// order as the default template, followed by any other Fields.
+func renderTaskFile(meta TaskMetadata, body string) ([]byte, error) {
+	var fields yaml.MapSlice
+	if meta.ID != "" {
+		fields = append(fields, yaml.MapItem{Key: "id", Value: meta.ID})
+	}
+	if meta.Title != "" {
+		fields = append(fields, yaml.MapItem{Key: "title", Value: meta.Title})
+	}
+	if meta.Status != "" {
+		fields = append(fields, yaml.MapItem{Key: "status", Value: meta.Status})
+	}
+	if meta.Priority != "" {
+		fields = append(fields, yaml.MapItem{Key: "priority", Value: meta.Priority})
+	}
+	if !meta.DueDate.IsZero() {
+		fields = append(fields, yaml.MapItem{Key: "due_date", Value: meta.DueDate})
+	}
+	if len(meta.Tags) > 0 {
+		fields = append(fields, yaml.MapItem{Key: "tags", Value: meta.Tags})
+	}
+	if !meta.Created.IsZero() {
+		fields = append(fields, yaml.MapItem{Key: "created", Value: meta.Created})
+	}
+	if !meta.Completed.IsZero() {
+		fields = append(fields, yaml.MapItem{Key: "completed", Value: meta.Completed})
+	}
+	for _, key := range sortedKeys(meta.Fields) {
+		fields = append(fields, yaml.MapItem{Key: key, Value: meta.Fields[key]})
+	}
+
+	out, err := yaml.Marshal(fields)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode frontmatter: %w", err)
 	}
 
-	return meta, nil
+	return []byte("---\n" + string(out) + "---\n\n" + body), nil
+}
+
+// splitFrontmatter separates a file's YAML frontmatter from its body.
+// ok is false when the file doesn't start with a --- block.
+func splitFrontmatter(content []byte) (front, body string, ok bool) {
+	text := string(content)
+	var rest string
+	if r, found := strings.CutPrefix(text, "---\n"); found {
+		rest = r
+	} else if r, found := strings.CutPrefix(text, "---\r\n"); found {
+		rest = r
+	} else {
+		return "", text, false
+	}
+
+	// Find the closing --- on a line of its own
+	offset := 0
+	for {
+		line, after, more := strings.Cut(rest[offset:], "\n")
+		if strings.TrimRight(line, "\r") == "---" {
+			return rest[:offset], after, true
+		}
+		if !more {
+			return "", text, false
+		}
+		offset += len(line) + 1
+	}
+}
+
+// updateFrontmatter rewrites a task file's frontmatter through update,
+// keeping every other field, the field order and the body as they were.
+// A file without frontmatter gets a new block. YAML comments inside the
+// frontmatter are not preserved.
+func updateFrontmatter(filePath string, update func(fields *yaml.MapSlice)) error {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+
+	front, body, ok := splitFrontmatter(content)
+	if !ok && (strings.HasPrefix(body, "+++") || strings.HasPrefix(body, ";;;")) {
+		return fmt.Errorf("%s: only YAML frontmatter can be updated", filepath.Base(filePath))
+	}
+
+	var fields yaml.MapSlice
+	if err := yaml.Unmarshal([]byte(front), &fields); err != nil {
+		return fmt.Errorf("%s: invalid frontmatter: %w", filepath.Base(filePath), err)
+	}
+
+	update(&fields)
+
+	out, err := yaml.Marshal(fields)
+	if err != nil {
+		return fmt.Errorf("failed to encode frontmatter: %w", err)
+	}
+	if len(fields) == 0 {
+		out = nil
+	}
+	out = unquoteTimestamps(out)
+
+	if !ok {
+		// Keep the body separated from the new block by a blank line
+		body = "\n" + body
+	}
+
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filePath, []byte("---\n"+string(out)+"---\n"+body), info.Mode().Perm())
+}
+
+// quotedTimestamp matches a scalar that yaml.v2 quoted because it looks
+// like a timestamp, such as due_date: "2025-12-31"
+var quotedTimestamp = regexp.MustCompile(`(?m)^(\s*(?:- )?(?:[A-Za-z0-9_-]+: )?)"(\d{4}-\d{1,2}-\d{1,2}(?:[Tt ][0-9:.]+(?:[Zz]|[+-]\d{2}:?\d{2})?)?)"$`)
+
+// unquoteTimestamps removes the quotes yaml.v2 adds around dates and
+// times it read as plain strings, so they stay dates in the file and
+// still parse into time.Time fields
+func unquoteTimestamps(out []byte) []byte {
+	return quotedTimestamp.ReplaceAll(out, []byte("$1$2"))
+}
+
+// getField returns the value of a frontmatter field
+func getField(fields yaml.MapSlice, key string) (interface{}, bool) {
+	for _, item := range fields {
+		if item.Key == key {
+			return item.Value, true
+		}
+	}
+	return nil, false
+}
+
+// setField sets a frontmatter field, replacing it in place if present
+// or adding it at the end otherwise
+func setField(fields *yaml.MapSlice, key string, value interface{}) {
+	for i, item := range *fields {
+		if item.Key == key {
+			(*fields)[i].Value = value
+			return
+		}
+	}
+	*fields = append(*fields, yaml.MapItem{Key: key, Value: value})
+}
+
+// deleteField removes a frontmatter field if present
+func deleteField(fields *yaml.MapSlice, key string) {
+	for i, item := range *fields {
+		if item.Key == key {
+			*fields = append((*fields)[:i], (*fields)[i+1:]...)
+			return
+		}
+	}
 }
 
 // getStatusEmoji returns an emoji for the task status
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// sampleMeta is a task with every kind of field set
+func sampleMeta() TaskMetadata {
+	return TaskMetadata{
+		ID:        "k3x9p2",
+		Title:     "Fix login redirect",
+		Status:    "todo",
+		Priority:  "high",
+		DueDate:   time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC),
+		Tags:      []string{"auth", "web"},
+		Created:   time.Date(2025, 1, 2, 10, 30, 0, 0, time.UTC),
+		Completed: time.Date(2025, 1, 3, 9, 0, 0, 0, time.UTC),
+		Fields:    map[string]string{"estimate": "3h"},
+	}
+}
+
+// checkSameMeta compares the fields a task file round-trips
+func checkSameMeta(t *testing.T, got, want TaskMetadata) {
+	t.Helper()
+	if got.ID != want.ID || got.Title != want.Title || got.Status != want.Status || got.Priority != want.Priority {
+		t.Errorf("got %q %q %q %q, want %q %q %q %q", got.ID, got.Title, got.Status, got.Priority, want.ID, want.Title, want.Status, want.Priority)
+	}
+	for _, field := range []struct {
+		name      string
+		got, want time.Time
+	}{{"due_date", got.DueDate, want.DueDate}, {"created", got.Created, want.Created}, {"completed", got.Completed, want.Completed}} {
+		if !field.got.Equal(field.want) {
+			t.Errorf("%s = %v, want %v", field.name, field.got, field.want)
+		}
+	}
+	if !reflect.DeepEqual(got.Tags, want.Tags) {
+		t.Errorf("tags = %v, want %v", got.Tags, want.Tags)
+	}
+	if !reflect.DeepEqual(got.Fields, want.Fields) {
+		t.Errorf("fields = %v, want %v", got.Fields, want.Fields)
+	}
+}
+
+// writeTask renders a task into a temporary file and returns its path
+func writeTask(t *testing.T, meta TaskMetadata) string {
+	t.Helper()
+	content, err := renderTaskFile(meta, "# "+meta.Title+"\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "task.md")
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRenderTaskFileRoundTrip(t *testing.T) {
+	want := sampleMeta()
+	got, err := parseFrontmatter(writeTask(t, want))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSameMeta(t, got, want)
+}
+
+func TestUpdateFrontmatterKeepsDates(t *testing.T) {
+	want := sampleMeta()
+	path := writeTask(t, want)
+
+	err := updateFrontmatter(path, func(fields *yaml.MapSlice) {
+		setField(fields, "status", "in-progress")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, _ := os.ReadFile(path)
+	if strings.Contains(string(content), `"20`) {
+		t.Errorf("dates were quoted:\n%s", content)
+	}
+
+	got, _ := parseFrontmatter(path)
+	want.Status = "in-progress"
+	checkSameMeta(t, got, want)
+}
+
+func TestUpdateFrontmatterKeepsHandwrittenDates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "task.md")
+	content := "---\ntitle: Pay bills\ndue_date: 2025-12-31\ncreated: 2025-01-02 10:30:00\n---\n\n# Pay bills\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Logging time is how the bug was first seen
+	entry := timeEntry{Start: time.Date(2025, 1, 5, 9, 0, 0, 0, time.UTC), End: time.Date(2025, 1, 5, 10, 0, 0, 0, time.UTC)}
+	if err := appendTimeEntry(path, entry); err != nil {
+		t.Fatal(err)
+	}
+
+	updated, _ := os.ReadFile(path)
+	if !strings.Contains(string(updated), "due_date: 2025-12-31\n") {
+		t.Errorf("due_date changed:\n%s", updated)
+	}
+	meta, _ := parseFrontmatter(path)
+	if meta.Title != "Pay bills" || meta.DueDate.Format("2006-01-02") != "2025-12-31" || len(meta.TimeLog) != 1 {
+		t.Errorf("metadata lost after update: %+v", meta)
+	}
+}
+
+func TestParseFrontmatterReadsQuotedDates(t *testing.T) {
+	// As written by updateFrontmatter before dates were unquoted
+	content := "---\ntitle: Pay bills\nstatus: todo\ndue_date: \"2025-12-31\"\ncreated: \"2025-01-02T10:30:00Z\"\n---\n\n# Pay bills\n"
+	meta := parseFrontmatterBytes([]byte(content))
+	if meta.Title != "Pay bills" || meta.Status != "todo" {
+		t.Errorf("metadata lost: %+v", meta)
+	}
+	if meta.DueDate.Format("2006-01-02") != "2025-12-31" || !meta.Created.Equal(time.Date(2025, 1, 2, 10, 30, 0, 0, time.UTC)) {
+		t.Errorf("dates = %v, %v", meta.DueDate, meta.Created)
+	}
+}
+
+func TestUnquoteTimestamps(t *testing.T) {
+	for in, want := range map[string]string{
+		"due_date: \"2025-12-31\"\n":               "due_date: 2025-12-31\n",
+		"created: \"2025-01-02T10:30:00Z\"\n":      "created: 2025-01-02T10:30:00Z\n",
+		"- start: \"2025-01-02T10:30:00+01:00\"\n": "- start: 2025-01-02T10:30:00+01:00\n",
+		"title: \"2025-12-31 release\"\n":          "title: \"2025-12-31 release\"\n",
+		"version: \"2025\"\n":                      "version: \"2025\"\n",
+	} {
+		if got := string(unquoteTimestamps([]byte(in))); got != want {
+			t.Errorf("unquoteTimestamps(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
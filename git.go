@@ -0,0 +1,336 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Default commit messages for each kind of change, used unless
+// overridden under [git.messages] in the config
+var defaultCommitMessages = map[string]string{
+	"create":  "task: add {{title}}",
+	"edit":    "task: update {{title}}",
+	"status":  "task: mark {{title}} {{status}}",
+	"delete":  "task: delete {{title}}",
+	"move":    "task: move {{title}} to {{dir}}",
+	"copy":    "task: copy {{title}} to {{dir}}",
+	"rename":  "task: rename {{title}}",
+	"restore": "task: restore {{title}}",
+	"time":    "task: log time on {{title}}",
+	"tags":    "task: {{title}}",
+	"import":  "task: {{title}}",
+	"todotxt": "task: {{title}}",
+}
+
+// gitFileState is a task file's state in its git repository
+type gitFileState int
+
+const (
+	gitClean     gitFileState = iota // Committed and unchanged
+	gitModified                      // Changed (or staged) since the last commit
+	gitUntracked                     // Not tracked by git yet
+)
+
+// runGit runs a git command in dir and returns its trimmed output
+func runGit(dir string, args ...string) (string, error) {
+	out, err := runGitRaw(dir, args...)
+	return strings.TrimSpace(out), err
+}
+
+// runGitRaw runs a git command in dir and returns its output as is.
+// Errors include git's own message, which is usually the useful part.
+func runGitRaw(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return "", fmt.Errorf("git %s: %s", args[0], msg)
+	}
+	return stdout.String(), nil
+}
+
+// gitRepoRoot returns the top-level directory of the git repository
+// containing dir, or ok=false if dir isn't in one (or git isn't installed)
+func gitRepoRoot(dir string) (string, bool) {
+	expandedDir, err := expandPath(dir)
+	if err != nil {
+		return "", false
+	}
+
+	// Work from the relative path up to the root rather than
+	// --show-toplevel, so the root is spelled the same way as dir even
+	// when symlinks are involved
+	cdup, err := runGit(expandedDir, "rev-parse", "--show-cdup")
+	if err != nil {
+		return "", false
+	}
+	return filepath.Clean(filepath.Join(expandedDir, cdup)), true
+}
+
+// gitRepoRoots maps each configured directory that lives in a git
+// repository to that repository's root
+func gitRepoRoots(dirs []string) map[string]string {
+	roots := make(map[string]string)
+	for _, dir := range dirs {
+		if root, ok := gitRepoRoot(dir); ok {
+			roots[dir] = root
+		}
+	}
+	return roots
+}
+
+// uniqueRoots returns the distinct repository roots, sorted
+func uniqueRoots(roots map[string]string) []string {
+	seen := make(map[string]bool)
+	var unique []string
+	for _, root := range roots {
+		if !seen[root] {
+			seen[root] = true
+			unique = append(unique, root)
+		}
+	}
+	sort.Strings(unique)
+	return unique
+}
+
+// gitFileStates returns the state of every changed or untracked file in
+// the given repositories, keyed by absolute path. Files that aren't in
+// the map are clean (or not in a repository at all).
+func gitFileStates(roots []string) map[string]gitFileState {
+	states := make(map[string]gitFileState)
+
+	for _, root := range roots {
+		out, err := runGitRaw(root, "status", "--porcelain=v1", "-z", "--untracked-files=all")
+		if err != nil {
+			continue
+		}
+
+		// Entries are "XY path\0", renames add "\0origpath"
+		entries := strings.Split(out, "\x00")
+		for i := 0; i < len(entries); i++ {
+			entry := entries[i]
+			if len(entry) < 4 {
+				continue
+			}
+
+			code, path := entry[:2], entry[3:]
+			fullPath := filepath.Join(root, filepath.FromSlash(path))
+			if code == "??" {
+				states[fullPath] = gitUntracked
+			} else {
+				states[fullPath] = gitModified
+			}
+
+			if code[0] == 'R' || code[0] == 'C' {
+				i++ // Skip the original path
+			}
+		}
+	}
+
+	return states
+}
+
+// commitMessage fills in a commit message template. Supported variables
+// are {{title}}, {{status}}, {{dir}}, {{file}} and {{id}}.
+func commitMessage(template string, meta TaskMetadata, file, dir string) string {
+	title := meta.Title
+	if title == "" {
+		title = strings.TrimSuffix(filepath.Base(file), ".md")
+	}
+
+	return strings.NewReplacer(
+		"{{title}}", title,
+		"{{status}}", meta.Status,
+		"{{dir}}", dir,
+		"{{file}}", filepath.Base(file),
+		"{{id}}", meta.ID,
+	).Replace(template)
+}
+
+// gitCommitPaths commits the given paths (including deletions) in every
+// repository they belong to. Paths outside a repository are ignored, and
+// a repository with nothing to commit is skipped.
+func gitCommitPaths(message string, paths ...string) error {
+	byRoot := make(map[string][]string)
+	for _, path := range paths {
+		root, ok := gitRepoRoot(filepath.Dir(path))
+		if !ok {
+			continue
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+
+		// A deleted file only needs committing if git knew about it
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			if _, err := runGit(root, "ls-files", "--error-unmatch", "--", rel); err != nil {
+				continue
+			}
+		}
+
+		byRoot[root] = append(byRoot[root], rel)
+	}
+
+	for root, rels := range byRoot {
+		// Stage additions, changes and deletions of just these paths
+		addArgs := append([]string{"add", "-A", "--"}, rels...)
+		if _, err := runGit(root, addArgs...); err != nil {
+			return err
+		}
+
+		// Nothing staged for these paths (e.g. an edit that changed nothing)
+		diffArgs := append([]string{"diff", "--cached", "--quiet", "--"}, rels...)
+		if _, err := runGit(root, diffArgs...); err == nil {
+			continue
+		}
+
+		commitArgs := append([]string{"commit", "-m", message, "--"}, rels...)
+		if _, err := runGit(root, commitArgs...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// autoCommit commits a change to a task if auto-commit is enabled.
+// action picks the message template ("create", "edit", "status", ...),
+// meta and dir fill it in, and paths lists every file that changed.
+func (c *GitConfig) autoCommit(action string, meta TaskMetadata, dir string, paths ...string) error {
+	if !c.AutoCommit || len(paths) == 0 {
+		return nil
+	}
+
+	message := commitMessage(c.GetMessage(action), meta, paths[0], dir)
+	if err := gitCommitPaths(message, paths...); err != nil {
+		return fmt.Errorf("auto-commit failed: %w", err)
+	}
+	return nil
+}
+
+// gitSync pulls (rebasing local commits) and then pushes each repository.
+// It returns a short summary line per repository.
+func gitSync(roots []string) []string {
+	var results []string
+
+	for _, root := range roots {
+		name := filepath.Base(root)
+
+		if _, err := runGit(root, "rev-parse", "--abbrev-ref", "--symbolic-full-name", "@{u}"); err != nil {
+			results = append(results, fmt.Sprintf("%s: no upstream branch", name))
+			continue
+		}
+		if _, err := runGit(root, "pull", "--rebase", "--autostash"); err != nil {
+			results = append(results, fmt.Sprintf("%s: %v", name, err))
+			continue
+		}
+		if _, err := runGit(root, "push"); err != nil {
+			results = append(results, fmt.Sprintf("%s: %v", name, err))
+			continue
+		}
+		results = append(results, fmt.Sprintf("%s: synced", name))
+	}
+
+	return results
+}
+
+// gitRevision is one commit in a task file's history
+type gitRevision struct {
+	hash    string    // Full commit hash
+	author  string    // Author name
+	date    time.Time // Author date
+	subject string    // First line of the commit message
+	path    string    // The file's path at this commit, relative to the repository root
+}
+
+// shortHash returns the abbreviated commit hash shown in the UI
+func (r gitRevision) shortHash() string {
+	if len(r.hash) > 7 {
+		return r.hash[:7]
+	}
+	return r.hash
+}
+
+// gitFileHistory lists the commits that touched a task file, newest
+// first, following it across renames. It returns the repository root
+// the revisions belong to.
+func gitFileHistory(taskPath string) ([]gitRevision, string, error) {
+	root, ok := gitRepoRoot(filepath.Dir(taskPath))
+	if !ok {
+		return nil, "", fmt.Errorf("task is not in a git repository")
+	}
+	rel, err := filepath.Rel(root, taskPath)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Each commit starts with a record separator, then its fields and
+	// the name of the file at that commit on the following line
+	out, err := runGit(root, "log", "--follow", "--name-only",
+		"--format=%x1e%H%x1f%an%x1f%aI%x1f%s", "--", filepath.ToSlash(rel))
+	if err != nil {
+		return nil, root, err
+	}
+
+	var revisions []gitRevision
+	for _, record := range strings.Split(out, "\x1e") {
+		record = strings.TrimSpace(record)
+		if record == "" {
+			continue
+		}
+
+		header, path, _ := strings.Cut(record, "\n")
+		fields := strings.SplitN(header, "\x1f", 4)
+		if len(fields) != 4 {
+			continue
+		}
+
+		date, _ := time.Parse(time.RFC3339, fields[2])
+		revisions = append(revisions, gitRevision{
+			hash:    fields[0],
+			author:  fields[1],
+			date:    date,
+			subject: fields[3],
+			path:    strings.TrimSpace(path),
+		})
+	}
+
+	return revisions, root, nil
+}
+
+// gitRevisionDiff returns the changes a revision made to the task file
+func gitRevisionDiff(root string, rev gitRevision) (string, error) {
+	// --follow keeps a rename shown as a rename rather than a new file
+	return runGitRaw(root, "log", "-1", "-p", "--follow", "--no-color", "--format=", rev.hash, "--", rev.path)
+}
+
+// restoreRevision overwrites a task file with its content at a revision
+func restoreRevision(taskPath, root string, rev gitRevision) error {
+	content, err := runGitRaw(root, "show", rev.hash+":"+rev.path)
+	if err != nil {
+		return fmt.Errorf("failed to read revision %s: %w", rev.shortHash(), err)
+	}
+
+	info, err := os.Stat(taskPath)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(taskPath, []byte(content), info.Mode().Perm()); err != nil {
+		return fmt.Errorf("failed to restore task: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// git runs a git command for a test and returns its trimmed output
+func git(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	out, err := runGit(dir, args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+// newRemote creates a bare repository with one commit on main and
+// returns its path
+func newRemote(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	// Keep the user's git config out of the tests
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+
+	remote := filepath.Join(t.TempDir(), "tasks.git")
+	git(t, filepath.Dir(remote), "init", "--bare", "--initial-branch=main", remote)
+
+	seed := t.TempDir()
+	git(t, seed, "init", "-q", "--initial-branch=main")
+	writeFile(t, filepath.Join(seed, "README.md"), "# Tasks\n")
+	git(t, seed, "add", "README.md")
+	git(t, seed, "-c", "user.name=Test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "initial")
+	git(t, seed, "push", "-q", remote, "main")
+	return remote
+}
+
+// cloneRemote clones a remote into a new directory with a commit identity
+func cloneRemote(t *testing.T, remote string) string {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "work")
+	git(t, filepath.Dir(dir), "clone", "-q", remote, dir)
+	git(t, dir, "config", "user.name", "Test")
+	git(t, dir, "config", "user.email", "test@example.com")
+	git(t, dir, "checkout", "-q", "-B", "main", "--track", "origin/main")
+	return dir
+}
+
+// writeFile writes a file for a test, creating its directory
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// commitCount returns the number of commits on HEAD
+func commitCount(t *testing.T, dir string) string {
+	return git(t, dir, "rev-list", "--count", "HEAD")
+}
+
+func TestGitCommitPaths(t *testing.T) {
+	work := cloneRemote(t, newRemote(t))
+	a := filepath.Join(work, "tasks", "a.md")
+	b := filepath.Join(work, "tasks", "b.md")
+	outside := filepath.Join(t.TempDir(), "c.md")
+	writeFile(t, a, "# A\n")
+	writeFile(t, b, "# B\n")
+	writeFile(t, outside, "# C\n")
+
+	// Only the given paths are committed; files outside a repo are ignored
+	if err := gitCommitPaths("task: add A", a, outside); err != nil {
+		t.Fatal(err)
+	}
+	if got := git(t, work, "log", "-1", "--format=%s"); got != "task: add A" {
+		t.Errorf("subject = %q", got)
+	}
+	if got := git(t, work, "show", "--name-only", "--format=", "HEAD"); got != "tasks/a.md" {
+		t.Errorf("committed %q, want tasks/a.md", got)
+	}
+	if got := git(t, work, "status", "--porcelain", "--", "tasks/b.md"); got != "?? tasks/b.md" {
+		t.Errorf("b.md status = %q, want untracked", got)
+	}
+
+	// Nothing changed, so nothing is committed
+	before := commitCount(t, work)
+	if err := gitCommitPaths("task: update A", a); err != nil {
+		t.Fatal(err)
+	}
+	if got := commitCount(t, work); got != before {
+		t.Errorf("an unchanged file made a commit (%s -> %s commits)", before, got)
+	}
+
+	// Deletions are committed, and deleting an untracked file is skipped
+	os.Remove(a)
+	os.Remove(b)
+	if err := gitCommitPaths("task: delete", a, b); err != nil {
+		t.Fatal(err)
+	}
+	if got := git(t, work, "show", "--name-status", "--format=", "HEAD"); got != "D\ttasks/a.md" {
+		t.Errorf("commit = %q, want the deletion of tasks/a.md", got)
+	}
+}
+
+func TestAutoCommit(t *testing.T) {
+	work := cloneRemote(t, newRemote(t))
+	path := filepath.Join(work, "fix-login.md")
+	writeFile(t, path, "---\ntitle: Fix login\n---\n")
+	meta := TaskMetadata{Title: "Fix login", Status: "done", ID: "k3x9p2"}
+
+	// Disabled by default
+	before := commitCount(t, work)
+	disabled := GitConfig{}
+	if err := disabled.autoCommit("status", meta, "work", path); err != nil {
+		t.Fatal(err)
+	}
+	if got := commitCount(t, work); got != before {
+		t.Error("committed with auto_commit off")
+	}
+
+	// Configured messages override the defaults
+	enabled := GitConfig{AutoCommit: true, Messages: map[string]string{"status": "{{id}}: {{title}} is {{status}} ({{file}})"}}
+	if err := enabled.autoCommit("status", meta, "work", path); err != nil {
+		t.Fatal(err)
+	}
+	if got := git(t, work, "log", "-1", "--format=%s"); got != "k3x9p2: Fix login is done (fix-login.md)" {
+		t.Errorf("subject = %q", got)
+	}
+
+	writeFile(t, path, "---\ntitle: Fix login\nstatus: todo\n---\n")
+	if err := enabled.autoCommit("edit", meta, "work", path); err != nil {
+		t.Fatal(err)
+	}
+	if got := git(t, work, "log", "-1", "--format=%s"); got != "task: update Fix login" {
+		t.Errorf("subject = %q, want the default edit message", got)
+	}
+}
+
+func TestGitSync(t *testing.T) {
+	remote := newRemote(t)
+	first, second := cloneRemote(t, remote), cloneRemote(t, remote)
+
+	// Both clones commit a task; the second has to rebase onto the first
+	writeFile(t, filepath.Join(first, "a.md"), "# A\n")
+	if err := gitCommitPaths("task: add A", filepath.Join(first, "a.md")); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(second, "b.md"), "# B\n")
+	if err := gitCommitPaths("task: add B", filepath.Join(second, "b.md")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, dir := range []string{first, second, first} {
+		results := gitSync([]string{dir})
+		if len(results) != 1 || !strings.HasSuffix(results[0], ": synced") {
+			t.Fatalf("gitSync(%s) = %v", dir, results)
+		}
+	}
+
+	for _, dir := range []string{first, second} {
+		for _, name := range []string{"a.md", "b.md"} {
+			if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+				t.Errorf("%s is missing from %s after syncing", name, dir)
+			}
+		}
+	}
+	if got := git(t, remote, "log", "--format=%s", "main"); got != "task: add B\ntask: add A\ninitial" {
+		t.Errorf("remote history = %q", got)
+	}
+}
+
+func TestGitSyncWithoutUpstream(t *testing.T) {
+	newRemote(t) // For the git config isolation
+	dir := t.TempDir()
+	git(t, dir, "init", "-q")
+
+	results := gitSync([]string{dir})
+	if len(results) != 1 || !strings.HasSuffix(results[0], ": no upstream branch") {
+		t.Errorf("gitSync = %v, want no upstream branch", results)
+	}
+}
+
+func TestGitFileStates(t *testing.T) {
+	work := cloneRemote(t, newRemote(t))
+	clean := filepath.Join(work, "tasks", "clean.md")
+	modified := filepath.Join(work, "tasks", "modified.md")
+	staged := filepath.Join(work, "tasks", "staged.md")
+	renamed := filepath.Join(work, "tasks", "renamed.md")
+	untracked := filepath.Join(work, "tasks", "new task.md")
+	for _, path := range []string{clean, modified, staged} {
+		writeFile(t, path, "# Task\n")
+	}
+	if err := gitCommitPaths("add tasks", clean, modified, staged); err != nil {
+		t.Fatal(err)
+	}
+
+	writeFile(t, modified, "# Changed\n")
+	writeFile(t, staged, "# Staged\n")
+	git(t, work, "add", "tasks/staged.md")
+	git(t, work, "mv", "tasks/clean.md", "tasks/renamed.md")
+	writeFile(t, untracked, "# New\n")
+
+	states := gitFileStates([]string{work})
+	want := map[string]gitFileState{
+		modified:  gitModified,
+		staged:    gitModified,
+		renamed:   gitModified,
+		untracked: gitUntracked,
+	}
+	for path, state := range want {
+		if got, ok := states[path]; !ok || got != state {
+			t.Errorf("state of %s = %v (found %v), want %v", filepath.Base(path), got, ok, state)
+		}
+	}
+	if _, ok := states[clean]; ok {
+		t.Errorf("the original path of a rename was reported: %v", states)
+	}
+	if len(states) != len(want) {
+		t.Errorf("got %d states, want %d: %v", len(states), len(want), states)
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// githubSource is the source field value of tasks imported from GitHub
+const githubSource = "github"
+
+// ghIssue is an issue as written by gh issue list --json, with any of
+// the fields below
+type ghIssue struct {
+	Number      int    `json:"number"`
+	Title       string `json:"title"`
+	Body        string `json:"body"`
+	State       string `json:"state"`       // OPEN or CLOSED
+	StateReason string `json:"stateReason"` // COMPLETED or NOT_PLANNED for closed issues
+	URL         string `json:"url"`
+	CreatedAt   string `json:"createdAt"`
+	ClosedAt    string `json:"closedAt"`
+	Labels      []struct {
+		Name string `json:"name"`
+	} `json:"labels"`
+	Assignees []struct {
+		Login string `json:"login"`
+	} `json:"assignees"`
+	Milestone *struct {
+		Title string `json:"title"`
+	} `json:"milestone"`
+}
+
+// importGitHubIssues reads issues saved with e.g.
+//
+//	gh issue list --state all --json number,title,body,state,stateReason,url,createdAt,closedAt,labels,assignees,milestone
+//
+// Issues are matched by owner/repo#number (from their URL). Closed
+// issues are done, or cancelled when closed as not planned; labels become
+// tags, or set the priority for labels such as "priority: high" or "P1";
+// the body is kept; and assignees and the milestone are kept in fields.
+func importGitHubIssues(data []byte, defaultStatus string) ([]importedTask, error) {
+	var issues []ghIssue
+	if err := json.Unmarshal(data, &issues); err != nil {
+		return nil, fmt.Errorf("invalid GitHub issues JSON: %w", err)
+	}
+	// The keys of each issue, since only the fields chosen with --json
+	// are exported and only those clear fields
+	var keys []map[string]json.RawMessage
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("invalid GitHub issues JSON: %w", err)
+	}
+
+	var items []importedTask
+	for i, issue := range issues {
+		if issue.Number == 0 {
+			return nil, fmt.Errorf("issue %q has no number; include number in --json", issue.Title)
+		}
+		id := githubIssueRef(issue)
+		item := importedTask{ref: id, carried: []string{"title", "status"}}
+		for key, field := range map[string]string{"labels": "tags", "closedAt": "completed", "url": "url", "assignees": "assignee", "milestone": "milestone"} {
+			if _, ok := keys[i][key]; ok {
+				item.carried = append(item.carried, field)
+			}
+		}
+		meta := &item.meta
+		meta.Title = strings.TrimSpace(issue.Title)
+		meta.Fields = map[string]string{"source": githubSource, "source_id": id, "url": issue.URL}
+
+		meta.Status = defaultStatus
+		if strings.EqualFold(issue.State, "closed") {
+			meta.Status = "done"
+			if strings.EqualFold(issue.StateReason, "not_planned") {
+				meta.Status = "cancelled"
+			}
+		}
+		for _, field := range []struct {
+			value string
+			dest  *time.Time
+		}{{issue.CreatedAt, &meta.Created}, {issue.ClosedAt, &meta.Completed}} {
+			if field.value == "" {
+				continue
+			}
+			t, err := time.Parse(time.RFC3339, field.value)
+			if err != nil {
+				return nil, fmt.Errorf("issue #%d: invalid date %q", issue.Number, field.value)
+			}
+			// gh writes the zero time for issues that were never closed
+			if t.Year() > 1 {
+				*field.dest = t.Local()
+			}
+		}
+		if !isDoneStatus(meta.Status) {
+			meta.Completed = time.Time{}
+		}
+
+		var labels, assignees []string
+		for _, label := range issue.Labels {
+			labels = append(labels, label.Name)
+		}
+		addImportLabels(meta, labels)
+		for _, assignee := range issue.Assignees {
+			assignees = append(assignees, assignee.Login)
+		}
+		meta.Fields["assignee"] = strings.Join(assignees, ", ")
+		meta.Fields["milestone"] = ""
+		if issue.Milestone != nil {
+			meta.Fields["milestone"] = issue.Milestone.Title
+		}
+
+		item.body = "# " + meta.Title + "\n"
+		if body := strings.TrimSpace(strings.ReplaceAll(issue.Body, "\r\n", "\n")); body != "" {
+			item.body += "\n" + body + "\n"
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// githubIssueRef returns owner/repo#number for an issue, or #number when
+// its URL wasn't exported
+func githubIssueRef(issue ghIssue) string {
+	path := strings.TrimPrefix(issue.URL, "https://github.com/")
+	if owner, rest, ok := strings.Cut(path, "/"); ok && path != issue.URL {
+		if repo, _, ok := strings.Cut(rest, "/"); ok {
+			return fmt.Sprintf("%s/%s#%d", owner, repo, issue.Number)
+		}
+	}
+	return fmt.Sprintf("#%d", issue.Number)
+}
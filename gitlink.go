@@ -0,0 +1,300 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// defaultBranchPattern is used for task branches unless the config sets
+// branch_pattern
+const defaultBranchPattern = "{{id}}-{{slug}}"
+
+// maxScannedCommits bounds how far back each repository is searched for
+// task IDs, so huge histories don't slow down loading
+const maxScannedCommits = 5000
+
+// commitWord splits commit messages into words that could be task IDs
+var commitWord = regexp.MustCompile(`[a-z0-9]+`)
+
+// closingWords mark a commit as finishing the task whose ID follows,
+// e.g. "fixes k3x9p2" or "Closes: k3x9p2"
+var closingWords = map[string]bool{
+	"fix": true, "fixes": true, "fixed": true,
+	"close": true, "closes": true, "closed": true,
+	"resolve": true, "resolves": true, "resolved": true,
+	"done": true,
+}
+
+// linkedCommit is a commit whose message mentions a task's ID
+type linkedCommit struct {
+	repo    string    // Repository the commit is in
+	hash    string    // Full commit hash
+	date    time.Time // Author date
+	subject string    // First line of the commit message
+	closes  bool      // Whether the message says the commit finishes the task
+}
+
+// shortHash returns the abbreviated commit hash shown in the UI
+func (c linkedCommit) shortHash() string {
+	if len(c.hash) > 7 {
+		return c.hash[:7]
+	}
+	return c.hash
+}
+
+// expandRepos expands the configured repository paths, skipping any that
+// can't be expanded
+func expandRepos(repos []string) []string {
+	var expanded []string
+	for _, repo := range repos {
+		if path, err := expandPath(repo); err == nil {
+			expanded = append(expanded, path)
+		}
+	}
+	return expanded
+}
+
+// branchName builds a task's branch name from the pattern. Supported
+// variables are {{id}} and {{slug}}.
+func branchName(pattern string, meta TaskMetadata) string {
+	return strings.NewReplacer(
+		"{{id}}", meta.ID,
+		"{{slug}}", slugify(meta.Title),
+	).Replace(pattern)
+}
+
+// createTaskBranch creates (or switches to, if it already exists) the
+// branch for a task in repo. The task is given an ID first if it has
+// none. It returns the branch name and whether an ID was added.
+func createTaskBranch(repo, taskPath, pattern string) (string, bool, error) {
+	before, err := parseFrontmatter(taskPath)
+	if err != nil {
+		return "", false, err
+	}
+	meta, err := ensureTaskID(taskPath)
+	if err != nil {
+		return "", false, err
+	}
+	addedID := before.ID == ""
+
+	name := branchName(pattern, meta)
+	if _, err := runGit(repo, "check-ref-format", "--branch", name); err != nil {
+		return "", addedID, fmt.Errorf("%q is not a valid branch name", name)
+	}
+
+	if _, err := runGit(repo, "rev-parse", "--verify", "--quiet", "refs/heads/"+name); err == nil {
+		_, err = runGit(repo, "checkout", name)
+		return name, addedID, err
+	}
+	_, err = runGit(repo, "checkout", "-b", name)
+	return name, addedID, err
+}
+
+// scanTaskCommits searches the commit messages of every branch in repos
+// for the given task IDs. It returns the matching commits for each ID,
+// newest first.
+func scanTaskCommits(repos []string, ids []string) map[string][]linkedCommit {
+	links := make(map[string][]linkedCommit)
+	for _, repo := range repos {
+		// Repositories that can't be read just have no commits to show
+		_ = scanRepoCommits(repo, taskIDSet(ids), links, "--all")
+	}
+	sortLinks(links)
+	return links
+}
+
+// scanNewTaskCommits is like scanTaskCommits, but skips commits reachable
+// from each repository's tips in seen (by repository path), so only
+// commits made since an earlier scan are returned. It also returns seen
+// updated with the tips of the repositories that were scanned, to pass
+// as seen next time. Repositories that can't be read, e.g. because
+// they're on an unmounted drive, keep their old tips, so their commits
+// are picked up once they're back.
+func scanNewTaskCommits(repos []string, ids []string, seen map[string][]string) (map[string][]linkedCommit, map[string][]string) {
+	links := make(map[string][]linkedCommit)
+	tips := make(map[string][]string)
+	for repo, old := range seen {
+		tips[repo] = old
+	}
+
+	wanted := taskIDSet(ids)
+	for _, repo := range repos {
+		current, err := repoTips(repo)
+		if err != nil || len(current) == 0 {
+			continue
+		}
+
+		// Tips that were since deleted or garbage collected are ignored
+		revs := append([]string{"--ignore-missing"}, current...)
+		if old := seen[repo]; len(old) > 0 {
+			revs = append(append(revs, "--not"), old...)
+		}
+		found := make(map[string][]linkedCommit)
+		if err := scanRepoCommits(repo, wanted, found, revs...); err != nil {
+			continue
+		}
+		for id, commits := range found {
+			links[id] = append(links[id], commits...)
+		}
+		tips[repo] = current
+	}
+	sortLinks(links)
+	return links, tips
+}
+
+// repoTips returns the commits every branch and tag of a repository
+// points to
+func repoTips(repo string) ([]string, error) {
+	out, err := runGit(repo, "for-each-ref", "--format=%(objectname)")
+	if err != nil {
+		return nil, err
+	}
+
+	var tips []string
+	seen := make(map[string]bool)
+	for _, hash := range strings.Fields(out) {
+		if !seen[hash] {
+			seen[hash] = true
+			tips = append(tips, hash)
+		}
+	}
+	return tips, nil
+}
+
+// taskIDSet returns the non-empty IDs, lowercased, as a set
+func taskIDSet(ids []string) map[string]bool {
+	wanted := make(map[string]bool)
+	for _, id := range ids {
+		if id != "" {
+			wanted[strings.ToLower(id)] = true
+		}
+	}
+	return wanted
+}
+
+// scanRepoCommits adds the commits among revs in repo that mention any
+// wanted ID to links
+func scanRepoCommits(repo string, wanted map[string]bool, links map[string][]linkedCommit, revs ...string) error {
+	if len(wanted) == 0 {
+		return nil
+	}
+
+	args := append([]string{"log", fmt.Sprintf("--max-count=%d", maxScannedCommits),
+		"--format=%x1e%H%x1f%aI%x1f%B"}, revs...)
+	out, err := runGit(repo, args...)
+	if err != nil {
+		return err
+	}
+
+	for _, record := range strings.Split(out, "\x1e") {
+		fields := strings.SplitN(strings.TrimSpace(record), "\x1f", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		message := fields[2]
+
+		// Note each ID once per commit, remembering if any mention closes it
+		found := make(map[string]bool)
+		words := commitWord.FindAllString(strings.ToLower(message), -1)
+		for i, word := range words {
+			if !wanted[word] {
+				continue
+			}
+			closes := i > 0 && closingWords[words[i-1]]
+			found[word] = found[word] || closes
+		}
+
+		if len(found) == 0 {
+			continue
+		}
+		date, _ := time.Parse(time.RFC3339, fields[1])
+		subject, _, _ := strings.Cut(message, "\n")
+		for id, closes := range found {
+			links[id] = append(links[id], linkedCommit{
+				repo:    repo,
+				hash:    fields[0],
+				date:    date,
+				subject: subject,
+				closes:  closes,
+			})
+		}
+	}
+	return nil
+}
+
+// sortLinks orders each task's commits newest first
+func sortLinks(links map[string][]linkedCommit) {
+	for _, commits := range links {
+		sort.SliceStable(commits, func(i, j int) bool {
+			return commits[i].date.After(commits[j].date)
+		})
+	}
+}
+
+// commitsForTask returns the commits in repos that mention a task's ID
+func commitsForTask(repos []string, meta TaskMetadata) []linkedCommit {
+	if meta.ID == "" || len(repos) == 0 {
+		return nil
+	}
+	return scanTaskCommits(repos, []string{meta.ID})[strings.ToLower(meta.ID)]
+}
+
+// applyCommitStatuses moves tasks mentioned in commits forward: to
+// in-progress when any commit mentions them, or to done when one closes
+// them. Only commits made since the last call are considered, so a task
+// moved back by hand stays where it was put; the repositories' tips are
+// remembered in the state file. Tasks are never moved backwards, so a
+// done task stays done. The tasks slice is updated in place and the
+// changed tasks are returned. It's safe to call from a background
+// command as long as tasks isn't shared.
+func applyCommitStatuses(tasks []taskFile, repos []string) ([]taskFile, error) {
+	var ids []string
+	for _, task := range tasks {
+		ids = append(ids, task.metadata.ID)
+	}
+	links, tips := scanNewTaskCommits(repos, ids, loadState().CommitTips)
+
+	var changed []taskFile
+	for i, task := range tasks {
+		commits := links[strings.ToLower(task.metadata.ID)]
+		if len(commits) == 0 || isDoneStatus(task.metadata.Status) {
+			continue
+		}
+
+		status := "in-progress"
+		for _, commit := range commits {
+			if commit.closes {
+				status = "done"
+			}
+		}
+		if status == "in-progress" && isInProgressStatus(task.metadata.Status) {
+			continue
+		}
+
+		if err := setTaskStatus(task.fullPath, status, "mentioned in a commit", time.Now()); err != nil {
+			return changed, fmt.Errorf("failed to update %s: %w", filepath.Base(task.fullPath), err)
+		}
+		if meta, err := parseFrontmatter(task.fullPath); err == nil {
+			tasks[i].metadata = meta
+		}
+		changed = append(changed, tasks[i])
+	}
+
+	state := loadState()
+	state.CommitTips = tips
+	return changed, saveState(state)
+}
+
+// isDoneStatus reports whether a status means the task is finished
+func isDoneStatus(status string) bool {
+	return status == "done" || status == "completed"
+}
+
+// isInProgressStatus reports whether a status means work has started
+func isInProgressStatus(status string) bool {
+	return status == "in-progress" || status == "doing"
+}
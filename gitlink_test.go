@@ -0,0 +1,129 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// commitEmpty makes an empty commit with a message in a test repository
+func commitEmpty(t *testing.T, dir, message string) {
+	t.Helper()
+	git(t, dir, "commit", "-q", "--allow-empty", "-m", message)
+}
+
+// linkedTask writes a task with an ID and status and loads it
+func linkedTask(t *testing.T, id, status string) []taskFile {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), id+".md")
+	writeFile(t, path, "---\nid: "+id+"\ntitle: Fix login\nstatus: "+status+"\n---\n\n# Fix login\n")
+	meta, err := parseFrontmatter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return []taskFile{{name: id + ".md", fullPath: path, metadata: meta}}
+}
+
+func TestApplyCommitStatusesOnlyUsesNewCommits(t *testing.T) {
+	repo := cloneRemote(t, newRemote(t))
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	commitEmpty(t, repo, "Start on k3x9p2")
+
+	tasks := linkedTask(t, "k3x9p2", "todo")
+	changed, err := applyCommitStatuses(tasks, []string{repo})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changed) != 1 || tasks[0].metadata.Status != "in-progress" {
+		t.Fatalf("status = %q after a mention, want in-progress", tasks[0].metadata.Status)
+	}
+
+	// Moved back by hand: the same commits don't move it again
+	if err := setTaskStatus(tasks[0].fullPath, "todo", "", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	tasks[0].metadata, _ = parseFrontmatter(tasks[0].fullPath)
+	if changed, _ := applyCommitStatuses(tasks, []string{repo}); len(changed) != 0 {
+		t.Errorf("already seen commits changed the status to %q", tasks[0].metadata.Status)
+	}
+
+	// Commits on any branch since the last scan do
+	git(t, repo, "checkout", "-q", "-b", "k3x9p2-fix-login")
+	commitEmpty(t, repo, "Fixes k3x9p2")
+	if _, err := applyCommitStatuses(tasks, []string{repo}); err != nil {
+		t.Fatal(err)
+	}
+	if tasks[0].metadata.Status != "done" {
+		t.Errorf("status = %q after a closing commit, want done", tasks[0].metadata.Status)
+	}
+}
+
+func TestScanNewTaskCommitsIgnoresMissingTips(t *testing.T) {
+	repo := cloneRemote(t, newRemote(t))
+	commitEmpty(t, repo, "Start on k3x9p2")
+
+	// A tip that was rewritten away, as after a rebase and gc
+	seen := map[string][]string{repo: {"1234567890123456789012345678901234567890"}}
+	links, tips := scanNewTaskCommits([]string{repo}, []string{"K3X9P2"}, seen)
+	if len(links["k3x9p2"]) != 1 {
+		t.Errorf("found %d commits, want 1", len(links["k3x9p2"]))
+	}
+
+	links, _ = scanNewTaskCommits([]string{repo}, []string{"k3x9p2"}, tips)
+	if len(links) != 0 {
+		t.Errorf("rescanned seen commits: %v", links)
+	}
+}
+
+func TestCommitsForTaskListsAllCommits(t *testing.T) {
+	repo := cloneRemote(t, newRemote(t))
+	commitEmpty(t, repo, "Start on k3x9p2")
+	commitEmpty(t, repo, "Unrelated")
+	commitEmpty(t, repo, "Closes k3x9p2")
+
+	commits := commitsForTask([]string{repo}, TaskMetadata{ID: "k3x9p2"})
+	if len(commits) != 2 {
+		t.Fatalf("found %d commits, want 2", len(commits))
+	}
+	if commits[0].subject != "Closes k3x9p2" || !commits[0].closes || commits[1].closes {
+		t.Errorf("commits = %+v, want the closing one first", commits)
+	}
+}
+
+func TestScanNewTaskCommitsKeepsTipsOfUnreadableRepos(t *testing.T) {
+	repo := cloneRemote(t, newRemote(t))
+	commitEmpty(t, repo, "Start on k3x9p2")
+	missing := filepath.Join(t.TempDir(), "unmounted")
+	seen := map[string][]string{missing: {"1234567890123456789012345678901234567890"}}
+
+	_, tips := scanNewTaskCommits([]string{repo, missing}, []string{"k3x9p2"}, seen)
+	if len(tips[repo]) == 0 {
+		t.Error("the scanned repository's tips weren't recorded")
+	}
+	if len(tips[missing]) != 1 {
+		t.Errorf("a missing repository lost its tips: %v", tips)
+	}
+}
+
+func TestScanNewTaskCommitsKeepsTipsWhenScanFails(t *testing.T) {
+	repo := cloneRemote(t, newRemote(t))
+	_, seen := scanNewTaskCommits([]string{repo}, []string{"k3x9p2"}, nil)
+	commitEmpty(t, repo, "Fixes k3x9p2")
+
+	// Corrupt the new commit, so the branch points to a commit that
+	// can't be read
+	head := git(t, repo, "rev-parse", "HEAD")
+	object := filepath.Join(repo, ".git", "objects", head[:2], head[2:])
+	if err := os.Chmod(object, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(object, []byte("corrupt"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	links, tips := scanNewTaskCommits([]string{repo}, []string{"k3x9p2"}, seen)
+	if len(links) != 0 || !reflect.DeepEqual(tips[repo], seen[repo]) {
+		t.Errorf("a failed scan recorded tips %v (was %v)", tips[repo], seen[repo])
+	}
+}
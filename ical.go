@@ -0,0 +1,376 @@
+package main
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// icalSource is the source field value of tasks imported from iCalendar
+const icalSource = "ical"
+
+// icalUIDSuffix ends the UIDs of exported tasks, so they can be matched
+// to the task again when a calendar is imported back
+const icalUIDSuffix = "@taskmanager"
+
+// icalProperty is one content line of an iCalendar file
+type icalProperty struct {
+	name   string            // Upper-cased property name, e.g. "DUE"
+	params map[string]string // Parameters, e.g. VALUE=DATE, with upper-cased names
+	value  string            // Raw value, still escaped
+}
+
+// icalTodo is the properties of one VTODO component
+type icalTodo []icalProperty
+
+// get returns the first property with a name
+func (t icalTodo) get(name string) (icalProperty, bool) {
+	for _, prop := range t {
+		if prop.name == name {
+			return prop, true
+		}
+	}
+	return icalProperty{}, false
+}
+
+// taskUID returns the UID a task is exported with: the original UID for
+// tasks imported from a calendar, otherwise one built from the task's ID
+// (or its filename, for tasks without one)
+func taskUID(task taskFile) string {
+	fields := task.metadata.Fields
+	if fields["source"] == icalSource && fields["source_id"] != "" {
+		return fields["source_id"]
+	}
+	if task.metadata.ID != "" {
+		return task.metadata.ID + icalUIDSuffix
+	}
+	return fmt.Sprintf("file-%x%s", sha1.Sum([]byte(task.name)), icalUIDSuffix)
+}
+
+// exportICal renders tasks as an iCalendar file of VTODO components
+func exportICal(tasks []taskFile, now time.Time) []byte {
+	var b strings.Builder
+	line := func(name, value string) {
+		b.WriteString(foldICalLine(name + ":" + value))
+	}
+
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", "-//taskmanager//taskmanager//EN")
+	for _, task := range tasks {
+		meta := task.metadata
+		line("BEGIN", "VTODO")
+		line("UID", escapeICalText(taskUID(task)))
+		line("DTSTAMP", formatICalTime(now))
+		line("SUMMARY", escapeICalText(displayTitle(task)))
+		line("STATUS", icalStatus(meta.Status))
+		if priority := icalPriority(meta.Priority); priority != "" {
+			line("PRIORITY", priority)
+		}
+		if !meta.DueDate.IsZero() {
+			if isMidnight(meta.DueDate) {
+				line("DUE;VALUE=DATE", meta.DueDate.Format("20060102"))
+			} else {
+				line("DUE", formatICalTime(meta.DueDate))
+			}
+		}
+		if !meta.Created.IsZero() {
+			line("CREATED", formatICalTime(meta.Created))
+		}
+		if !meta.Completed.IsZero() {
+			line("COMPLETED", formatICalTime(meta.Completed))
+		}
+		if len(meta.Tags) > 0 {
+			var tags []string
+			for _, tag := range meta.Tags {
+				tags = append(tags, escapeICalText(tag))
+			}
+			line("CATEGORIES", strings.Join(tags, ","))
+		}
+		if body := taskBody(task); body != "" {
+			line("DESCRIPTION", escapeICalText(body))
+		}
+		line("END", "VTODO")
+	}
+	line("END", "VCALENDAR")
+	return []byte(b.String())
+}
+
+// importICal reads the VTODO components of an iCalendar file as tasks
+func importICal(data []byte, defaultStatus string) ([]importedTask, error) {
+	todos, err := parseICal(string(data))
+	if err != nil {
+		return nil, err
+	}
+
+	var items []importedTask
+	for _, todo := range todos {
+		uid, ok := todo.get("UID")
+		if !ok || uid.value == "" {
+			return nil, fmt.Errorf("VTODO without a UID")
+		}
+		item := importedTask{ref: unescapeICalText(uid.value), carried: standardFields}
+		meta := &item.meta
+
+		// Our own UIDs keep their task's ID; others are kept as the source
+		if id, ours := strings.CutSuffix(item.ref, icalUIDSuffix); !ours {
+			meta.Fields = map[string]string{"source": icalSource, "source_id": item.ref}
+		} else if !strings.HasPrefix(id, "file-") {
+			meta.ID = id
+		}
+
+		if prop, ok := todo.get("SUMMARY"); ok {
+			meta.Title = unescapeICalText(prop.value)
+		}
+		meta.Status = taskStatusFromICal(todo, defaultStatus)
+		if prop, ok := todo.get("PRIORITY"); ok {
+			meta.Priority = taskPriorityFromICal(prop.value)
+		}
+		for _, field := range []struct {
+			name string
+			dest *time.Time
+		}{{"DUE", &meta.DueDate}, {"CREATED", &meta.Created}, {"COMPLETED", &meta.Completed}} {
+			prop, ok := todo.get(field.name)
+			if !ok {
+				continue
+			}
+			t, err := parseICalTime(prop)
+			if err != nil {
+				return nil, fmt.Errorf("%s of %q: %w", field.name, meta.Title, err)
+			}
+			*field.dest = t
+		}
+		for _, prop := range todo {
+			if prop.name != "CATEGORIES" {
+				continue
+			}
+			for _, tag := range splitICalList(prop.value) {
+				addImportTag(meta, tag)
+			}
+		}
+
+		item.body = "# " + meta.Title + "\n"
+		if prop, ok := todo.get("DESCRIPTION"); ok {
+			description := strings.TrimSpace(unescapeICalText(prop.value))
+			if strings.HasPrefix(description, "# ") {
+				// Exported by us, heading included
+				item.body = description + "\n"
+			} else if description != "" {
+				item.body += "\n" + description + "\n"
+			}
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// parseICal returns the VTODO components of an iCalendar file. Components
+// nested inside a VTODO, such as alarms, are skipped.
+func parseICal(text string) ([]icalTodo, error) {
+	// Unfold lines continued with a leading space or tab
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\n ", "")
+	text = strings.ReplaceAll(text, "\n\t", "")
+
+	var todos []icalTodo
+	var current icalTodo
+	inTodo, depth := false, 0
+	for n, raw := range strings.Split(text, "\n") {
+		if strings.TrimSpace(raw) == "" {
+			continue
+		}
+		prop, err := parseICalLine(raw)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n+1, err)
+		}
+
+		switch {
+		case prop.name == "BEGIN" && strings.EqualFold(prop.value, "VTODO") && !inTodo:
+			inTodo, current = true, nil
+		case prop.name == "END" && strings.EqualFold(prop.value, "VTODO") && inTodo && depth == 0:
+			todos = append(todos, current)
+			inTodo = false
+		case !inTodo:
+		case prop.name == "BEGIN":
+			depth++
+		case prop.name == "END":
+			depth--
+		case depth == 0:
+			current = append(current, prop)
+		}
+	}
+	if inTodo {
+		return nil, fmt.Errorf("VTODO without END")
+	}
+	return todos, nil
+}
+
+// parseICalLine splits a content line into name, parameters and value
+func parseICalLine(line string) (icalProperty, error) {
+	// The value starts at the first colon outside a quoted parameter
+	colon, quoted := -1, false
+	for i, r := range line {
+		if r == '"' {
+			quoted = !quoted
+		} else if r == ':' && !quoted {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return icalProperty{}, fmt.Errorf("no value in %q", line)
+	}
+
+	parts := strings.Split(line[:colon], ";")
+	prop := icalProperty{name: strings.ToUpper(parts[0]), params: map[string]string{}, value: line[colon+1:]}
+	for _, param := range parts[1:] {
+		key, value, _ := strings.Cut(param, "=")
+		prop.params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+	}
+	return prop, nil
+}
+
+// parseICalTime parses a DATE or DATE-TIME value, in UTC, in the
+// property's TZID time zone, or in local time
+func parseICalTime(prop icalProperty) (time.Time, error) {
+	value := prop.value
+	if len(value) == 8 {
+		return time.ParseInLocation("20060102", value, time.Local)
+	}
+	if strings.HasSuffix(value, "Z") {
+		return time.Parse("20060102T150405Z", value)
+	}
+	loc := time.Local
+	if tzid := prop.params["TZID"]; tzid != "" {
+		if l, err := time.LoadLocation(tzid); err == nil {
+			loc = l
+		}
+	}
+	return time.ParseInLocation("20060102T150405", value, loc)
+}
+
+// formatICalTime formats a time as a UTC DATE-TIME value
+func formatICalTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// icalStatus maps a task status to a VTODO STATUS
+func icalStatus(status string) string {
+	switch {
+	case isDoneStatus(status):
+		return "COMPLETED"
+	case isInProgressStatus(status):
+		return "IN-PROCESS"
+	case status == "cancelled" || status == "canceled":
+		return "CANCELLED"
+	}
+	return "NEEDS-ACTION"
+}
+
+// taskStatusFromICal maps a VTODO's STATUS (or COMPLETED date) to a
+// task status
+func taskStatusFromICal(todo icalTodo, defaultStatus string) string {
+	prop, _ := todo.get("STATUS")
+	switch strings.ToUpper(prop.value) {
+	case "COMPLETED":
+		return "done"
+	case "IN-PROCESS":
+		return "in-progress"
+	case "CANCELLED":
+		return "cancelled"
+	}
+	if _, ok := todo.get("COMPLETED"); ok {
+		return "done"
+	}
+	return defaultStatus
+}
+
+// icalPriority maps a task priority to a VTODO PRIORITY (1 highest, 9
+// lowest), or "" for none
+func icalPriority(priority string) string {
+	switch strings.ToLower(priority) {
+	case "high":
+		return "1"
+	case "medium":
+		return "5"
+	case "low":
+		return "9"
+	}
+	return ""
+}
+
+// taskPriorityFromICal maps a VTODO PRIORITY to a task priority
+func taskPriorityFromICal(value string) string {
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	switch {
+	case err != nil || n <= 0:
+		return ""
+	case n <= 4:
+		return "high"
+	case n == 5:
+		return "medium"
+	}
+	return "low"
+}
+
+// escapeICalText escapes a TEXT value
+func escapeICalText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// unescapeICalText undoes escapeICalText
+func unescapeICalText(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i == len(s)-1 {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n', 'N':
+			b.WriteByte('\n')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// splitICalList splits a list value on commas that aren't escaped, and
+// unescapes each item
+func splitICalList(s string) []string {
+	var items []string
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case ',':
+			items = append(items, unescapeICalText(s[start:i]))
+			start = i + 1
+		}
+	}
+	return append(items, unescapeICalText(s[start:]))
+}
+
+// foldICalLine ends a content line with CRLF, folding it into lines of
+// at most 75 bytes without splitting a character
+func foldICalLine(line string) string {
+	var b strings.Builder
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut] + "\r\n ")
+		line = line[cut:]
+		limit = 74 // The leading space counts
+	}
+	b.WriteString(line + "\r\n")
+	return b.String()
+}
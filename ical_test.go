@@ -0,0 +1,165 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFoldICalLine(t *testing.T) {
+	for _, value := range []string{
+		"short",
+		strings.Repeat("a", 67), // Exactly 75 bytes with "SUMMARY:"
+		strings.Repeat("a", 68),
+		strings.Repeat("long line ", 30),
+		strings.Repeat("é", 100), // Two bytes each, so some cuts fall mid-character
+	} {
+		folded := foldICalLine("SUMMARY:" + value)
+		if !strings.HasSuffix(folded, "\r\n") {
+			t.Errorf("folded line %q doesn't end with CRLF", folded)
+		}
+		for i, line := range strings.Split(strings.TrimSuffix(folded, "\r\n"), "\r\n") {
+			if len(line) > 75 {
+				t.Errorf("line %d is %d bytes: %q", i, len(line), line)
+			}
+			if i > 0 && !strings.HasPrefix(line, " ") {
+				t.Errorf("continuation line %d doesn't start with a space: %q", i, line)
+			}
+		}
+
+		todos, err := parseICal("BEGIN:VTODO\r\n" + folded + "END:VTODO\r\n")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := todos[0].get("SUMMARY"); got.value != value {
+			t.Errorf("unfolded %q, want %q", got.value, value)
+		}
+	}
+}
+
+func TestParseICalUnfoldsTabs(t *testing.T) {
+	todos, err := parseICal("BEGIN:VTODO\nSUMMARY:Fix \n\tthe login\nEND:VTODO\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := todos[0].get("SUMMARY"); got.value != "Fix the login" {
+		t.Errorf("SUMMARY = %q", got.value)
+	}
+}
+
+func TestICalTextEscaping(t *testing.T) {
+	for _, test := range []struct{ text, escaped string }{
+		{"plain", "plain"},
+		{"a, b; c", `a\, b\; c`},
+		{"line one\nline two", `line one\nline two`},
+		{`C:\tasks`, `C:\\tasks`},
+		{`\n is not a newline`, `\\n is not a newline`},
+	} {
+		if got := escapeICalText(test.text); got != test.escaped {
+			t.Errorf("escapeICalText(%q) = %q, want %q", test.text, got, test.escaped)
+		}
+		if got := unescapeICalText(test.escaped); got != test.text {
+			t.Errorf("unescapeICalText(%q) = %q, want %q", test.escaped, got, test.text)
+		}
+	}
+	if got := unescapeICalText(`a\Nb\`); got != "a\nb\\" {
+		t.Errorf("unescapeICalText kept %q", got)
+	}
+
+	got := splitICalList(`work,a\, b,c\;d`)
+	if want := []string{"work", "a, b", "c;d"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("splitICalList = %q, want %q", got, want)
+	}
+}
+
+func TestParseICalSkipsNestedComponents(t *testing.T) {
+	text := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"BEGIN:VEVENT",
+		"SUMMARY:Not a task",
+		"END:VEVENT",
+		"BEGIN:VTODO",
+		"UID:abc@example.com",
+		"BEGIN:VALARM",
+		"ACTION:DISPLAY",
+		"DESCRIPTION:Reminder",
+		"BEGIN:X-NESTED",
+		"SUMMARY:Deeper",
+		"END:X-NESTED",
+		"END:VALARM",
+		"SUMMARY:Pay bills",
+		`DESCRIPTION;LANGUAGE="en:us":Due\, soon`,
+		"END:VTODO",
+		"END:VCALENDAR",
+	}, "\r\n")
+	todos, err := parseICal(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(todos) != 1 {
+		t.Fatalf("found %d todos, want 1", len(todos))
+	}
+	var names []string
+	for _, prop := range todos[0] {
+		names = append(names, prop.name)
+	}
+	if want := []string{"UID", "SUMMARY", "DESCRIPTION"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("properties = %v, want %v", names, want)
+	}
+	if prop, _ := todos[0].get("DESCRIPTION"); prop.value != `Due\, soon` || prop.params["LANGUAGE"] != "en:us" {
+		t.Errorf("DESCRIPTION = %+v, want the VTODO's own", prop)
+	}
+
+	if _, err := parseICal("BEGIN:VTODO\nSUMMARY:Unfinished\n"); err == nil {
+		t.Error("a VTODO without END was accepted")
+	}
+}
+
+func TestICalReimportMatchesTasks(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "ours.md"), "---\nid: k3x9p2\ntitle: Fix login, again\nstatus: in-progress\npriority: high\ndue_date: 2025-01-10\ntags:\n  - work\n  - a,b\n---\n\n# Fix login, again\n\nSteps; more steps.\n")
+	writeFile(t, filepath.Join(dir, "theirs.md"), "---\ntitle: Dentist\nstatus: todo\nsource: ical\nsource_id: 1234-abcd@calendar.example.com\n---\n\n# Dentist\n")
+	writeFile(t, filepath.Join(dir, "no id.md"), "---\ntitle: Untracked\nstatus: done\ncompleted: 2025-01-05\n---\n")
+	tasks, err := loadTasksFromDirectories([]string{dir}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := exportICal(tasks, time.Date(2025, 1, 6, 12, 0, 0, 0, time.UTC))
+	items, err := importICal(data, "todo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	refs := map[string]importedTask{}
+	for _, item := range items {
+		refs[item.ref] = item
+	}
+
+	ours := refs["k3x9p2@taskmanager"]
+	if ours.meta.ID != "k3x9p2" || ours.meta.Fields != nil {
+		t.Errorf("our UID imported as ID %q with fields %v", ours.meta.ID, ours.meta.Fields)
+	}
+	if ours.meta.Title != "Fix login, again" || !reflect.DeepEqual(ours.meta.Tags, []string{"work", "a,b"}) {
+		t.Errorf("escaped values came back as %q and %q", ours.meta.Title, ours.meta.Tags)
+	}
+	if ours.body != "# Fix login, again\n\nSteps; more steps.\n" {
+		t.Errorf("body = %q", ours.body)
+	}
+	theirs := refs["1234-abcd@calendar.example.com"]
+	if want := map[string]string{"source": "ical", "source_id": "1234-abcd@calendar.example.com"}; !reflect.DeepEqual(theirs.meta.Fields, want) || theirs.meta.ID != "" {
+		t.Errorf("a foreign UID imported as ID %q with fields %v", theirs.meta.ID, theirs.meta.Fields)
+	}
+
+	// Importing the export again matches every task and changes nothing
+	steps := planImport(items, tasks, taskUID, "todo")
+	if len(steps) != 3 {
+		t.Fatalf("planned %d steps, want 3", len(steps))
+	}
+	for _, step := range steps {
+		if step.task == nil || len(step.changed) != 0 {
+			t.Errorf("re-import: %s", step.describe())
+		}
+	}
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// importer reads one tool's export format for the import command
+type importer struct {
+	name       string   // --format value
+	aliases    []string // Other --format values
+	extensions []string // File extensions chosen by default, without the dot
+
+	// detect recognizes the format from a file's contents, for extensions
+	// several formats share such as .json
+	detect func(data []byte) bool
+
+	// read parses an export into tasks to import
+	read func(data []byte, in importInput) ([]importedTask, error)
+
+	// taskRef returns an existing task's ref, to match imported tasks
+	taskRef func(task taskFile) string
+}
+
+// importInput is what importers can use besides the file's contents
+type importInput struct {
+	tasks         []taskFile
+	projects      []ProjectConfig
+	defaultStatus string
+}
+
+// importers are the formats the import command reads
+var importers = []importer{
+	{
+		name:       "ics",
+		aliases:    []string{"ical"},
+		extensions: []string{"ics", "ical"},
+		read: func(data []byte, in importInput) ([]importedTask, error) {
+			return importICal(data, in.defaultStatus)
+		},
+		taskRef: taskUID,
+	},
+	{
+		name:       "todotxt",
+		aliases:    []string{"txt"},
+		extensions: []string{"txt", "todotxt"},
+		read: func(data []byte, in importInput) ([]importedTask, error) {
+			return importTodoTxt(data, in.projects, in.defaultStatus), nil
+		},
+		taskRef: func(task taskFile) string { return task.metadata.ID },
+	},
+	{
+		name:       "taskwarrior",
+		aliases:    []string{"tw"},
+		extensions: []string{"json"},
+		detect:     func(data []byte) bool { return hasJSONKey(data, "uuid") },
+		read: func(data []byte, in importInput) ([]importedTask, error) {
+			return importTaskwarrior(data, in.tasks, in.projects, in.defaultStatus)
+		},
+		taskRef: twUUID,
+	},
+	{
+		name:       "trello",
+		extensions: []string{"json"},
+		detect:     func(data []byte) bool { return hasJSONKey(data, "cards") },
+		read: func(data []byte, in importInput) ([]importedTask, error) {
+			return importTrello(data, in.projects, in.defaultStatus)
+		},
+		taskRef: sourceRef(trelloSource),
+	},
+	{
+		name:       "jira",
+		extensions: []string{"csv"},
+		read: func(data []byte, in importInput) ([]importedTask, error) {
+			return importJira(data, in.projects, in.defaultStatus)
+		},
+		taskRef: sourceRef(jiraSource),
+	},
+	{
+		name:       "github",
+		aliases:    []string{"gh"},
+		extensions: []string{"json"},
+		detect:     func(data []byte) bool { return hasJSONKey(data, "number") },
+		read: func(data []byte, in importInput) ([]importedTask, error) {
+			return importGitHubIssues(data, in.defaultStatus)
+		},
+		taskRef: sourceRef(githubSource),
+	},
+}
+
+// importerNames lists the import formats, for usage and errors
+func importerNames() []string {
+	var names []string
+	for _, imp := range importers {
+		names = append(names, imp.name)
+	}
+	return names
+}
+
+// findImporter returns the importer named by format or, without one,
+// chosen by the file's extension and, where formats share an extension,
+// its contents
+func findImporter(format, path string, data []byte) (importer, error) {
+	if format != "" {
+		for _, imp := range importers {
+			if strings.EqualFold(imp.name, format) || containsFold(imp.aliases, format) {
+				return imp, nil
+			}
+		}
+		return importer{}, fmt.Errorf("unknown format %q (use %s)", format, strings.Join(importerNames(), ", "))
+	}
+
+	ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	var candidates []importer
+	for _, imp := range importers {
+		if containsFold(imp.extensions, ext) {
+			candidates = append(candidates, imp)
+		}
+	}
+	if len(candidates) == 1 {
+		return candidates[0], nil
+	}
+	for _, imp := range candidates {
+		if imp.detect != nil && imp.detect(data) {
+			return imp, nil
+		}
+	}
+	return importer{}, fmt.Errorf("can't tell the format of %s; choose one with --format (%s)", filepath.Base(path), strings.Join(importerNames(), ", "))
+}
+
+// hasJSONKey reports whether a JSON object, the first object of a JSON
+// array or the first line of one object per line has a key
+func hasJSONKey(data []byte, key string) bool {
+	data = bytes.TrimSpace(data)
+	if bytes.HasPrefix(data, []byte("[")) {
+		var list []json.RawMessage
+		if json.Unmarshal(data, &list) != nil || len(list) == 0 {
+			return false
+		}
+		data = list[0]
+	}
+
+	var object map[string]json.RawMessage
+	if json.Unmarshal(data, &object) != nil {
+		line, _, _ := bytes.Cut(data, []byte("\n"))
+		if json.Unmarshal(line, &object) != nil {
+			return false
+		}
+	}
+	_, ok := object[key]
+	return ok
+}
+
+// sourceRef returns a taskRef matching tasks imported from source by
+// their source_id
+func sourceRef(source string) func(taskFile) string {
+	return func(task taskFile) string {
+		if fields := task.metadata.Fields; fields["source"] == source {
+			return fields["source_id"]
+		}
+		return ""
+	}
+}
+
+// placeInProject puts a new imported task into a configured project's
+// directory, or keeps the project name in a project field when no
+// project has that code or name
+func placeInProject(item *importedTask, projects []ProjectConfig, name string) {
+	name = strings.TrimSpace(name)
+	if project, ok := findProject(projects, name); ok {
+		item.dir = project.Path
+	} else if name != "" {
+		item.meta.Fields["project"] = name
+	}
+}
+
+// importTag turns a label from another tool into a tag, which can't
+// contain spaces
+func importTag(label string) string {
+	return strings.Join(strings.Fields(label), "-")
+}
+
+// addImportTag adds a tag unless the task already has it
+func addImportTag(meta *TaskMetadata, label string) {
+	if tag := importTag(label); tag != "" && !containsFold(meta.Tags, tag) {
+		meta.Tags = append(meta.Tags, tag)
+	}
+}
+
+// statusFromName maps another tool's status or column name to a task
+// status: names meaning finished are done, started ones in-progress and
+// abandoned ones cancelled; anything else gets defaultStatus
+func statusFromName(name, defaultStatus string) string {
+	lower := strings.ToLower(name)
+	for _, rule := range []struct {
+		status string
+		words  []string
+	}{
+		{"cancelled", []string{"cancel", "won't", "wont", "not planned", "rejected", "invalid", "duplicate"}},
+		{"done", []string{"done", "complete", "closed", "resolved", "finished", "shipped"}},
+		{"in-progress", []string{"progress", "doing", "started", "review", "active"}},
+	} {
+		for _, word := range rule.words {
+			if strings.Contains(lower, word) {
+				return rule.status
+			}
+		}
+	}
+	return defaultStatus
+}
+
+// priorityFromName maps another tool's priority name to a task priority,
+// or "" when it isn't one
+func priorityFromName(name string) string {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "highest", "high", "critical", "blocker", "urgent":
+		return "high"
+	case "medium", "normal", "major":
+		return "medium"
+	case "low", "lowest", "minor", "trivial":
+		return "low"
+	}
+	return ""
+}
+
+// priorityLabel matches labels that give a priority, e.g. "priority: high"
+// or "P1"
+var priorityLabel = regexp.MustCompile(`(?i)^(?:priority[\s:/_-]*(\w+)|p([0-9]))$`)
+
+// priorityFromLabel returns the priority a label gives, or ""
+func priorityFromLabel(label string) string {
+	match := priorityLabel.FindStringSubmatch(strings.TrimSpace(label))
+	switch {
+	case match == nil:
+		return ""
+	case match[1] != "":
+		return priorityFromName(match[1])
+	case match[2] <= "1":
+		return "high"
+	case match[2] == "2":
+		return "medium"
+	}
+	return "low"
+}
+
+// addImportLabels adds labels as tags, except the first one that gives
+// a priority, which sets the task's priority instead
+func addImportLabels(meta *TaskMetadata, labels []string) {
+	for _, label := range labels {
+		if priority := priorityFromLabel(label); priority != "" && meta.Priority == "" {
+			meta.Priority = priority
+			continue
+		}
+		addImportTag(meta, label)
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const (
+	taskwarriorJSON = `[{"uuid": "11111111-1111-4111-8111-111111111111", "description": "Pay bills", "status": "pending"}]`
+	trelloJSON      = `{
+		"name": "Website",
+		"lists": [
+			{"id": "l1", "name": "Doing"},
+			{"id": "l2", "name": "Old ideas", "closed": true}
+		],
+		"cards": [
+			{"id": "5f1a2b3c0000000000000001", "name": "Fix login", "idList": "l1", "labels": [{"name": "priority: high"}, {"name": "bug"}, {"color": "green"}], "shortUrl": "https://trello.com/c/abc"},
+			{"id": "5f1a2b3c0000000000000002", "name": "Archived card", "idList": "l1", "closed": true},
+			{"id": "5f1a2b3c0000000000000003", "name": "On an archived list", "idList": "l2"}
+		]
+	}`
+	githubJSON = `[
+		{"number": 12, "title": "Crash on start", "state": "CLOSED", "stateReason": "NOT_PLANNED", "url": "https://github.com/acme/app/issues/12", "closedAt": "2025-01-05T10:00:00Z", "labels": [{"name": "P2"}, {"name": "bug"}], "assignees": [], "milestone": null},
+		{"number": 13, "title": "Add dark mode", "state": "OPEN", "url": "https://github.com/acme/app/issues/13", "closedAt": "0001-01-01T00:00:00Z", "labels": [], "assignees": [{"login": "sam"}, {"login": "kim"}], "milestone": {"title": "v2"}},
+		{"number": 14, "title": "Fix typo", "state": "CLOSED", "stateReason": "COMPLETED", "closedAt": "2025-01-06T10:00:00Z"}
+	]`
+)
+
+func TestFindImporter(t *testing.T) {
+	for _, test := range []struct {
+		format, path, data string
+		want               string // Importer name, or "" for an error
+	}{
+		{"", "tasks.json", taskwarriorJSON, "taskwarrior"},
+		{"", "tasks.json", `{"uuid": "a", "description": "Old style"}` + "\n" + `{"uuid": "b", "description": "Two"}`, "taskwarrior"},
+		{"", "board.JSON", trelloJSON, "trello"},
+		{"", "issues.json", githubJSON, "github"},
+		{"", "empty.json", `[]`, ""},
+		{"", "other.json", `{"items": []}`, ""},
+		{"", "calendar.ics", "", "ics"},
+		{"", "todo.txt", "", "todotxt"},
+		{"", "export.csv", "", "jira"},
+		{"", "notes.md", "", ""},
+		{"gh", "issues", githubJSON, "github"},
+		{"TaskWarrior", "board.json", trelloJSON, "taskwarrior"},
+		{"asana", "tasks.json", "", ""},
+	} {
+		imp, err := findImporter(test.format, test.path, []byte(test.data))
+		if test.want == "" {
+			if err == nil {
+				t.Errorf("findImporter(%q, %q) = %s, want an error", test.format, test.path, imp.name)
+			}
+			continue
+		}
+		if err != nil || imp.name != test.want {
+			t.Errorf("findImporter(%q, %q) = %s, %v, want %s", test.format, test.path, imp.name, err, test.want)
+		}
+	}
+}
+
+func TestImportTrello(t *testing.T) {
+	items, err := importTrello([]byte(trelloJSON), nil, "todo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 {
+		t.Fatalf("imported %d cards, want the one that isn't archived", len(items))
+	}
+	meta := items[0].meta
+	if meta.Status != "in-progress" || meta.Priority != "high" || !reflect.DeepEqual(meta.Tags, []string{"bug", "green"}) {
+		t.Errorf("card = %+v", meta)
+	}
+	if meta.Fields["project"] != "Website" || meta.Fields["list"] != "Doing" || meta.Created.Unix() != 0x5f1a2b3c {
+		t.Errorf("card fields = %v, created %v", meta.Fields, meta.Created)
+	}
+}
+
+func TestImportJira(t *testing.T) {
+	csv := "\ufeffSummary,Issue key,Issue Type,Status,Status Category,Resolution,Priority,Labels,Labels,Labels,Created,Resolved\n" +
+		"Fix login,WEB-1,Bug,Closed,Done,Won't Do,High,backend,needs review,,02/Jan/25 9:30 AM,05/Jan/25 10:00 AM\n" +
+		"\"Add \"\"dark\"\" mode\",WEB-2,Story,Done,Done,Done,Low,,,,2025-01-02,2025-01-06\n" +
+		"Write docs,WEB-3,Task,In Review,,,,docs,,,02/Jan/25,\n"
+	items, err := importJira([]byte(csv), nil, "todo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 3 {
+		t.Fatalf("imported %d issues, want 3", len(items))
+	}
+
+	for i, want := range []struct {
+		title, status, priority string
+		tags                    []string
+		completed               bool
+	}{
+		{"Fix login", "cancelled", "high", []string{"backend", "needs-review"}, false},
+		{`Add "dark" mode`, "done", "low", nil, true},
+		{"Write docs", "in-progress", "", []string{"docs"}, false},
+	} {
+		meta := items[i].meta
+		if meta.Title != want.title || meta.Status != want.status || meta.Priority != want.priority ||
+			!reflect.DeepEqual(meta.Tags, want.tags) || meta.Completed.IsZero() == want.completed {
+			t.Errorf("issue %d = %+v, want %+v", i, meta, want)
+		}
+	}
+	if items[0].meta.Fields["type"] != "Bug" || items[0].meta.Created.Hour() != 9 {
+		t.Errorf("WEB-1 fields = %v, created %v", items[0].meta.Fields, items[0].meta.Created)
+	}
+
+	if _, err := importJira([]byte("Name,Key\nA,B\n"), nil, "todo"); err == nil {
+		t.Error("a CSV without Jira's columns was accepted")
+	}
+}
+
+func TestImportGitHubIssues(t *testing.T) {
+	items, err := importGitHubIssues([]byte(githubJSON), "todo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 3 {
+		t.Fatalf("imported %d issues, want 3", len(items))
+	}
+
+	closed, open, fixed := items[0].meta, items[1].meta, items[2].meta
+	if items[0].ref != "acme/app#12" || closed.Status != "cancelled" || closed.Priority != "medium" || !reflect.DeepEqual(closed.Tags, []string{"bug"}) {
+		t.Errorf("not planned issue = %s %+v", items[0].ref, closed)
+	}
+	if !closed.Completed.IsZero() {
+		t.Errorf("a cancelled issue was completed at %v", closed.Completed)
+	}
+	if open.Status != "todo" || !open.Completed.IsZero() || open.Fields["assignee"] != "sam, kim" || open.Fields["milestone"] != "v2" {
+		t.Errorf("open issue = %+v", open)
+	}
+	if items[2].ref != "#14" || fixed.Status != "done" || fixed.Completed.IsZero() {
+		t.Errorf("completed issue = %s %+v", items[2].ref, fixed)
+	}
+
+	if _, err := importGitHubIssues([]byte(`[{"title": "No number"}]`), "todo"); err == nil {
+		t.Error("an issue without a number was accepted")
+	}
+}
+
+func TestReimportKeepsFieldsTheSourceLacks(t *testing.T) {
+	task := taskFile{name: "login.md", metadata: TaskMetadata{
+		Title: "Fix login", Status: "todo", Priority: "high", Tags: []string{"backend"},
+		Fields: map[string]string{"source": "jira", "source_id": "WEB-1", "type": "Bug", "assignee": "sam"},
+	}}
+
+	// Without Priority, Labels, Issue Type or Assignee columns, those
+	// fields are left alone
+	items, err := importJira([]byte("Summary,Issue key,Status\nFix the login,WEB-1,To Do\n"), nil, "todo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	steps := planImport(items, []taskFile{task}, sourceRef(jiraSource), "todo")
+	if got := steps[0].changed; !reflect.DeepEqual(got, []string{"title"}) {
+		t.Errorf("changed %v, want only the title", got)
+	}
+
+	// With the columns, empty values clear them
+	items, err = importJira([]byte("Summary,Issue key,Status,Priority,Labels,Issue Type,Assignee\nFix login,WEB-1,To Do,,,Bug,\n"), nil, "todo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	steps = planImport(items, []taskFile{task}, sourceRef(jiraSource), "todo")
+	if got := steps[0].changed; !reflect.DeepEqual(got, []string{"priority", "tags", "assignee"}) {
+		t.Errorf("changed %v, want the emptied columns", got)
+	}
+
+	// GitHub issues exported without assignees or a milestone keep theirs
+	task.metadata.Fields = map[string]string{"source": "github", "source_id": "#12", "assignee": "sam", "milestone": "v2"}
+	items, err = importGitHubIssues([]byte(`[{"number": 12, "title": "Fix login", "state": "OPEN"}]`), "todo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	steps = planImport(items, []taskFile{task}, sourceRef(githubSource), "todo")
+	if got := steps[0].changed; len(got) != 0 {
+		t.Errorf("changed %s", strings.Join(got, ", "))
+	}
+}
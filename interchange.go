@@ -0,0 +1,279 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// importedTask is a task read from another tool's export
+type importedTask struct {
+	ref  string       // The task's ID in the other tool, matched against existing tasks
+	meta TaskMetadata // Fields to write; Fields holds anything beyond the usual ones, ID is only used for new tasks
+	body string       // Body for a new task file
+	dir  string       // Directory for a new task, if not the import's
+
+	// carried is the fields the source always has, so an empty value
+	// clears them; empty values of other fields are left out
+	carried []string
+}
+
+// standardFields are the fields every task has, which formats that
+// describe a whole task carry
+var standardFields = []string{"title", "status", "priority", "due_date", "tags", "completed"}
+
+// importStep is what importing one task does: create a new file, update
+// the fields that changed in an existing one, or nothing
+type importStep struct {
+	item    importedTask
+	task    *taskFile // The existing task, or nil to create one
+	changed []string  // Frontmatter fields that differ from the existing task
+}
+
+// planImport matches imported tasks to existing ones by ref, comparing
+// it with taskRef of every task. A ref seen twice keeps its last task.
+// Existing tasks without a status count as having defaultStatus.
+func planImport(items []importedTask, tasks []taskFile, taskRef func(taskFile) string, defaultStatus string) []importStep {
+	existing := map[string]*taskFile{}
+	for i := range tasks {
+		if ref := taskRef(tasks[i]); ref != "" {
+			existing[ref] = &tasks[i]
+		}
+	}
+
+	var steps []importStep
+	index := map[string]int{}
+	for _, item := range items {
+		step := importStep{item: item, task: existing[item.ref]}
+		if step.task != nil {
+			current := step.task.metadata
+			if current.Status == "" {
+				current.Status = defaultStatus
+			}
+			step.changed = changedFields(current, item)
+		}
+		if i, ok := index[item.ref]; ok && item.ref != "" {
+			steps[i] = step
+			continue
+		}
+		index[item.ref] = len(steps)
+		steps = append(steps, step)
+	}
+	return steps
+}
+
+// importValues returns the frontmatter fields an import writes, with
+// nil for fields that should be removed
+func importValues(meta TaskMetadata) yaml.MapSlice {
+	values := yaml.MapSlice{
+		{Key: "title", Value: orNil(meta.Title)},
+		{Key: "status", Value: orNil(meta.Status)},
+		{Key: "priority", Value: orNil(meta.Priority)},
+		{Key: "due_date", Value: timeOrNil(meta.DueDate)},
+		{Key: "tags", Value: tagsOrNil(meta.Tags)},
+		{Key: "completed", Value: timeOrNil(meta.Completed)},
+	}
+	for _, key := range sortedKeys(meta.Fields) {
+		values = append(values, yaml.MapItem{Key: key, Value: orNil(meta.Fields[key])})
+	}
+	return values
+}
+
+// changedFields lists the fields an import would change in a task. Empty
+// fields only count when the import carries them.
+func changedFields(current TaskMetadata, imported importedTask) []string {
+	now := map[string]interface{}{}
+	for _, item := range importValues(current) {
+		now[item.Key.(string)] = item.Value
+	}
+	for key, value := range current.Fields {
+		now[key] = orNil(value)
+	}
+
+	var changed []string
+	for _, item := range importValues(imported.meta) {
+		key := item.Key.(string)
+		if item.Value == nil && !containsFold(imported.carried, key) {
+			continue
+		}
+		if !sameValue(now[key], item.Value) {
+			changed = append(changed, key)
+		}
+	}
+	return changed
+}
+
+// sameValue compares two field values. Times compare as dates when
+// either is at midnight, since files and other tools often store only
+// the date, and as instants otherwise.
+func sameValue(a, b interface{}) bool {
+	at, aTime := a.(time.Time)
+	bt, bTime := b.(time.Time)
+	if aTime && bTime {
+		if isMidnight(at) || isMidnight(bt) {
+			return at.Format("2006-01-02") == bt.Format("2006-01-02")
+		}
+		return at.Equal(bt)
+	}
+	return fmt.Sprint(a) == fmt.Sprint(b)
+}
+
+// isMidnight reports whether a time has no time of day, i.e. is a date
+func isMidnight(t time.Time) bool {
+	return t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0
+}
+
+// orNil returns nil for an empty string
+func orNil(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// timeOrNil returns nil for a zero time
+func timeOrNil(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return t
+}
+
+// tagsOrNil returns nil for no tags
+func tagsOrNil(tags []string) interface{} {
+	if len(tags) == 0 {
+		return nil
+	}
+	return tags
+}
+
+// describe summarizes an import step for the preview
+func (s importStep) describe() string {
+	switch {
+	case s.task == nil:
+		return "create  " + s.item.meta.Title
+	case len(s.changed) == 0:
+		return "same    " + s.task.name
+	}
+	return fmt.Sprintf("update  %s (%s)", s.task.name, strings.Join(s.changed, ", "))
+}
+
+// applyImport creates and updates task files for the planned steps.
+// New tasks go into dir, named with pattern. It returns the paths of
+// every file written.
+func applyImport(steps []importStep, dir, pattern string) ([]string, error) {
+	expandedDir, err := expandPath(dir)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+
+	var paths []string
+	for _, step := range steps {
+		switch {
+		case step.task == nil:
+			meta := step.item.meta
+			if meta.ID == "" {
+				meta.ID = newTaskID()
+			}
+			if meta.Created.IsZero() {
+				meta.Created = now.Truncate(time.Second)
+			}
+			meta.Fields = map[string]string{}
+			for key, value := range step.item.meta.Fields {
+				if value != "" {
+					meta.Fields[key] = value
+				}
+			}
+			content, err := renderTaskFile(meta, step.item.body)
+			if err != nil {
+				return paths, err
+			}
+			taskDir := expandedDir
+			if step.item.dir != "" {
+				if taskDir, err = expandPath(step.item.dir); err != nil {
+					return paths, err
+				}
+			}
+			taskPath, err := writeNewTaskFile(taskDir, renderFilename(pattern, meta, now), content)
+			if err != nil {
+				return paths, err
+			}
+			paths = append(paths, taskPath)
+
+		case len(step.changed) > 0:
+			err := updateFrontmatter(step.task.fullPath, func(fields *yaml.MapSlice) {
+				for _, item := range importValues(step.item.meta) {
+					key := item.Key.(string)
+					if !containsFold(step.changed, key) {
+						continue
+					}
+					if item.Value == nil {
+						deleteField(fields, key)
+					} else {
+						setField(fields, key, item.Value)
+					}
+				}
+			})
+			if err != nil {
+				return paths, fmt.Errorf("failed to update %s: %w", step.task.name, err)
+			}
+			paths = append(paths, step.task.fullPath)
+		}
+	}
+	return paths, nil
+}
+
+// countSteps counts the tasks an import creates and updates
+func countSteps(steps []importStep) (created, updated int) {
+	for _, step := range steps {
+		if step.task == nil {
+			created++
+		} else if len(step.changed) > 0 {
+			updated++
+		}
+	}
+	return created, updated
+}
+
+// taskBody returns a task file's body without its frontmatter
+func taskBody(task taskFile) string {
+	content, err := os.ReadFile(task.fullPath)
+	if err != nil {
+		return ""
+	}
+	_, body, _ := splitFrontmatter(content)
+	return strings.TrimSpace(body)
+}
+
+// exportedTasks loads every task, filtered by a query, for an export
+func exportedTasks(cfg Config, query string, useCache bool) ([]taskFile, error) {
+	filter, err := parseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	tasks, err := loadTasksFromDirectories(cfg.TaskManager.GetDirectories(), openMetadataCache(useCache))
+	if err != nil {
+		return nil, err
+	}
+	assignProjects(tasks, cfg.Projects)
+	return filter.apply(tasks, time.Now()), nil
+}
+
+// writeExport writes exported data to a file, or standard output when
+// output is empty
+func writeExport(data []byte, output string) error {
+	if output == "" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(output, data, 0644); err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+	fmt.Printf("Wrote %s\n", filepath.Clean(output))
+	return nil
+}
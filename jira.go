@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// jiraSource is the source field value of tasks imported from Jira
+const jiraSource = "jira"
+
+// jiraTimeFormats are the date formats Jira writes in CSV exports,
+// depending on the site's settings
+var jiraTimeFormats = []string{
+	"02/Jan/06 3:04 PM",
+	"02/Jan/06 15:04",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04:05.000-0700",
+	"02/Jan/06",
+	"2006-01-02",
+}
+
+// jiraRow is one issue of a Jira CSV export. Columns can repeat, e.g.
+// one Labels column per label.
+type jiraRow map[string][]string
+
+// get returns the first value of a column, matched ignoring case
+func (r jiraRow) get(name string) string {
+	if values := r[strings.ToLower(name)]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// importJira reads a Jira CSV export (Export > Export CSV). Issues are
+// matched by their key. The status category (or status name) decides the
+// status, with resolutions such as Won't Do as cancelled; labels become
+// tags; the description is the body; and the issue type and assignee are
+// kept in fields.
+func importJira(data []byte, projects []ProjectConfig, defaultStatus string) ([]importedTask, error) {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\ufeff"))))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	header := records[0]
+	for i := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(header[i]))
+	}
+	if !containsFold(header, "summary") || !containsFold(header, "issue key") {
+		return nil, fmt.Errorf("not a Jira export: needs Summary and Issue key columns")
+	}
+
+	// Exports have only the columns chosen, and only those clear fields
+	carried := []string{"title", "status"}
+	for _, column := range []struct{ name, field string }{
+		{"priority", "priority"}, {"due date", "due_date"}, {"labels", "tags"}, {"resolved", "completed"},
+		{"issue type", "type"}, {"assignee", "assignee"},
+	} {
+		if containsFold(header, column.name) {
+			carried = append(carried, column.field)
+		}
+	}
+
+	var items []importedTask
+	for n, record := range records[1:] {
+		row := jiraRow{}
+		for i, value := range record {
+			if value = strings.TrimSpace(value); i < len(header) && value != "" {
+				row[header[i]] = append(row[header[i]], value)
+			}
+		}
+		key := row.get("Issue key")
+		if key == "" {
+			continue
+		}
+
+		item := importedTask{ref: key, carried: carried}
+		meta := &item.meta
+		meta.Title = row.get("Summary")
+		meta.Fields = map[string]string{
+			"source":    jiraSource,
+			"source_id": key,
+			"type":      row.get("Issue Type"),
+			"assignee":  row.get("Assignee"),
+		}
+		meta.Status = jiraStatus(row, defaultStatus)
+		meta.Priority = priorityFromName(row.get("Priority"))
+
+		for _, field := range []struct {
+			column string
+			dest   *time.Time
+		}{{"Due Date", &meta.DueDate}, {"Created", &meta.Created}, {"Resolved", &meta.Completed}} {
+			value := row.get(field.column)
+			if value == "" {
+				continue
+			}
+			t, err := parseJiraTime(value)
+			if err != nil {
+				return nil, fmt.Errorf("row %d: %s: %w", n+2, field.column, err)
+			}
+			*field.dest = t
+		}
+		if !isDoneStatus(meta.Status) {
+			meta.Completed = time.Time{}
+		}
+
+		for _, label := range row["labels"] {
+			addImportTag(meta, label)
+		}
+		project := row.get("Project key")
+		if _, ok := findProject(projects, project); !ok && row.get("Project name") != "" {
+			project = row.get("Project name")
+		}
+		placeInProject(&item, projects, project)
+
+		item.body = "# " + meta.Title + "\n"
+		if description := strings.TrimSpace(strings.ReplaceAll(row.get("Description"), "\r\n", "\n")); description != "" {
+			item.body += "\n" + description + "\n"
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// jiraStatus maps an issue's status category, or status name for exports
+// without one, to a task status. Done issues resolved as not to be done
+// are cancelled.
+func jiraStatus(row jiraRow, defaultStatus string) string {
+	status := statusFromName(row.get("Status"), defaultStatus)
+	switch strings.ToLower(row.get("Status Category")) {
+	case "done":
+		status = "done"
+	case "in progress":
+		status = "in-progress"
+	case "to do":
+		status = defaultStatus
+	}
+	if resolution := statusFromName(row.get("Resolution"), ""); status == "done" && resolution == "cancelled" {
+		status = "cancelled"
+	}
+	return status
+}
+
+// parseJiraTime parses a date in any of the formats Jira exports
+func parseJiraTime(value string) (time.Time, error) {
+	for _, layout := range jiraTimeFormats {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unknown date format %q", value)
+}
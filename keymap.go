@@ -0,0 +1,396 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// keyAction is something a key can be bound to
+type keyAction struct {
+	name     string   // Name used in the [keys] config
+	contexts []string // Views the action works in: list, task, history or views
+	keys     []string // Default keys; Update switches on the first one
+	help     string   // Description for the help screen
+}
+
+// The views "views" stands for every other view: the pickers, reports,
+// confirmations and the help screen. Views where keys type text use only
+// the quit and back bindings, and only keys that don't type a character.
+var (
+	inList    = []string{"list"}
+	inTask    = []string{"task"}
+	inBoth    = []string{"list", "task"}
+	inHistory = []string{"history"}
+	inMoving  = []string{"list", "history", "views"}
+	inLeaving = []string{"task", "history", "views"}
+	inEvery   = []string{"list", "task", "history", "views"}
+	inOpening = []string{"list", "views"}
+)
+
+// contexts are the views checkConflicts checks, in the order they're
+// reported, with how errors describe them
+var contexts = []struct{ name, description string }{
+	{"list", "the list view"},
+	{"task", "the task view"},
+	{"history", "the history view"},
+	{"views", "the other views"},
+	{"typing", "text fields"},
+}
+
+// fixedKeys are the keys the views handle themselves, which can't be bound
+// to an action that works there
+var fixedKeys = map[string][]string{
+	"list":    {"1", "2", "3", "4", "5", "6", "7", "8", "9", "0"},
+	"history": {"pgdown", "ctrl+d", " ", "pgup", "ctrl+u", "y", "n"},
+	"views":   {"enter", "tab", "g", "s", "r", "m", "d", "y", "n", "1", "2", "3", "4", "5", "6", "7", "8", "9", "0"},
+	"typing":  {"enter", "backspace", "up", "down"},
+}
+
+// noAction is what translate returns for a default key that was rebound
+const noAction = ""
+
+// Every action that can be rebound, in the order the help screen lists them
+var keyActions = []keyAction{
+	{"up", inMoving, []string{"up", "k"}, "Move cursor up"},
+	{"down", inMoving, []string{"down", "j"}, "Move cursor down"},
+	{"open", inList, []string{"enter"}, "View selected task"},
+	{"search", inList, []string{"/"}, "Search/filter tasks"},
+	{"new", inList, []string{"n"}, "Create new task"},
+	{"quick_add", inList, []string{"a"}, "Quick-add a task without an editor"},
+	{"edit", inTask, []string{"e"}, "Edit task in $EDITOR"},
+	{"move", inTask, []string{"m"}, "Move task to another directory"},
+	{"copy", inTask, []string{"c"}, "Copy task to another directory"},
+	{"history", inTask, []string{"h"}, "Show the task's git history and restore revisions"},
+	{"branch", inTask, []string{"b"}, "Create and check out a branch for the task"},
+	{"status", inBoth, []string{"s"}, "Step the status through todo, in-progress and done"},
+	{"status_note", inTask, []string{"u"}, "Change the status with a note"},
+	{"done", inBoth, []string{"x"}, "Mark done, or reopen a done task"},
+	{"completed", inList, []string{"C"}, "Show tasks completed today, this week, or all tasks"},
+	{"tags", inOpening, []string{"#"}, "List tags: enter filters, r renames, m merges, d deletes"},
+	{"projects", inOpening, []string{"P"}, "Show projects; enter lists a project's tasks"},
+	{"clear_filter", inList, []string{"esc"}, "Show all tasks again after picking a tag or project"},
+	{"group", inList, []string{"g"}, "Group by status, priority, directory, project, tag, due date, or not at all"},
+	{"fold", inList, []string{"tab"}, "Collapse or expand the group under the cursor"},
+	{"fold_all", inList, []string{"Z"}, "Collapse or expand every group"},
+	{"views", inList, []string{"v"}, "Choose a saved view"},
+	{"timer", inBoth, []string{"t"}, "Start or stop the timer on the task"},
+	{"time_report", inOpening, []string{"T"}, "Show time spent (tab: grouping, s: period)"},
+	{"stats", inOpening, []string{"R"}, "Show statistics: counts, overdue, weekly throughput"},
+	{"sync", inList, []string{"S"}, "Sync git-backed directories (pull, then push)"},
+	{"delete", inTask, []string{"d"}, "Delete task (with confirmation)"},
+	{"back", inLeaving, []string{"esc"}, "Return to list"},
+	{"restore", inHistory, []string{"r"}, "Restore the selected revision"},
+	{"help", inList, []string{"?", "h"}, "Show this help screen"},
+	{"quit", inEvery, []string{"q", "ctrl+c"}, "Quit application"},
+}
+
+// keyBinding is one or more keys from the [keys] config, written as a
+// string or a list of strings
+type keyBinding []string
+
+// UnmarshalTOML accepts key = "x" as well as key = ["x", "y"]
+func (b *keyBinding) UnmarshalTOML(value interface{}) error {
+	switch v := value.(type) {
+	case string:
+		*b = keyBinding{v}
+	case []interface{}:
+		for _, item := range v {
+			key, ok := item.(string)
+			if !ok {
+				return fmt.Errorf("keys must be strings, got %v", item)
+			}
+			*b = append(*b, key)
+		}
+	default:
+		return fmt.Errorf("keys must be a string or a list of strings, got %v", value)
+	}
+	return nil
+}
+
+// keyMap holds the keys bound to each action. The zero value uses the
+// default keys.
+type keyMap struct {
+	bindings map[string][]string
+}
+
+// keysFor returns the keys bound to an action
+func (k keyMap) keysFor(action string) []string {
+	if k.bindings == nil {
+		found, _ := findKeyAction(action)
+		return found.keys
+	}
+	return k.bindings[action]
+}
+
+// defaultKeyMap returns the built-in bindings
+func defaultKeyMap() keyMap {
+	k := keyMap{bindings: map[string][]string{}}
+	for _, action := range keyActions {
+		k.bindings[action.name] = action.keys
+	}
+	return k
+}
+
+// newKeyMap applies the [keys] config to the default bindings. Unknown
+// actions, empty bindings and keys bound to two actions in the same view
+// are errors, and the default bindings are returned with them.
+func newKeyMap(config map[string]keyBinding) (keyMap, error) {
+	k := defaultKeyMap()
+	if len(config) == 0 {
+		return k, nil
+	}
+
+	for name, keys := range config {
+		if _, ok := findKeyAction(name); !ok {
+			return defaultKeyMap(), fmt.Errorf("unknown key action %q", name)
+		}
+		if len(keys) == 0 {
+			return defaultKeyMap(), fmt.Errorf("no keys given for %q", name)
+		}
+		var normalized []string
+		for _, key := range keys {
+			normalized = append(normalized, normalizeKey(key))
+		}
+		k.bindings[name] = normalized
+	}
+
+	if err := k.checkConflicts(); err != nil {
+		return defaultKeyMap(), err
+	}
+	return k, nil
+}
+
+// findKeyAction returns the action with the given name
+func findKeyAction(name string) (keyAction, bool) {
+	for _, action := range keyActions {
+		if action.name == name {
+			return action, true
+		}
+	}
+	return keyAction{}, false
+}
+
+// normalizeKey turns a key from the config into the form Bubble Tea
+// reports it in
+func normalizeKey(key string) string {
+	switch strings.ToLower(key) {
+	case "space":
+		return " "
+	case "escape":
+		return "esc"
+	case "return":
+		return "enter"
+	}
+	if len(key) > 1 {
+		return strings.ToLower(key)
+	}
+	return key
+}
+
+// checkConflicts reports a key bound to more than one action in a view,
+// or bound to an action in a view that handles the key itself
+func (k keyMap) checkConflicts() error {
+	for _, context := range contexts {
+		owner := map[string]string{}
+		for _, key := range fixedKeys[context.name] {
+			owner[key] = ""
+		}
+		for _, action := range keyActions {
+			if !k.worksIn(action, context.name) {
+				continue
+			}
+			for _, key := range k.keysFor(action.name) {
+				if context.name == "typing" && typesText(key) {
+					continue
+				}
+				other, ok := owner[key]
+				if ok && other == "" {
+					return fmt.Errorf("key %q is bound to %s but already used in %s", keyLabel(key), action.name, context.description)
+				}
+				if ok && other != action.name {
+					return fmt.Errorf("key %q is bound to both %s and %s in %s", keyLabel(key), other, action.name, context.description)
+				}
+				owner[key] = action.name
+			}
+		}
+	}
+	return nil
+}
+
+// worksIn reports whether an action works in a context. Only quit and
+// back work while typing.
+func (k keyMap) worksIn(action keyAction, context string) bool {
+	if context == "typing" {
+		return action.name == "quit" || action.name == "back"
+	}
+	return containsFold(action.contexts, context)
+}
+
+// typesText reports whether a key types a character rather than being a
+// named key such as esc or ctrl+c
+func typesText(key string) bool {
+	return len([]rune(key)) == 1
+}
+
+// modeContext returns the context of the keys pressed in a view
+func modeContext(mode viewMode) string {
+	switch mode {
+	case listMode:
+		return "list"
+	case taskViewMode:
+		return "task"
+	case historyMode:
+		return "history"
+	case quickAddMode, templatePromptMode, statusPickMode, searchMode:
+		return "typing"
+	}
+	return "views"
+}
+
+// translate maps a key pressed in a view to the default key of the
+// action it's bound to, which is what Update switches on. Default keys
+// that were moved to another action or unbound map to noAction. Other
+// keys are returned unchanged.
+func (k keyMap) translate(mode viewMode, key string) string {
+	return k.translateIn(modeContext(mode), key)
+}
+
+// translateTyping is translate for keys pressed while typing text, such
+// as a new tag name, in a view that otherwise doesn't take text
+func (k keyMap) translateTyping(key string) string {
+	return k.translateIn("typing", key)
+}
+
+// translateIn is translate for a context. While typing, keys that type
+// a character are returned unchanged, and quit and back map to the
+// default key that doesn't (ctrl+c and esc), so a typed "q" isn't taken
+// for quit.
+func (k keyMap) translateIn(context, key string) string {
+	if context == "typing" && typesText(key) {
+		return key
+	}
+
+	unbound := false
+	for _, action := range keyActions {
+		if !k.worksIn(action, context) {
+			continue
+		}
+		for _, bound := range k.keysFor(action.name) {
+			if bound == key {
+				return defaultKey(action, context)
+			}
+		}
+		for _, def := range action.keys {
+			if def == key {
+				unbound = true
+			}
+		}
+	}
+	if unbound {
+		return noAction
+	}
+	return key
+}
+
+// defaultKey returns the default key of an action that Update switches
+// on in a context
+func defaultKey(action keyAction, context string) string {
+	if context == "typing" {
+		for _, key := range action.keys {
+			if !typesText(key) {
+				return key
+			}
+		}
+	}
+	return action.keys[0]
+}
+
+// matches reports whether key is bound to the action
+func (k keyMap) matches(action, key string) bool {
+	for _, bound := range k.keysFor(action) {
+		if bound == key {
+			return true
+		}
+	}
+	return false
+}
+
+// label describes the keys bound to an action, e.g. "↑/k"
+func (k keyMap) label(action string) string {
+	var labels []string
+	for _, key := range k.keysFor(action) {
+		labels = append(labels, keyLabel(key))
+	}
+	return strings.Join(labels, "/")
+}
+
+// hint describes an action for a footer, e.g. "n: new"
+func (k keyMap) hint(action, description string) string {
+	return k.label(action) + ": " + description
+}
+
+// moveHint describes the cursor keys for a footer, e.g. "↑/k ↓/j: choose"
+func (k keyMap) moveHint(description string) string {
+	return k.label("up") + " " + k.label("down") + ": " + description
+}
+
+// typingLabel is label for views where keys type text, leaving out keys
+// that type a character. It's empty if every key bound to the action does.
+func (k keyMap) typingLabel(action string) string {
+	var labels []string
+	for _, key := range k.keysFor(action) {
+		if !typesText(key) {
+			labels = append(labels, keyLabel(key))
+		}
+	}
+	return strings.Join(labels, "/")
+}
+
+// typingHint is hint for views where keys type text, or empty if the
+// action has no key that works there
+func (k keyMap) typingHint(action, description string) string {
+	if label := k.typingLabel(action); label != "" {
+		return label + ": " + description
+	}
+	return ""
+}
+
+// joinHints joins footer hints, skipping empty ones
+func joinHints(hints ...string) string {
+	var kept []string
+	for _, hint := range hints {
+		if hint != "" {
+			kept = append(kept, hint)
+		}
+	}
+	return strings.Join(kept, " • ")
+}
+
+// keyLabel shows a key the way footers and the help screen write it
+func keyLabel(key string) string {
+	switch key {
+	case "up":
+		return "↑"
+	case "down":
+		return "↓"
+	case " ":
+		return "space"
+	}
+	return key
+}
+
+// helpLines renders the help screen entries for the actions in a view
+func (k keyMap) helpLines(context string) string {
+	var content string
+	for _, action := range keyActions {
+		if containsFold(action.contexts, context) {
+			content += helpLine(k.label(action.name), action.help)
+		}
+	}
+	return content
+}
+
+// helpLine renders one help screen entry
+func helpLine(key, description string) string {
+	return "  " + helpKeyStyle.Render(fmt.Sprintf("%-13s", key)) + helpDescStyle.Render(description) + "\n"
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestDefaultKeysHaveNoConflicts(t *testing.T) {
+	if err := defaultKeyMap().checkConflicts(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewKeyMapConflicts(t *testing.T) {
+	for _, test := range []struct {
+		config map[string]keyBinding
+		want   string // Part of the error, or empty for none
+	}{
+		{map[string]keyBinding{"new": {"j"}}, "both down and new in the list view"},
+		{map[string]keyBinding{"quit": {"r"}}, "both restore and quit in the history view"},
+		{map[string]keyBinding{"quit": {"5"}}, "bound to quit but already used in the list view"},
+		{map[string]keyBinding{"quit": {"pgup"}}, "already used in the history view"},
+		{map[string]keyBinding{"back": {"backspace"}}, "already used in text fields"},
+		{map[string]keyBinding{"tags": {"d"}}, "already used in the other views"},
+		{map[string]keyBinding{"restore": {"k"}}, "both up and restore in the history view"},
+		// Keys that type a character don't quit while typing, so they
+		// don't conflict there
+		{map[string]keyBinding{"quit": {"Q", "ctrl+q"}}, ""},
+		{map[string]keyBinding{"restore": {"R"}}, ""},
+	} {
+		_, err := newKeyMap(test.config)
+		switch {
+		case test.want == "" && err != nil:
+			t.Errorf("%v: unexpected error %v", test.config, err)
+		case test.want != "" && (err == nil || !strings.Contains(err.Error(), test.want)):
+			t.Errorf("%v: error = %v, want %q", test.config, err, test.want)
+		}
+	}
+}
+
+func TestTranslateInOtherViews(t *testing.T) {
+	keys, err := newKeyMap(map[string]keyBinding{
+		"quit": {"Q", "ctrl+q"},
+		"back": {"ctrl+g"},
+		"up":   {"up", "i"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		mode     viewMode
+		key      string
+		want     string
+		describe string
+	}{
+		{dirPickMode, "Q", "q", "a bound quit key quits"},
+		{dirPickMode, "q", noAction, "the default quit key was rebound"},
+		{dirPickMode, "ctrl+c", noAction, "the default quit key was rebound"},
+		{statsMode, "i", "up", "a bound up key moves the cursor"},
+		{historyMode, "k", noAction, "the default up key was rebound"},
+		{historyMode, "ctrl+g", "esc", "a bound back key goes back"},
+		{historyMode, "r", "r", "restore keeps its default"},
+		{confirmDeleteMode, "Q", "q", "a bound quit key quits"},
+		{tagsMode, "enter", "enter", "fixed keys are unchanged"},
+		{searchMode, "Q", "Q", "keys that type text are typed"},
+		{searchMode, "q", "q", "keys that type text are typed"},
+		{searchMode, "ctrl+q", "ctrl+c", "quit maps to the default key that can't be typed"},
+		{searchMode, "ctrl+g", "esc", "back maps to esc"},
+		{searchMode, "esc", noAction, "the default back key was rebound"},
+		{statusPickMode, "up", "up", "arrows choose a status"},
+	} {
+		if got := keys.translate(test.mode, test.key); got != test.want {
+			t.Errorf("translate(%d, %q) = %q, want %q: %s", test.mode, test.key, got, test.want, test.describe)
+		}
+	}
+	if got := keys.translateTyping("q"); got != "q" {
+		t.Errorf("translateTyping(q) = %q, want q typed", got)
+	}
+}
+
+// press sends a key to the model, as typed
+func press(m model, key string) model {
+	msg := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)}
+	switch key {
+	case "up":
+		msg = tea.KeyMsg{Type: tea.KeyUp}
+	case "down":
+		msg = tea.KeyMsg{Type: tea.KeyDown}
+	}
+	updated, _ := m.Update(msg)
+	return updated.(model)
+}
+
+func TestMoveKeysInListAndSearch(t *testing.T) {
+	keys, err := newKeyMap(map[string]keyBinding{"up": {"up", "i"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := model{keys: keys, tasks: taggedTasks(nil, nil, nil), cursor: 2}
+	m.filterTasks()
+
+	// In the list, only the bound keys move the cursor
+	if m = press(m, "k"); m.cursor != 2 {
+		t.Errorf("the rebound k moved the cursor to %d", m.cursor)
+	}
+	if m = press(press(m, "i"), "up"); m.cursor != 0 {
+		t.Errorf("cursor = %d after i and up, want 0", m.cursor)
+	}
+	if m = press(m, "j"); m.cursor != 1 {
+		t.Errorf("cursor = %d after j, want 1", m.cursor)
+	}
+
+	// While searching, letters are typed and arrows move
+	m = press(m, "/")
+	if m = press(m, "down"); m.cursor != 1 {
+		t.Errorf("cursor = %d after down in search, want 1", m.cursor)
+	}
+	for _, key := range []string{"j", "k", "i"} {
+		m = press(m, key)
+	}
+	if m.searchQuery != "jki" {
+		t.Errorf("search query = %q, want the letters typed", m.searchQuery)
+	}
+}
+
+func TestHints(t *testing.T) {
+	keys, err := newKeyMap(map[string]keyBinding{"quit": {"Q", "ctrl+q"}, "down": {"down", "J"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := keys.hint("quit", "quit"); got != "Q/ctrl+q: quit" {
+		t.Errorf("hint = %q", got)
+	}
+	if got := keys.typingHint("quit", "quit"); got != "ctrl+q: quit" {
+		t.Errorf("typingHint = %q", got)
+	}
+	if got := keys.moveHint("choose"); got != "↑/k ↓/J: choose" {
+		t.Errorf("moveHint = %q", got)
+	}
+	if got := joinHints("a", "", "b"); got != "a • b" {
+		t.Errorf("joinHints = %q", got)
+	}
+
+	typed, _ := newKeyMap(map[string]keyBinding{"back": {"Z"}})
+	if got := typed.typingHint("back", "cancel"); got != "" {
+		t.Errorf("typingHint = %q, want none for keys that type text", got)
+	}
+}
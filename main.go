@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
 	"os/exec"
@@ -13,7 +14,8 @@ import (
 	"github.com/charmbracelet/lipgloss"
 )
 
-// Color styles for the UI
+// Color styles for the UI. These are the dark theme's colors; applyTheme
+// restyles them from the [theme] config.
 var (
 	// Box and border styles - using lighter white/gray colors
 	mainBoxStyle = lipgloss.NewStyle().
@@ -94,17 +96,75 @@ var (
 
 	dimStyle = lipgloss.NewStyle().
 			Foreground(lipgloss.Color("240")) // Very dark gray
+
+	diffAddStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("78")) // Green
+
+	diffRemoveStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("196")) // Red
+
+	diffHunkStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("39")) // Blue
 )
 
 // reloadTasksMsg is sent when we need to reload the task list
 type reloadTasksMsg struct{}
 
+// taskEditedMsg is sent when the editor closes after editing a task
+type taskEditedMsg struct {
+	path      string // The file that was edited
+	oldTitle  string // Its title before editing
+	oldStatus string // Its status before editing
+	isNew     bool   // Whether the task was created just before editing
+}
+
+// taskErrorMsg reports a failed action, such as creating a task, in the
+// footer
+type taskErrorMsg struct {
+	err error
+}
+
+// reportError returns a command that shows err in the footer
+func reportError(err error) tea.Cmd {
+	return func() tea.Msg { return taskErrorMsg{err: err} }
+}
+
+// timerTickMsg redraws the running timer in the footer. gen matches the
+// timer it was scheduled for, so ticks from a stopped timer die out.
+type timerTickMsg struct {
+	gen int
+}
+
+// gitSyncMsg is sent when a background git sync finishes
+type gitSyncMsg struct {
+	results []string // One summary line per repository
+}
+
+// taskCommitsMsg is sent when the search for commits that mention a
+// viewed task finishes
+type taskCommitsMsg struct {
+	path    string         // The task that was searched for
+	commits []linkedCommit // Commits that mention it, newest first
+}
+
+// scanCommitsMsg starts looking for commits that mention tasks, to
+// update their statuses
+type scanCommitsMsg struct{}
+
+// commitStatusesMsg is sent when a background search for commits that
+// mention tasks finishes
+type commitStatusesMsg struct {
+	changed []taskFile // Tasks whose status was changed
+	err     error      // Why the search or an update failed, if it did
+}
+
 // taskFile represents a markdown file with its metadata
 type taskFile struct {
 	name      string       // filename
 	modTime   time.Time    // last modification time
 	fullPath  string       // absolute path to the file
 	sourceDir string       // which directory this task came from
+	project   string       // code of the project the directory belongs to, if any
 	metadata  TaskMetadata // parsed frontmatter metadata
 }
 
@@ -112,76 +172,301 @@ type taskFile struct {
 type viewMode int
 
 const (
-	listMode          viewMode = iota // Showing the list of tasks
-	taskViewMode                      // Viewing a single task's content
-	confirmDeleteMode                 // Confirming task deletion
-	searchMode                        // Searching/filtering tasks
-	helpMode                          // Showing help/keyboard shortcuts
+	listMode           viewMode = iota // Showing the list of tasks
+	taskViewMode                       // Viewing a single task's content
+	confirmDeleteMode                  // Confirming task deletion
+	searchMode                         // Searching/filtering tasks
+	helpMode                           // Showing help/keyboard shortcuts
+	quickAddMode                       // Typing a one-line quick-add task
+	templatePickMode                   // Choosing a template for a new task
+	templatePromptMode                 // Filling in a template's prompted fields
+	dirPickMode                        // Choosing a directory for a new, moved or copied task
+	historyMode                        // Browsing a task's git history
+	timeReportMode                     // Showing time spent, grouped
+	statusPickMode                     // Choosing a new status, with an optional note
+	statsMode                          // Showing workload statistics
+	viewPickMode                       // Choosing a saved view
+	tagsMode                           // Listing tags, renaming, merging or deleting them
+	projectsMode                       // Showing the projects overview
+)
+
+// dirPickPurpose says what the directory picker was opened for
+type dirPickPurpose int
+
+const (
+	pickForNewTask dirPickPurpose = iota // Creating a new task
+	pickForMove                          // Moving the selected task
+	pickForCopy                          // Duplicating the selected task
+	pickForBranch                        // Creating a branch for the selected task
 )
 
 // model represents the application state
 // In Bubble Tea, the model holds all the data your application needs
 type model struct {
-	tasks         []taskFile    // Our list of task files
-	filteredTasks []taskFile    // Filtered list based on search
-	cursor        int           // Which task our cursor is pointing at
-	err           error         // Any error encountered while loading files
-	configDirs    []string      // The configured task directories
-	showDirInfo   bool          // Whether to show directory info for each task
-	config        DisplayConfig // Display configuration
-	mode          viewMode      // Current view mode
-	taskContent   string        // Content of the task being viewed
-	searchQuery   string        // Current search query
-	width         int           // Terminal width
-	height        int           // Terminal height
-}
-
-// visibleTasks returns the list of tasks that should be displayed
-// (either filtered tasks if searching, or all tasks otherwise)
-func (m model) visibleTasks() []taskFile {
-	if m.mode == searchMode && len(m.filteredTasks) > 0 {
-		return m.filteredTasks
+	tasks         []taskFile              // Our list of task files
+	filteredTasks []taskFile              // Filtered list based on search
+	cursor        int                     // Which task our cursor is pointing at
+	listCursor    int                     // List cursor to return to when leaving the task view
+	err           error                   // Any error encountered while loading files
+	configDirs    []string                // The configured task directories
+	showDirInfo   bool                    // Whether to show directory info for each task
+	config        DisplayConfig           // Display configuration
+	taskConfig    TaskManagerConfig       // Task file settings (naming, etc.)
+	gitConfig     GitConfig               // Git auto-commit settings
+	gitRoots      map[string]string       // Repository root for each git-backed directory
+	gitStates     map[string]gitFileState // Uncommitted state of task files, by path
+	scanning      bool                    // Whether commits are being searched for task IDs
+	rescan        bool                    // Whether to search again once the running search finishes
+	gitRepos      []string                // Code repositories linked to tasks
+	cache         *metadataCache          // Parsed metadata cache (nil when disabled)
+	mode          viewMode                // Current view mode
+	taskContent   string                  // Content of the task being viewed
+	taskCommits   []linkedCommit          // Commits that mention the task being viewed
+	searchQuery   string                  // Current search query
+	completedOnly int                     // Index into completedFilters; 0 shows every task
+	views         []ViewConfig            // Saved views from the config
+	activeView    int                     // 1-based index into views; 0 shows every task
+	viewCursor    int                     // Selected entry in the view picker
+	groupBy       string                  // How the list is grouped, one of groupKeys or "" for no groups
+	collapsed     map[string]bool         // Collapsed groups, by name
+	keys          keyMap                  // Keys bound to each action
+	tagFilter     string                  // Only list tasks with this tag, if set
+	projects      []ProjectConfig         // Configured projects
+	projectFilter string                  // Only list tasks in the project with this code, if set
+	projectCursor int                     // Selected project in the overview
+	quickAddInput string                  // Text typed into the quick-add bar
+	quickAddErr   error                   // Error from the last quick-add attempt
+	message       string                  // One-off status message shown in the footer
+	width         int                     // Terminal width
+	height        int                     // Terminal height
+
+	// New task from a template
+	targetDir       string            // Directory the new task will be written to
+	lastDir         string            // Directory chosen for the previous new task
+	dirCursor       int               // Selected directory in the picker
+	dirPickFor      dirPickPurpose    // What the picker was opened for
+	templates       []taskTemplate    // Templates offered by the picker
+	templateCursor  int               // Selected template in the picker
+	pendingTemplate taskTemplate      // Template being filled in
+	promptFields    []string          // Fields the template asks for
+	promptAnswers   map[string]string // Answers given so far, by field
+	promptInput     string            // Text typed for the current field
+
+	// Revision history of the viewed task
+	history        []gitRevision // Commits that touched the task, newest first
+	historyRoot    string        // Repository the commits belong to
+	historyCursor  int           // Selected revision
+	historyDiff    string        // Diff of the selected revision
+	historyScroll  int           // First diff line shown (also used by the stats view)
+	confirmRestore bool          // Whether we're asking to restore the selected revision
+
+	// Status change with a note
+	statusCursor int    // Selected status in statusCycle
+	statusNote   string // Note typed for the change
+
+	// Tag management
+	tagCounts    []tagCount      // Every tag with its task count
+	tagCursor    int             // Selected row of the tags view
+	tagCollapsed map[string]bool // Parent tags whose nested tags are hidden, lowercased
+	tagAction    string          // Pending action on the selected tag: rename, merge or delete
+	tagInput     string          // New name typed for a rename or merge
+	tagPreview   []tagChange     // Changes waiting to be confirmed
+
+	// Time tracking
+	timer       *activeTimer // The running timer, if any
+	timerGen    int          // Bumped whenever a timer starts, see timerTickMsg
+	reportGroup int          // Index into timeReportGroups for the time report
+	reportSince int          // Index into timeReportPeriods for the time report
+}
+
+// Periods the time report can cover, as understood by parseSince
+var timeReportPeriods = []string{"", "today", "week", "month"}
+
+// selectedTask returns the task under the cursor, if there is one. There
+// isn't when the cursor is on a collapsed group.
+func (m model) selectedTask() (taskFile, bool) {
+	rows := m.listRows()
+	if m.cursor < len(rows) && !rows[m.cursor].header {
+		return rows[m.cursor].task, true
+	}
+	return taskFile{}, false
+}
+
+// defaultTaskDir picks the directory a new task goes into unless the
+// user chooses another: the directory every filtered task shares, then
+// the selected task's directory, then the last one chosen, then the
+// first configured directory
+func (m model) defaultTaskDir() string {
+	visibleTasks := m.visibleTasks()
+	if len(visibleTasks) > 0 && len(visibleTasks) < len(m.tasks) {
+		shared := visibleTasks[0].sourceDir
+		for _, task := range visibleTasks[1:] {
+			if task.sourceDir != shared {
+				shared = ""
+				break
+			}
+		}
+		if shared != "" {
+			return shared
+		}
+	}
+
+	if task, ok := m.selectedTask(); ok {
+		return task.sourceDir
 	}
+
+	for _, dir := range m.configDirs {
+		if dir == m.lastDir {
+			return dir
+		}
+	}
+
+	return m.configDirs[0]
+}
+
+// visibleTasks returns the list of tasks that should be displayed, in
+// the order they are listed
+func (m model) visibleTasks() []taskFile {
+	return flattenGroups(m.taskGroups())
+}
+
+// taskGroups returns the tasks to display (either filtered tasks if
+// searching, or all tasks otherwise), narrowed to recently completed
+// tasks if that filter is on, then filtered and sorted by the active view
+// and grouped by the current grouping
+func (m model) taskGroups() []taskGroup {
+	tasks := m.tasks
 	if m.mode == searchMode && m.searchQuery != "" {
-		return []taskFile{} // No matches
+		tasks = m.filteredTasks
+	}
+	if m.completedOnly > 0 {
+		since, _ := parseSince(completedFilters[m.completedOnly], time.Now())
+		tasks = completedSince(tasks, since)
+	}
+	if m.tagFilter != "" {
+		tasks = taskFilter{terms: []filterTerm{{match: matchTags([]string{m.tagFilter})}}}.apply(tasks, time.Now())
+	}
+	if m.projectFilter != "" {
+		inProject := []taskFile{}
+		for _, task := range tasks {
+			if task.project == m.projectFilter {
+				inProject = append(inProject, task)
+			}
+		}
+		tasks = inProject
 	}
-	return m.tasks
+	view, _ := m.currentView()
+	view.Group = m.groupBy
+	// Views are checked when they are chosen, so this can't fail
+	if groups, err := applyView(tasks, view, m.config.GetDefaultStatus(), time.Now()); err == nil {
+		return groups
+	}
+	return []taskGroup{{tasks: tasks}}
 }
 
-// filterTasks filters the task list based on the search query
-func (m *model) filterTasks() {
-	if m.searchQuery == "" {
-		m.filteredTasks = m.tasks
-		return
+// currentView returns the active saved view, if there is one
+func (m model) currentView() (ViewConfig, bool) {
+	if m.activeView > 0 && m.activeView <= len(m.views) {
+		return m.views[m.activeView-1], true
 	}
+	return ViewConfig{}, false
+}
 
-	query := strings.ToLower(m.searchQuery)
-	m.filteredTasks = []taskFile{}
+// defaultGrouping returns the active view's grouping, or the configured
+// group_by when no view is active
+func (m model) defaultGrouping() string {
+	if view, ok := m.currentView(); ok {
+		return strings.ToLower(view.Group)
+	}
+	if containsFold(groupKeys, m.config.GroupBy) {
+		return strings.ToLower(m.config.GroupBy)
+	}
+	return ""
+}
 
-	for _, task := range m.tasks {
-		// Search in filename
-		if strings.Contains(strings.ToLower(task.name), query) {
-			m.filteredTasks = append(m.filteredTasks, task)
-			continue
-		}
-		// Search in title
-		if strings.Contains(strings.ToLower(task.metadata.Title), query) {
-			m.filteredTasks = append(m.filteredTasks, task)
-			continue
+// selectView switches to the saved view at the 1-based index i, or back
+// to every task for 0
+func (m model) selectView(i int) model {
+	if i > len(m.views) {
+		return m
+	}
+	if i > 0 {
+		if err := validateView(m.views[i-1]); err != nil {
+			m.message = err.Error()
+			return m
 		}
-		// Search in status
-		if strings.Contains(strings.ToLower(task.metadata.Status), query) {
-			m.filteredTasks = append(m.filteredTasks, task)
-			continue
+	}
+	m.activeView = i
+	m.groupBy = m.defaultGrouping()
+	m.collapsed = nil
+	m.cursor = 0
+	m.mode = listMode
+	return m
+}
+
+// viewTask opens a task in the task view. The cursor is pointed at the
+// task in the full list, which the task view works from, and the list
+// position is remembered for when the view is closed.
+func (m model) viewTask(task taskFile) (model, tea.Cmd) {
+	content, err := os.ReadFile(task.fullPath)
+	if err != nil {
+		m.err = fmt.Errorf("failed to read task: %w", err)
+		return m, nil
+	}
+
+	for i, t := range m.tasks {
+		if t.fullPath == task.fullPath {
+			m.listCursor = m.cursor
+			m.cursor = i
+			break
 		}
-		// Search in tags
-		for _, tag := range task.metadata.Tags {
-			if strings.Contains(strings.ToLower(tag), query) {
-				m.filteredTasks = append(m.filteredTasks, task)
-				break
-			}
+	}
+	m.mode = taskViewMode
+	m.taskContent = string(content)
+	m.taskCommits = nil
+
+	// Searching the repositories can take a while, so do it in the
+	// background
+	if task.metadata.ID == "" || len(m.gitRepos) == 0 {
+		return m, nil
+	}
+	repos, meta := m.gitRepos, task.metadata
+	return m, func() tea.Msg {
+		return taskCommitsMsg{path: task.fullPath, commits: commitsForTask(repos, meta)}
+	}
+}
+
+// changeStatus sets a task's status (recording the change and updating
+// its completed field) and refreshes it in place
+func (m model) changeStatus(task taskFile, status, note string) (tea.Model, tea.Cmd) {
+	if err := setTaskStatus(task.fullPath, status, note, time.Now()); err != nil {
+		m.message = err.Error()
+		return m, nil
+	}
+
+	m.message = fmt.Sprintf("Marked %s", status)
+	if updated, ok := m.refreshTask(task.fullPath); ok {
+		if err := m.gitConfig.autoCommit("status", updated.metadata, updated.sourceDir, updated.fullPath); err != nil {
+			m.message = err.Error()
 		}
 	}
+	return m, nil
+}
+
+// filterTasks filters the task list based on the search query, which
+// can use the same terms as a view's query
+func (m *model) filterTasks() {
+	if m.searchQuery == "" {
+		m.filteredTasks = m.tasks
+		return
+	}
+
+	filter, err := parseQuery(m.searchQuery)
+	if err != nil {
+		// Half-typed terms like due:tom are searched for as text
+		filter = taskFilter{terms: []filterTerm{{match: matchText(strings.ToLower(m.searchQuery))}}}
+	}
+	m.filteredTasks = filter.apply(m.tasks, time.Now())
 
 	// Reset cursor if out of bounds
 	if m.cursor >= len(m.filteredTasks) {
@@ -213,8 +498,8 @@ func embedTitleInBorder(box string, title string) string {
 		return box // Not enough space
 	}
 
-	// Create styled border characters
-	borderStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("250"))
+	// Create styled border characters in the theme's border color
+	borderStyle := lipgloss.NewStyle().Foreground(mainBoxStyle.GetBorderTopForeground())
 
 	// Calculate how many horizontal line characters we need
 	// The original border is: space + corner + line chars + corner
@@ -243,7 +528,8 @@ func expandPath(path string) (string, error) {
 }
 
 // loadTasksFromDirectory reads all .md files from the specified directory
-func loadTasksFromDirectory(dir string) ([]taskFile, error) {
+// Metadata is taken from the cache when the file hasn't changed
+func loadTasksFromDirectory(dir string, cache *metadataCache) ([]taskFile, error) {
 	// Expand the tilde (~) to the user's home directory
 	expandedDir, err := expandPath(dir)
 	if err != nil {
@@ -278,9 +564,9 @@ func loadTasksFromDirectory(dir string) ([]taskFile, error) {
 
 		fullPath := filepath.Join(expandedDir, entry.Name())
 
-		// Parse frontmatter metadata
-		metadata, _ := parseFrontmatter(fullPath)
-		// We ignore errors here - files without frontmatter are valid
+		// Parse frontmatter metadata (or reuse the cached copy)
+		// Errors are ignored here - files without frontmatter are valid
+		metadata := cache.metadataFor(fullPath, info)
 
 		tasks = append(tasks, taskFile{
 			name:      entry.Name(),
@@ -295,12 +581,13 @@ func loadTasksFromDirectory(dir string) ([]taskFile, error) {
 }
 
 // loadTasksFromDirectories reads all .md files from multiple directories
-func loadTasksFromDirectories(dirs []string) ([]taskFile, error) {
+// The cache may be nil, in which case every file is parsed
+func loadTasksFromDirectories(dirs []string, cache *metadataCache) ([]taskFile, error) {
 	var allTasks []taskFile
 	var errors []string
 
 	for _, dir := range dirs {
-		tasks, err := loadTasksFromDirectory(dir)
+		tasks, err := loadTasksFromDirectory(dir, cache)
 		if err != nil {
 			// Don't fail completely, just track the error
 			errors = append(errors, fmt.Sprintf("%s: %v", dir, err))
@@ -309,6 +596,12 @@ func loadTasksFromDirectories(dirs []string) ([]taskFile, error) {
 		allTasks = append(allTasks, tasks...)
 	}
 
+	// Persist the cache for the next run - a failure here only costs
+	// us speed next time, so it's reported but never fatal
+	if err := cache.save(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+
 	// Sort all tasks by modification time (newest first)
 	sort.Slice(allTasks, func(i, j int) bool {
 		return allTasks[i].modTime.After(allTasks[j].modTime)
@@ -332,7 +625,8 @@ func loadTasksFromDirectories(dirs []string) ([]taskFile, error) {
 }
 
 // initialModel creates the starting state of our application
-func initialModel() model {
+// Pass useCache=false to always re-parse every file (--no-cache)
+func initialModel(useCache bool) model {
 	// Load configuration
 	cfg, err := loadConfig()
 	if err != nil {
@@ -350,18 +644,93 @@ func initialModel() model {
 	// Get all configured directories
 	dirs := cfg.TaskManager.GetDirectories()
 
+	// Restore the directory last chosen for a new task
+	state := loadState()
+
+	// Find directories that live in git repositories
+	gitRoots := gitRepoRoots(dirs)
+
+	// Open the metadata cache unless it was disabled
+	cache := openMetadataCache(useCache)
+
 	// Load tasks from all configured directories
-	tasks, loadErr := loadTasksFromDirectories(dirs)
+	tasks, loadErr := loadTasksFromDirectories(dirs, cache)
+	assignProjects(tasks, cfg.Projects)
 
-	return model{
+	m := model{
 		tasks:       tasks,
 		cursor:      0,
 		err:         loadErr,
 		configDirs:  dirs,
 		showDirInfo: len(dirs) > 1, // Show directory info if multiple directories
 		config:      cfg.Display,
+		taskConfig:  cfg.TaskManager,
+		gitConfig:   cfg.Git,
+		gitRoots:    gitRoots,
+		gitRepos:    expandRepos(cfg.Git.Repos),
+		cache:       cache,
+		lastDir:     state.LastDir,
+		timer:       state.Timer,
+		views:       cfg.Views,
+		projects:    cfg.Projects,
 		mode:        listMode,
 	}
+
+	// A bad theme leaves the default colors, and says why
+	if err := applyTheme(cfg.Theme); err != nil {
+		m.message = err.Error()
+	}
+
+	// Unknown columns fall back to the default columns
+	if err := m.config.validateColumns(); err != nil {
+		m.message = err.Error()
+		m.config.Columns = nil
+		m.config.ColumnOptions = nil
+	}
+
+	// Conflicting bindings fall back to the default keys
+	keys, err := newKeyMap(cfg.Keys)
+	if err != nil {
+		m.message = err.Error()
+	}
+	m.keys = keys
+
+	m.groupBy = m.defaultGrouping()
+
+	m.gitStates = gitFileStates(uniqueRoots(gitRoots))
+	return m
+}
+
+// updateStatusesFromCommits returns a command that moves tasks to
+// in-progress or done when commits in the linked repositories mention
+// them, if enabled. Searching long histories can take a while, so it
+// runs in the background; if a search is already running, another one
+// follows it.
+func (m *model) updateStatusesFromCommits() tea.Cmd {
+	if !m.gitConfig.AutoStatus || len(m.gitRepos) == 0 {
+		return nil
+	}
+	if m.scanning {
+		m.rescan = true
+		return nil
+	}
+	m.scanning = true
+
+	// The command gets its own copy of the tasks to update
+	tasks := append([]taskFile(nil), m.tasks...)
+	repos, gitConfig := m.gitRepos, m.gitConfig
+	return func() tea.Msg {
+		changed, err := applyCommitStatuses(tasks, repos)
+		if err != nil {
+			return commitStatusesMsg{changed: changed, err: err}
+		}
+		for _, task := range changed {
+			if err := gitConfig.autoCommit("status", task.metadata, task.sourceDir, task.fullPath); err != nil {
+				return commitStatusesMsg{changed: changed, err: err}
+			}
+		}
+		return commitStatusesMsg{changed: changed}
+	}
 }
 
 // getEditor returns the user's preferred editor
@@ -381,59 +750,280 @@ func getEditor() string {
 // editTask opens the current task in the user's editor
 func (m model) editTask() tea.Cmd {
 	editor := getEditor()
-	taskPath := m.tasks[m.cursor].fullPath
+	task := m.tasks[m.cursor]
 
-	c := exec.Command(editor, taskPath)
+	c := exec.Command(editor, task.fullPath)
 	return tea.ExecProcess(c, func(err error) tea.Msg {
-		// After editing, reload the task list to show updated content
-		return reloadTasksMsg{}
+		// After editing, rename if needed and reload the task list
+		return taskEditedMsg{path: task.fullPath, oldTitle: task.metadata.Title, oldStatus: task.metadata.Status}
 	})
 }
 
-// createTask creates a new task file and opens it in the editor
-func (m model) createTask() tea.Cmd {
+// chooseTaskDir begins creating a new task by asking which directory it
+// belongs in. With a single directory there's nothing to ask.
+func (m model) chooseTaskDir() (tea.Model, tea.Cmd) {
+	defaultDir := m.defaultTaskDir()
+
+	if len(m.configDirs) == 1 {
+		m.targetDir = defaultDir
+		return m.startCreateTask()
+	}
+
+	m.mode = dirPickMode
+	m.dirPickFor = pickForNewTask
+	m.dirCursor = 0
+	for i, dir := range m.configDirs {
+		if dir == defaultDir {
+			m.dirCursor = i
+		}
+	}
+	return m, nil
+}
+
+// chooseTransferDir opens the directory picker to move or copy the
+// current task, starting on the first directory it isn't already in
+func (m model) chooseTransferDir(purpose dirPickPurpose) (tea.Model, tea.Cmd) {
+	if len(m.configDirs) < 2 {
+		m.message = "Only one directory is configured"
+		return m, nil
+	}
+
+	m.mode = dirPickMode
+	m.dirPickFor = purpose
+	m.dirCursor = 0
+	for i, dir := range m.configDirs {
+		if dir != m.tasks[m.cursor].sourceDir {
+			m.dirCursor = i
+			break
+		}
+	}
+	return m, nil
+}
+
+// pickerDirs returns the directories the picker offers: linked
+// repositories when creating a branch, task directories otherwise
+func (m model) pickerDirs() []string {
+	if m.dirPickFor == pickForBranch {
+		return m.gitRepos
+	}
+	return m.configDirs
+}
+
+// chooseBranchRepo asks which linked repository to create the current
+// task's branch in. With a single repository there's nothing to ask.
+func (m model) chooseBranchRepo() (tea.Model, tea.Cmd) {
+	switch len(m.gitRepos) {
+	case 0:
+		m.message = "No repositories configured (set repos under [git])"
+		return m, nil
+	case 1:
+		return m.createBranch(m.gitRepos[0])
+	}
+
+	m.mode = dirPickMode
+	m.dirPickFor = pickForBranch
+	m.dirCursor = 0
+	return m, nil
+}
+
+// createBranch creates and checks out the current task's branch in repo
+func (m model) createBranch(repo string) (tea.Model, tea.Cmd) {
+	task := &m.tasks[m.cursor]
+	m.mode = taskViewMode
+
+	name, addedID, err := createTaskBranch(repo, task.fullPath, m.gitConfig.GetBranchPattern())
+	if addedID {
+		// The task was given an ID for the branch name, so show it
+		if meta, err := parseFrontmatter(task.fullPath); err == nil {
+			task.metadata = meta
+		}
+		if content, err := os.ReadFile(task.fullPath); err == nil {
+			m.taskContent = string(content)
+		}
+		if err := m.gitConfig.autoCommit("edit", task.metadata, task.sourceDir, task.fullPath); err != nil {
+			m.message = err.Error()
+			return m, nil
+		}
+	}
+	if err != nil {
+		m.message = err.Error()
+		return m, nil
+	}
+
+	m.message = fmt.Sprintf("Checked out %s in %s", name, filepath.Base(repo))
+	return m, nil
+}
+
+// transferTask moves or copies the current task into dir
+func (m model) transferTask(dir string) (tea.Model, tea.Cmd) {
+	task := m.tasks[m.cursor]
+
+	var result transferResult
+	var err error
+	if m.dirPickFor == pickForMove {
+		result, err = moveTask(task.fullPath, dir, m.configDirs)
+	} else {
+		result, err = copyTask(task.fullPath, dir)
+	}
+	if err != nil {
+		m.mode = taskViewMode
+		m.message = err.Error()
+		return m, nil
+	}
+
+	if m.dirPickFor == pickForMove {
+		m.message = fmt.Sprintf("Moved to %s", filepath.Join(dir, filepath.Base(result.newPath)))
+		if len(result.updatedPaths) > 0 {
+			m.message += fmt.Sprintf(" (updated links in %d tasks)", len(result.updatedPaths))
+		}
+		paths := append([]string{task.fullPath, result.newPath}, result.updatedPaths...)
+		err = m.gitConfig.autoCommit("move", task.metadata, dir, paths...)
+	} else {
+		m.message = fmt.Sprintf("Copied to %s", filepath.Join(dir, filepath.Base(result.newPath)))
+		err = m.gitConfig.autoCommit("copy", task.metadata, dir, result.newPath)
+	}
+	if err != nil {
+		m.message = err.Error()
+	}
+	return m, func() tea.Msg { return reloadTasksMsg{} }
+}
+
+// startCreateTask continues creating a task in m.targetDir. With more
+// than one template available it shows the template picker first.
+func (m model) startCreateTask() (tea.Model, tea.Cmd) {
+	templates, err := loadTemplates(m.targetDir)
+	if err != nil {
+		m.err = err
+		return m, nil
+	}
+
+	if len(templates) == 1 {
+		return m.beginTemplate(templates[0])
+	}
+
+	m.mode = templatePickMode
+	m.templates = templates
+	m.templateCursor = 0
+
+	// Start on the project's default template
+	if project, ok := projectForDir(m.projects, m.targetDir); ok {
+		for i, t := range templates {
+			if strings.EqualFold(t.name, project.DefaultTemplate) {
+				m.templateCursor = i
+			}
+		}
+	}
+	return m, nil
+}
+
+// beginTemplate asks for the template's prompted fields, or creates the
+// task straight away if it has none
+func (m model) beginTemplate(t taskTemplate) (tea.Model, tea.Cmd) {
+	m.pendingTemplate = t
+	m.promptFields = t.prompts()
+	m.promptAnswers = make(map[string]string)
+	m.promptInput = ""
+
+	if len(m.promptFields) == 0 {
+		m.mode = listMode
+		return m, m.createTask(t, m.promptAnswers)
+	}
+
+	m.mode = templatePromptMode
+	return m, nil
+}
+
+// createTask creates a new task file from a template and opens it in the editor
+func (m model) createTask(t taskTemplate, answers map[string]string) tea.Cmd {
 	editor := getEditor()
 
-	// Use the first configured directory for new tasks
-	firstDir, err := expandPath(m.configDirs[0])
+	// Write into the directory chosen for this task
+	targetDir, err := expandPath(m.targetDir)
 	if err != nil {
-		return nil
+		return reportError(fmt.Errorf("failed to create task: %w", err))
 	}
 
-	// Generate a filename based on timestamp
-	timestamp := time.Now().Format("20060102-150405")
-	filename := fmt.Sprintf("task-%s.md", timestamp)
-	taskPath := filepath.Join(firstDir, filename)
+	// Fill in the template's variables
+	now := time.Now()
+	id := newTaskID()
+	content := t.expand(templateVars{
+		id:      id,
+		title:   answers["title"],
+		dir:     m.targetDir,
+		now:     now,
+		answers: answers,
+	})
 
-	// Create a template for the new task
-	template := `---
-title: "New Task"
-status: todo
-priority: medium
-created: ` + time.Now().Format(time.RFC3339) + `
----
+	// Name the file after the title the template produced
+	meta := parseFrontmatterBytes([]byte(content))
+	hasID := meta.ID != ""
+	if !hasID {
+		meta.ID = id
+	}
+	pattern := m.taskConfig.GetFilenamePattern()
+	filename := renderFilename(pattern, meta, now)
 
-# New Task
+	taskPath, err := writeNewTaskFile(targetDir, filename, []byte(content))
+	if err != nil {
+		return reportError(err)
+	}
 
-Write your task description here...
-`
+	// A filename with an ID needs that ID stored in the task too
+	if !hasID && patternNeedsID(pattern) {
+		if _, err := ensureTaskID(taskPath); err != nil {
+			// Don't leave a task behind without the ID its name refers to
+			os.Remove(taskPath)
+			return reportError(fmt.Errorf("failed to add an id to the new task: %w", err))
+		}
+	}
 
-	// Write the template to the file
-	if err := os.WriteFile(taskPath, []byte(template), 0644); err != nil {
-		return nil
+	// Tasks in a project start with its default tags
+	if project, ok := projectForDir(m.projects, m.targetDir); ok {
+		if err := addDefaultTags(taskPath, project); err != nil {
+			os.Remove(taskPath)
+			return reportError(fmt.Errorf("failed to add %s's default tags to the new task: %w", project.Name, err))
+		}
 	}
 
 	// Open in editor
 	c := exec.Command(editor, taskPath)
 	return tea.ExecProcess(c, func(err error) tea.Msg {
-		// Return a message to reload tasks and go back to list mode
-		return reloadTasksMsg{}
+		// Rename after the real title if it changed, then reload
+		return taskEditedMsg{path: taskPath, oldTitle: meta.Title, oldStatus: meta.Status, isNew: true}
 	})
 }
 
+// writeNewTaskFile writes content to a new file called filename in dir
+// and returns its path. If a file with that name already exists a
+// numeric suffix is added (fix-login-2.md, fix-login-3.md, ...).
+func writeNewTaskFile(dir, filename string, content []byte) (string, error) {
+	for i := 1; ; i++ {
+		taskPath := filepath.Join(dir, numberedFilename(filename, i))
+
+		// O_EXCL makes the existence check and the create a single step
+		f, err := os.OpenFile(taskPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to create task file: %w", err)
+		}
+
+		if _, err := f.Write(content); err != nil {
+			f.Close()
+			return "", fmt.Errorf("failed to write task file: %w", err)
+		}
+		if err := f.Close(); err != nil {
+			return "", fmt.Errorf("failed to write task file: %w", err)
+		}
+		return taskPath, nil
+	}
+}
+
 // deleteTask deletes the current task file after confirmation
 func (m model) deleteTask() tea.Model {
-	taskPath := m.tasks[m.cursor].fullPath
+	task := m.tasks[m.cursor]
+	taskPath := task.fullPath
 
 	// Delete the file
 	if err := os.Remove(taskPath); err != nil {
@@ -442,37 +1032,183 @@ func (m model) deleteTask() tea.Model {
 		return m
 	}
 
-	// Remove the task from the list
-	m.tasks = append(m.tasks[:m.cursor], m.tasks[m.cursor+1:]...)
+	// Record the deletion in git if enabled
+	if err := m.gitConfig.autoCommit("delete", task.metadata, task.sourceDir, taskPath); err != nil {
+		m.message = err.Error()
+	}
 
-	// Adjust cursor if needed
-	if m.cursor >= len(m.tasks) && m.cursor > 0 {
-		m.cursor--
+	// A timer on the task has nowhere to log its time
+	if m.timer != nil && m.timer.Path == taskPath {
+		if err := discardTimer(); err != nil {
+			m.message = err.Error()
+		}
+		m.timer = nil
 	}
 
-	// Return to list mode
+	// Remove the task from the list and any search results
+	m.tasks = append(m.tasks[:m.cursor], m.tasks[m.cursor+1:]...)
+	m.filterTasks()
+
+	// Return to list mode, where the list was left
 	m.mode = listMode
 	m.taskContent = ""
+	m.cursor = min(m.listCursor, max(len(m.listRows())-1, 0))
 
 	return m
 }
 
+// openHistory loads the git history of the viewed task
+func (m model) openHistory() (tea.Model, tea.Cmd) {
+	task := m.tasks[m.cursor]
+	history, root, err := gitFileHistory(task.fullPath)
+	if err != nil {
+		m.message = err.Error()
+		return m, nil
+	}
+	if len(history) == 0 {
+		m.message = "No commits touch this task yet"
+		return m, nil
+	}
+
+	m.history = history
+	m.historyRoot = root
+	m.confirmRestore = false
+	m.mode = historyMode
+	return m.selectRevision(0), nil
+}
+
+// selectRevision moves the history cursor and loads that revision's diff
+func (m model) selectRevision(i int) model {
+	m.historyCursor = i
+	m.historyScroll = 0
+	diff, err := gitRevisionDiff(m.historyRoot, m.history[i])
+	if err != nil {
+		diff = err.Error()
+	}
+	m.historyDiff = diff
+	return m
+}
+
+// restoreSelectedRevision writes the selected revision back over the task
+func (m model) restoreSelectedRevision() (tea.Model, tea.Cmd) {
+	task := m.tasks[m.cursor]
+	rev := m.history[m.historyCursor]
+	m.confirmRestore = false
+
+	if err := restoreRevision(task.fullPath, m.historyRoot, rev); err != nil {
+		m.message = err.Error()
+		return m, nil
+	}
+
+	m.message = fmt.Sprintf("Restored %s from %s", task.name, rev.shortHash())
+	meta, _ := parseFrontmatter(task.fullPath)
+	if err := m.gitConfig.autoCommit("restore", meta, task.sourceDir, task.fullPath); err != nil {
+		m.message = err.Error()
+	}
+	return m, func() tea.Msg { return reloadTasksMsg{} }
+}
+
 // Init is called once when the program starts
 // It can return a command to run (we don't need any for now)
 func (m model) Init() tea.Cmd {
-	// No initial commands needed for this simple app
-	return nil
+	// Catch up on commits made since the last run
+	scan := func() tea.Msg { return scanCommitsMsg{} }
+
+	// Keep a timer left running by a previous session ticking
+	if m.timer != nil {
+		return tea.Batch(scan, m.tickTimer())
+	}
+	return scan
 }
 
-// Update is called when something happens (like a key press)
-// This is where we handle user input and update our model
-func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
-	switch msg := msg.(type) {
+// tickTimer schedules the next redraw of the running timer
+func (m model) tickTimer() tea.Cmd {
+	gen := m.timerGen
+	return tea.Tick(time.Second, func(time.Time) tea.Msg {
+		return timerTickMsg{gen: gen}
+	})
+}
 
-	// Handle window resize
-	case tea.WindowSizeMsg:
-		m.width = msg.Width
-		m.height = msg.Height
+// toggleTimer starts timing a task, or stops the timer if it's already
+// running for that task. Starting one stops any other.
+func (m model) toggleTimer(task taskFile) (tea.Model, tea.Cmd) {
+	if m.timer != nil && m.timer.Path == task.fullPath {
+		return m.stopTimer()
+	}
+
+	stopped, err := startTimer(task.fullPath, m.configDirs)
+	if err != nil {
+		m.message = err.Error()
+		return m, nil
+	}
+	if stopped != nil {
+		m.refreshTask(stopped.Path)
+	}
+
+	m.timer = loadState().Timer
+	m.timerGen++
+	m.message = "Started timer"
+	if stopped != nil {
+		m.message = fmt.Sprintf("Stopped timer on %s, started timer", stopped.Title)
+	}
+	return m, m.tickTimer()
+}
+
+// stopTimer stops the running timer and logs the time on its task
+func (m model) stopTimer() (tea.Model, tea.Cmd) {
+	timer, taskPath, err := stopTimer(m.configDirs)
+	m.timer = nil
+	if err != nil {
+		m.message = err.Error()
+		return m, nil
+	}
+
+	m.message = fmt.Sprintf("Logged %s on %s", formatDuration(time.Since(timer.Start)), timer.Title)
+	if task, ok := m.refreshTask(taskPath); ok {
+		if err := m.gitConfig.autoCommit("time", task.metadata, task.sourceDir, taskPath); err != nil {
+			m.message = err.Error()
+		}
+	}
+	return m, nil
+}
+
+// refreshTask re-reads a task the app just changed, so the list and the
+// task view show it without a full reload
+func (m *model) refreshTask(taskPath string) (taskFile, bool) {
+	for i, task := range m.tasks {
+		if task.fullPath != taskPath {
+			continue
+		}
+		if meta, err := parseFrontmatter(taskPath); err == nil {
+			m.tasks[i].metadata = meta
+		}
+		if m.mode == taskViewMode && i == m.cursor {
+			if content, err := os.ReadFile(taskPath); err == nil {
+				m.taskContent = string(content)
+			}
+		}
+		return m.tasks[i], true
+	}
+	return taskFile{}, false
+}
+
+// timerIndicator describes the running timer for the footer
+func (m model) timerIndicator() string {
+	if m.timer == nil {
+		return ""
+	}
+	return statusInProgressStyle.Render(fmt.Sprintf("⏱ %s %s", m.timer.Title, formatElapsed(time.Since(m.timer.Start))))
+}
+
+// Update is called when something happens (like a key press)
+// This is where we handle user input and update our model
+func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+
+	// Handle window resize
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
 		// Update box styles with new width
 		mainBoxStyle = mainBoxStyle.Width(m.width - 4)
 		dirBoxStyle = dirBoxStyle.Width(m.width - 4)
@@ -481,20 +1217,493 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	// Handle reload tasks message
 	case reloadTasksMsg:
 		// Reload tasks from all configured directories
-		tasks, err := loadTasksFromDirectories(m.configDirs)
+		tasks, err := loadTasksFromDirectories(m.configDirs, m.cache)
+		assignProjects(tasks, m.projects)
 		m.tasks = tasks
 		m.err = err
 		m.mode = listMode
 		m.taskContent = ""
 		// Reset cursor to top
 		m.cursor = 0
+		// Refresh the uncommitted-changes indicators
+		m.gitStates = gitFileStates(uniqueRoots(m.gitRoots))
+		// Pick up commits that mention tasks
+		return m, m.updateStatusesFromCommits()
+
+	// Look for commits that mention tasks
+	case scanCommitsMsg:
+		return m, m.updateStatusesFromCommits()
+
+	// Show the statuses changed by commits
+	case commitStatusesMsg:
+		m.scanning = false
+		for _, task := range msg.changed {
+			m.refreshTask(task.fullPath)
+		}
+		if len(msg.changed) > 0 {
+			m.message = fmt.Sprintf("Updated the status of %d tasks from commits", len(msg.changed))
+			m.gitStates = gitFileStates(uniqueRoots(m.gitRoots))
+			if m.mode == searchMode {
+				m.filterTasks()
+			}
+		}
+		if msg.err != nil {
+			m.message = msg.err.Error()
+		}
+		if m.rescan {
+			m.rescan = false
+			return m, m.updateStatusesFromCommits()
+		}
+		return m, nil
+
+	// Handle the editor closing after an edit
+	case taskEditedMsg:
+		meta, _ := parseFrontmatter(msg.path)
+		paths := []string{msg.path}
+
+		// Record when the task was completed, or clear it if reopened
+		if !msg.isNew && meta.Status != msg.oldStatus {
+			if err := recordStatusChange(msg.path, msg.oldStatus, meta.Status, time.Now()); err != nil {
+				m.err = err
+			}
+		}
+
+		// New tasks are named after their template's title, so rename
+		// them once the real title is known (and others if configured)
+		if (msg.isNew || m.taskConfig.RenameOnRetitle) && meta.Title != msg.oldTitle {
+			newPath, err := renameForTitle(msg.path, m.taskConfig.GetFilenamePattern())
+			if err != nil {
+				m.err = err
+			} else if newPath != msg.path {
+				paths = append(paths, newPath)
+			}
+		}
+
+		// Commit the change if enabled, describing what happened
+		action := "edit"
+		if msg.isNew {
+			action = "create"
+		} else if meta.Status != msg.oldStatus {
+			action = "status"
+		}
+		if err := m.gitConfig.autoCommit(action, meta, filepath.Dir(msg.path), paths...); err != nil {
+			m.message = err.Error()
+		}
+		return m, func() tea.Msg { return reloadTasksMsg{} }
+
+	// Redraw the running timer once a second
+	case timerTickMsg:
+		if m.timer == nil || msg.gen != m.timerGen {
+			return m, nil
+		}
+		return m, m.tickTimer()
+
+	// Show a failed action in the footer
+	case taskErrorMsg:
+		m.message = msg.err.Error()
+		return m, nil
+
+	// Show the commits found for the viewed task, unless another task
+	// was opened meanwhile
+	case taskCommitsMsg:
+		if m.mode == taskViewMode && m.cursor < len(m.tasks) && m.tasks[m.cursor].fullPath == msg.path {
+			m.taskCommits = msg.commits
+		}
 		return m, nil
 
+	// Handle a finished git sync
+	case gitSyncMsg:
+		m.message = strings.Join(msg.results, " • ")
+		return m, func() tea.Msg { return reloadTasksMsg{} }
+
 	// Is it a key press?
 	case tea.KeyMsg:
+		// In quick-add mode, keys edit the quick-add line
+		if m.mode == quickAddMode {
+			switch m.keys.translate(m.mode, msg.String()) {
+			case "esc":
+				// Cancel without creating anything
+				m.mode = listMode
+				m.quickAddInput = ""
+				m.quickAddErr = nil
+
+			case "backspace":
+				if len(m.quickAddInput) > 0 {
+					// Remove the last character (not just the last byte)
+					runes := []rune(m.quickAddInput)
+					m.quickAddInput = string(runes[:len(runes)-1])
+					m.quickAddErr = nil
+				}
+
+			case "enter":
+				// Write the task and reload the list
+				taskPath, err := quickAdd(m.quickAddInput, m.configDirs, m.projects, m.defaultTaskDir(), m.config.GetDefaultStatus(), m.taskConfig.GetFilenamePattern())
+				if err != nil {
+					m.quickAddErr = err
+					return m, nil
+				}
+				meta, _ := parseFrontmatter(taskPath)
+				if err := m.gitConfig.autoCommit("create", meta, filepath.Dir(taskPath), taskPath); err != nil {
+					m.message = err.Error()
+				}
+				m.quickAddInput = ""
+				m.quickAddErr = nil
+				return m, func() tea.Msg { return reloadTasksMsg{} }
+
+			case "ctrl+c":
+				return m, tea.Quit
+
+			default:
+				// Add typed characters (including spaces) to the line
+				if msg.Type == tea.KeyRunes || msg.Type == tea.KeySpace {
+					m.quickAddInput += string(msg.Runes)
+					m.quickAddErr = nil
+				}
+			}
+			return m, nil
+		}
+
+		// In the directory picker, choose where the task goes
+		if m.mode == dirPickMode {
+			switch m.keys.translate(m.mode, msg.String()) {
+			case "esc":
+				if m.dirPickFor == pickForNewTask {
+					m.mode = listMode
+				} else {
+					m.mode = taskViewMode
+				}
+			case "up":
+				if m.dirCursor > 0 {
+					m.dirCursor--
+				}
+			case "down":
+				if m.dirCursor < len(m.pickerDirs())-1 {
+					m.dirCursor++
+				}
+			case "enter":
+				if m.dirPickFor == pickForBranch {
+					return m.createBranch(m.gitRepos[m.dirCursor])
+				}
+				if m.dirPickFor != pickForNewTask {
+					return m.transferTask(m.configDirs[m.dirCursor])
+				}
+
+				// Remember the choice for next time (failing to is harmless)
+				m.targetDir = m.configDirs[m.dirCursor]
+				m.lastDir = m.targetDir
+				_ = rememberLastDir(m.targetDir)
+				return m.startCreateTask()
+			case "q":
+				return m, tea.Quit
+			}
+			return m, nil
+		}
+
+		// In the template picker, choose a template for the new task
+		if m.mode == templatePickMode {
+			switch m.keys.translate(m.mode, msg.String()) {
+			case "esc":
+				m.mode = listMode
+			case "up":
+				if m.templateCursor > 0 {
+					m.templateCursor--
+				}
+			case "down":
+				if m.templateCursor < len(m.templates)-1 {
+					m.templateCursor++
+				}
+			case "enter":
+				return m.beginTemplate(m.templates[m.templateCursor])
+			case "q":
+				return m, tea.Quit
+			}
+			return m, nil
+		}
+
+		// While filling in a template, keys edit the current field
+		if m.mode == templatePromptMode {
+			switch m.keys.translate(m.mode, msg.String()) {
+			case "esc":
+				// Cancel without creating anything
+				m.mode = listMode
+				m.promptInput = ""
+
+			case "backspace":
+				if len(m.promptInput) > 0 {
+					runes := []rune(m.promptInput)
+					m.promptInput = string(runes[:len(runes)-1])
+				}
+
+			case "enter":
+				// Store the answer and move on to the next field
+				field := m.promptFields[len(m.promptAnswers)]
+				m.promptAnswers[field] = m.promptInput
+				m.promptInput = ""
+				if len(m.promptAnswers) == len(m.promptFields) {
+					m.mode = listMode
+					return m, m.createTask(m.pendingTemplate, m.promptAnswers)
+				}
+
+			case "ctrl+c":
+				return m, tea.Quit
+
+			default:
+				if msg.Type == tea.KeyRunes || msg.Type == tea.KeySpace {
+					m.promptInput += string(msg.Runes)
+				}
+			}
+			return m, nil
+		}
+
+		// In history mode, keys browse revisions and scroll the diff
+		if m.mode == historyMode {
+			if m.confirmRestore {
+				switch m.keys.translate(m.mode, msg.String()) {
+				case "y":
+					return m.restoreSelectedRevision()
+				case "n", "esc":
+					m.confirmRestore = false
+				case "ctrl+c":
+					return m, tea.Quit
+				}
+				return m, nil
+			}
+
+			switch m.keys.translate(m.mode, msg.String()) {
+			case "esc":
+				m.mode = taskViewMode
+			case "up":
+				if m.historyCursor > 0 {
+					m = m.selectRevision(m.historyCursor - 1)
+				}
+			case "down":
+				if m.historyCursor < len(m.history)-1 {
+					m = m.selectRevision(m.historyCursor + 1)
+				}
+			case "pgdown", "ctrl+d", " ":
+				m.historyScroll += 10
+			case "pgup", "ctrl+u":
+				m.historyScroll = max(m.historyScroll-10, 0)
+			case "r":
+				m.confirmRestore = true
+			case "q":
+				return m, tea.Quit
+			}
+			return m, nil
+		}
+
+		// In the status picker, arrows choose the status and typing
+		// writes the note
+		if m.mode == statusPickMode {
+			switch m.keys.translate(m.mode, msg.String()) {
+			case "esc":
+				m.mode = taskViewMode
+			case "up":
+				if m.statusCursor > 0 {
+					m.statusCursor--
+				}
+			case "down":
+				if m.statusCursor < len(statusCycle)-1 {
+					m.statusCursor++
+				}
+			case "backspace":
+				if len(m.statusNote) > 0 {
+					runes := []rune(m.statusNote)
+					m.statusNote = string(runes[:len(runes)-1])
+				}
+			case "enter":
+				m.mode = taskViewMode
+				task := m.tasks[m.cursor]
+				if statusCycle[m.statusCursor] == task.metadata.Status {
+					m.message = "Status unchanged"
+					return m, nil
+				}
+				return m.changeStatus(task, statusCycle[m.statusCursor], strings.TrimSpace(m.statusNote))
+			case "ctrl+c":
+				return m, tea.Quit
+			default:
+				if msg.Type == tea.KeyRunes || msg.Type == tea.KeySpace {
+					m.statusNote += string(msg.Runes)
+				}
+			}
+			return m, nil
+		}
+
+		// In the stats view, keys scroll
+		if m.mode == statsMode {
+			key := m.keys.translate(m.mode, msg.String())
+			if m.keys.matches("stats", msg.String()) {
+				// The key that opened the view also closes it
+				key = "esc"
+			}
+			switch key {
+			case "esc":
+				m.mode = listMode
+			case "up":
+				m.historyScroll = max(m.historyScroll-1, 0)
+			case "down":
+				m.historyScroll++
+			case "q":
+				return m, tea.Quit
+			}
+			return m, nil
+		}
+
+		// In the view picker, choose a saved view or every task
+		if m.mode == viewPickMode {
+			switch m.keys.translate(m.mode, msg.String()) {
+			case "esc":
+				m.mode = listMode
+			case "up":
+				if m.viewCursor > 0 {
+					m.viewCursor--
+				}
+			case "down":
+				if m.viewCursor < len(m.views) {
+					m.viewCursor++
+				}
+			case "enter":
+				m = m.selectView(m.viewCursor)
+			case "1", "2", "3", "4", "5", "6", "7", "8", "9", "0":
+				m = m.selectView(int(msg.String()[0] - '0'))
+			case "q":
+				return m, tea.Quit
+			}
+			return m, nil
+		}
+
+		// In the tags view, choose a tag to filter by, or rename, merge
+		// or delete it after previewing the changes
+		if m.mode == tagsMode {
+			switch {
+			case m.tagPreview != nil:
+				switch m.keys.translate(m.mode, msg.String()) {
+				case "y":
+					return m.applyTagAction()
+				case "n", "esc":
+					m.tagPreview = nil
+					m.tagAction = ""
+				case "ctrl+c":
+					return m, tea.Quit
+				}
+
+			case m.tagAction != "":
+				m.message = ""
+				switch m.keys.translateTyping(msg.String()) {
+				case "esc":
+					m.tagAction = ""
+				case "backspace":
+					if len(m.tagInput) > 0 {
+						runes := []rune(m.tagInput)
+						m.tagInput = string(runes[:len(runes)-1])
+					}
+				case "enter":
+					m = m.previewTagAction()
+				case "ctrl+c":
+					return m, tea.Quit
+				default:
+					if msg.Type == tea.KeyRunes {
+						m.tagInput += string(msg.Runes)
+					}
+				}
+
+			default:
+				m.message = ""
+				key := m.keys.translate(m.mode, msg.String())
+				if m.keys.matches("tags", msg.String()) {
+					key = "esc"
+				}
+				switch key {
+				case "esc":
+					m.mode = listMode
+				case "up":
+					if m.tagCursor > 0 {
+						m.tagCursor--
+					}
+				case "down":
+					if m.tagCursor < len(m.tagRows())-1 {
+						m.tagCursor++
+					}
+				case "tab":
+					// Collapse or expand the nested tags
+					m = m.toggleTag()
+				case "enter":
+					if tag, ok := m.selectedTag(); ok {
+						// List only the tasks with this tag or one nested below it
+						m.tagFilter = tag.name
+						m.mode = listMode
+						m.cursor = 0
+						m.collapsed = nil
+					}
+				case "r", "m":
+					if _, ok := m.selectedTag(); ok {
+						m.tagAction = map[string]string{"r": "rename", "m": "merge"}[msg.String()]
+						m.tagInput = ""
+					}
+				case "d":
+					if _, ok := m.selectedTag(); ok {
+						m.tagAction = "delete"
+						m = m.previewTagAction()
+					}
+				case "q":
+					return m, tea.Quit
+				}
+			}
+			return m, nil
+		}
+
+		// In the projects overview, choose a project to list its tasks
+		if m.mode == projectsMode {
+			key := m.keys.translate(m.mode, msg.String())
+			if m.keys.matches("projects", msg.String()) {
+				// The key that opened the view also closes it
+				key = "esc"
+			}
+			switch key {
+			case "esc":
+				m.mode = listMode
+			case "up":
+				if m.projectCursor > 0 {
+					m.projectCursor--
+				}
+			case "down":
+				if m.projectCursor < len(m.projects)-1 {
+					m.projectCursor++
+				}
+			case "enter":
+				m.projectFilter = m.projects[m.projectCursor].GetCode()
+				m.mode = listMode
+				m.cursor = 0
+				m.collapsed = nil
+			case "q":
+				return m, tea.Quit
+			}
+			return m, nil
+		}
+
+		// In the time report, keys change the grouping and period
+		if m.mode == timeReportMode {
+			key := m.keys.translate(m.mode, msg.String())
+			if m.keys.matches("time_report", msg.String()) {
+				// The key that opened the view also closes it
+				key = "esc"
+			}
+			switch key {
+			case "esc":
+				m.mode = listMode
+			case "tab", "g":
+				m.reportGroup = (m.reportGroup + 1) % len(timeReportGroups)
+			case "s":
+				m.reportSince = (m.reportSince + 1) % len(timeReportPeriods)
+			case "q":
+				return m, tea.Quit
+			}
+			return m, nil
+		}
+
 		// In search mode, handle input differently
 		if m.mode == searchMode {
-			switch msg.String() {
+			switch m.keys.translate(m.mode, msg.String()) {
 			case "esc":
 				// Exit search mode
 				m.mode = listMode
@@ -510,28 +1719,18 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 
 			case "enter":
-				if len(m.visibleTasks()) > 0 {
-					// View selected task from search results
-					visibleTasks := m.visibleTasks()
-					if m.cursor < len(visibleTasks) {
-						content, err := os.ReadFile(visibleTasks[m.cursor].fullPath)
-						if err != nil {
-							m.err = fmt.Errorf("failed to read task: %w", err)
-						} else {
-							m.mode = taskViewMode
-							m.taskContent = string(content)
-						}
-					}
+				// View selected task from search results
+				if task, ok := m.selectedTask(); ok {
+					return m.viewTask(task)
 				}
 
-			case "up", "k":
+			case "up":
 				if m.cursor > 0 {
 					m.cursor--
 				}
 
-			case "down", "j":
-				visibleTasks := m.visibleTasks()
-				if m.cursor < len(visibleTasks)-1 {
+			case "down":
+				if m.cursor < len(m.listRows())-1 {
 					m.cursor++
 				}
 
@@ -549,11 +1748,17 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 
+		// Status messages only last until the next key press
+		m.message = ""
+
+		// Keys in the list and task views go through the keymap
+		key := m.keys.translate(m.mode, msg.String())
+
 		// Handle keys for other modes
-		switch msg.String() {
+		switch key {
 
 		// Quit keys
-		case "q", "ctrl+c":
+		case "q":
 			return m, tea.Quit
 
 		// Navigation and actions depend on current mode
@@ -561,6 +1766,12 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if m.mode == taskViewMode {
 				m.mode = listMode
 				m.taskContent = ""
+				m.cursor = m.listCursor
+			} else if m.mode == listMode && (m.tagFilter != "" || m.projectFilter != "") {
+				// Show every tag and project again
+				m.tagFilter = ""
+				m.projectFilter = ""
+				m.cursor = 0
 			} else if m.mode == confirmDeleteMode {
 				// Cancel deletion
 				m.mode = taskViewMode
@@ -573,20 +1784,18 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if m.mode == listMode {
 				// Show help screen
 				m.mode = helpMode
+			} else if m.mode == taskViewMode && key == "h" && len(m.tasks) > 0 {
+				// Show the task's git history
+				return m.openHistory()
 			}
 
 		case "enter":
-			if m.mode == listMode && len(m.tasks) > 0 {
-				// Read the task file content
-				visibleTasks := m.visibleTasks()
-				if m.cursor < len(visibleTasks) {
-					content, err := os.ReadFile(visibleTasks[m.cursor].fullPath)
-					if err != nil {
-						m.err = fmt.Errorf("failed to read task: %w", err)
-					} else {
-						m.mode = taskViewMode
-						m.taskContent = string(content)
-					}
+			if m.mode == listMode {
+				// Read the task file content, or expand a collapsed group
+				if task, ok := m.selectedTask(); ok {
+					return m.viewTask(task)
+				} else {
+					m = m.toggleGroup()
 				}
 			}
 
@@ -598,13 +1807,31 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		case "n":
 			if m.mode == listMode {
-				// Create a new task
-				return m, m.createTask()
+				// Create a new task (via the directory and template pickers)
+				return m.chooseTaskDir()
 			} else if m.mode == confirmDeleteMode {
 				// Cancel deletion
 				m.mode = taskViewMode
 			}
 
+		case "m":
+			if m.mode == taskViewMode && len(m.tasks) > 0 {
+				// Move the task to another directory
+				return m.chooseTransferDir(pickForMove)
+			}
+
+		case "c":
+			if m.mode == taskViewMode && len(m.tasks) > 0 {
+				// Duplicate the task into another directory
+				return m.chooseTransferDir(pickForCopy)
+			}
+
+		case "b":
+			if m.mode == taskViewMode && len(m.tasks) > 0 {
+				// Create and check out a branch for the task
+				return m.chooseBranchRepo()
+			}
+
 		case "d":
 			if m.mode == taskViewMode && len(m.tasks) > 0 {
 				// Show delete confirmation
@@ -617,6 +1844,141 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return m.deleteTask(), nil
 			}
 
+		case "t":
+			if m.mode == listMode {
+				// Start or stop timing the selected task
+				if task, ok := m.selectedTask(); ok {
+					return m.toggleTimer(task)
+				}
+			} else if m.mode == taskViewMode && len(m.tasks) > 0 {
+				return m.toggleTimer(m.tasks[m.cursor])
+			}
+
+		case "s", "x":
+			// s steps through todo, in-progress and done; x toggles done
+			var task taskFile
+			if m.mode == listMode {
+				selected, ok := m.selectedTask()
+				if !ok {
+					break
+				}
+				task = selected
+			} else if m.mode == taskViewMode && len(m.tasks) > 0 {
+				task = m.tasks[m.cursor]
+			} else {
+				break
+			}
+
+			status := nextStatus(task.metadata.Status)
+			if key == "x" {
+				status = "done"
+				if isDoneStatus(task.metadata.Status) {
+					status = m.config.GetDefaultStatus()
+				}
+			}
+			return m.changeStatus(task, status, "")
+
+		case "u":
+			if m.mode == taskViewMode && len(m.tasks) > 0 {
+				// Change the status with a note, starting on the next one
+				next := nextStatus(m.tasks[m.cursor].metadata.Status)
+				m.statusCursor = 0
+				for i, status := range statusCycle {
+					if status == next {
+						m.statusCursor = i
+					}
+				}
+				m.statusNote = ""
+				m.mode = statusPickMode
+			}
+
+		case "C":
+			if m.mode == listMode {
+				// Show tasks completed today, then this week, then all
+				m.completedOnly = (m.completedOnly + 1) % len(completedFilters)
+				m.cursor = 0
+			}
+
+		case "R":
+			if m.mode == listMode {
+				// Show workload statistics
+				m.mode = statsMode
+				m.historyScroll = 0
+			}
+
+		case "T":
+			if m.mode == listMode {
+				// Show time spent per task, tag, directory or day
+				m.mode = timeReportMode
+			}
+
+		case "a":
+			if m.mode == listMode {
+				// Open the quick-add bar
+				m.mode = quickAddMode
+				m.quickAddInput = ""
+				m.quickAddErr = nil
+			}
+
+		case "S":
+			if m.mode == listMode && len(m.gitRoots) > 0 {
+				// Pull and push every git-backed directory in the background
+				roots := uniqueRoots(m.gitRoots)
+				m.message = "Syncing..."
+				return m, func() tea.Msg { return gitSyncMsg{results: gitSync(roots)} }
+			}
+
+		case "v":
+			if m.mode == listMode && len(m.views) > 0 {
+				// Choose a saved view
+				m.mode = viewPickMode
+				m.viewCursor = m.activeView
+			}
+
+		case "1", "2", "3", "4", "5", "6", "7", "8", "9", "0":
+			if m.mode == listMode && len(m.views) > 0 {
+				// Switch straight to a saved view; 0 shows every task
+				m = m.selectView(int(key[0] - '0'))
+			}
+
+		case "P":
+			if m.mode == listMode && len(m.projects) > 0 {
+				// Show every project with its task counts
+				m.mode = projectsMode
+				for i, project := range m.projects {
+					if project.GetCode() == m.projectFilter {
+						m.projectCursor = i
+					}
+				}
+			}
+
+		case "#":
+			if m.mode == listMode {
+				// List every tag
+				m = m.openTags()
+			}
+
+		case "g":
+			if m.mode == listMode {
+				// Group by status, priority, directory, tag, due date or nothing
+				m = m.cycleGrouping()
+				if m.groupBy != "" {
+					m.message = "Grouped by " + m.groupBy
+				}
+			}
+
+		case "tab":
+			if m.mode == listMode {
+				// Collapse or expand the group under the cursor
+				m = m.toggleGroup()
+			}
+
+		case "Z":
+			if m.mode == listMode {
+				// Collapse or expand every group
+				m = m.toggleAllGroups()
+			}
+
 		case "/":
 			if m.mode == listMode {
 				// Enter search mode
@@ -626,126 +1988,410 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 
 		// Move up (only in list mode now)
-		case "up", "k":
+		case "up":
 			if m.mode == listMode && m.cursor > 0 {
 				m.cursor--
 			}
 
-		// Move down (only in list mode now)
-		case "down", "j":
-			visibleTasks := m.visibleTasks()
-			if m.mode == listMode && m.cursor < len(visibleTasks)-1 {
-				m.cursor++
-			}
+		// Move down (only in list mode now)
+		case "down":
+			if m.mode == listMode && m.cursor < len(m.listRows())-1 {
+				m.cursor++
+			}
+
+		default:
+			// No special handling needed for other keys
+		}
+	}
+
+	// Return the updated model (and no command)
+	return m, nil
+}
+
+// View renders the UI based on the current model state
+// This function is called after every Update
+func (m model) View() string {
+	// If in help mode, show help screen
+	if m.mode == helpMode {
+		return m.renderHelpView()
+	}
+
+	// If creating a task, show the pickers or the prompt
+	if m.mode == dirPickMode {
+		return m.renderDirPicker()
+	}
+	if m.mode == templatePickMode {
+		return m.renderTemplatePicker()
+	}
+	if m.mode == templatePromptMode {
+		return m.renderTemplatePrompt()
+	}
+
+	// If in confirmation mode, show confirmation dialog
+	if m.mode == confirmDeleteMode {
+		return m.renderDeleteConfirmation()
+	}
+
+	// If changing a status with a note, show the picker
+	if m.mode == statusPickMode {
+		return m.renderStatusPicker()
+	}
+
+	// If showing projects, show the overview
+	if m.mode == projectsMode {
+		return m.renderProjectsView()
+	}
+
+	// If managing tags, show the tag list
+	if m.mode == tagsMode {
+		return m.renderTagsView()
+	}
+
+	// If choosing a saved view, show the picker
+	if m.mode == viewPickMode {
+		return m.renderViewPicker()
+	}
+
+	// If showing statistics, show the overview
+	if m.mode == statsMode {
+		return m.renderStatsView()
+	}
+
+	// If showing the time report, show the totals
+	if m.mode == timeReportMode {
+		return m.renderTimeReport()
+	}
+
+	// If browsing a task's history, show revisions and their diff
+	if m.mode == historyMode {
+		return m.renderHistoryView()
+	}
+
+	// If viewing a task, show task content
+	if m.mode == taskViewMode {
+		return m.renderTaskView()
+	}
+
+	// Otherwise, show the task list
+	return m.renderListView()
+}
+
+// renderHelpView displays keyboard shortcuts and help
+func (m model) renderHelpView() string {
+	var sections []string
+
+	// Add top padding
+	sections = append(sections, "")
+
+	title := titleStyle.Render("Keyboard Shortcuts")
+	sections = append(sections, title)
+
+	var content string
+	content += headerStyle.Render("LIST VIEW") + "\n"
+	content += m.keys.helpLines("list")
+	content += helpLine("1-9, 0", "Switch to a saved view, or back to all tasks") + "\n"
+
+	content += headerStyle.Render("SEARCH MODE") + "\n"
+	content += "  " + helpKeyStyle.Render("[type]") + "       " + helpDescStyle.Render("Filter tasks (searches name, title, status, tags)") + "\n"
+	content += "  " + helpKeyStyle.Render("status:todo") + "  " + helpDescStyle.Render("Filter terms: status: priority: tag: dir: due: is: id:, - negates") + "\n"
+	content += "  " + helpKeyStyle.Render("↑/k, ↓/j") + "     " + helpDescStyle.Render("Navigate filtered results") + "\n"
+	content += "  " + helpKeyStyle.Render("enter") + "        " + helpDescStyle.Render("View selected task") + "\n"
+	content += "  " + helpKeyStyle.Render("backspace") + "    " + helpDescStyle.Render("Delete last character") + "\n"
+	content += helpLine(m.keys.typingLabel("back"), "Exit search mode") + "\n"
+
+	content += headerStyle.Render("NEW TASK") + "\n"
+	content += helpLine(m.keys.label("up")+", "+m.keys.label("down"), "Choose a directory or template")
+	content += "  " + helpKeyStyle.Render("enter") + "        " + helpDescStyle.Render("Use selection / confirm field") + "\n"
+	content += helpLine(m.keys.label("back"), "Cancel") + "\n"
+
+	content += headerStyle.Render("QUICK ADD") + "\n"
+	content += "  " + helpKeyStyle.Render("[type]") + "       " + helpDescStyle.Render("Title plus !high #tag due:fri @dir") + "\n"
+	content += "  " + helpKeyStyle.Render("enter") + "        " + helpDescStyle.Render("Create the task") + "\n"
+	content += helpLine(m.keys.typingLabel("back"), "Cancel") + "\n"
+
+	content += headerStyle.Render("TASK VIEW") + "\n"
+	content += m.keys.helpLines("task") + "\n"
+
+	content += headerStyle.Render("DELETE CONFIRMATION") + "\n"
+	content += "  " + helpKeyStyle.Render("y") + "            " + helpDescStyle.Render("Confirm deletion") + "\n"
+	content += helpLine("n/"+m.keys.label("back"), "Cancel deletion") + "\n"
+
+	content += headerStyle.Render("CONFIGURATION") + "\n"
+	content += "  " + helpDescStyle.Render("Config: ~/.config/taskmanager/config.toml") + "\n"
+	content += "  " + helpDescStyle.Render("Customize directories, status indicators, and more")
+
+	sections = append(sections, mainBoxStyle.
+		MarginLeft(1).
+		MarginRight(1).
+		Render(content))
+	sections = append(sections, footerStyle.Render(m.keys.hint("back", "close help")+" • "+m.keys.hint("quit", "quit")))
+
+	return lipgloss.JoinVertical(lipgloss.Left, sections...)
+}
+
+// renderDeleteConfirmation shows a confirmation dialog for deleting a task
+func (m model) renderDeleteConfirmation() string {
+	var sections []string
+
+	// Add top padding
+	sections = append(sections, "")
+
+	title := titleStyle.Render("Delete Task")
+	sections = append(sections, title)
+
+	var content string
+	if m.cursor < len(m.tasks) {
+		content += "Are you sure you want to delete this task?\n\n"
+		content += fmt.Sprintf("File: %s\n", m.tasks[m.cursor].name)
+		if m.tasks[m.cursor].metadata.Title != "" {
+			content += fmt.Sprintf("Title: %s\n", m.tasks[m.cursor].metadata.Title)
+		}
+		content += fmt.Sprintf("Path: %s\n", m.tasks[m.cursor].fullPath)
+	}
+
+	content += "\n" + errorStyle.Render("This action cannot be undone!")
+
+	sections = append(sections, mainBoxStyle.
+		MarginLeft(1).
+		MarginRight(1).
+		Render(content))
+	sections = append(sections, footerStyle.Render("y: yes, delete • "+m.keys.label("back")+"/n: cancel • "+m.keys.hint("quit", "quit")))
+
+	return lipgloss.JoinVertical(lipgloss.Left, sections...)
+}
+
+// renderDirPicker lists the configured directories a task can go in
+func (m model) renderDirPicker() string {
+	var sections []string
+
+	// Add top padding
+	sections = append(sections, "")
+
+	var content string
+	switch m.dirPickFor {
+	case pickForMove:
+		sections = append(sections, titleStyle.Render("Move Task"))
+		content += "Move the task to:\n\n"
+	case pickForCopy:
+		sections = append(sections, titleStyle.Render("Copy Task"))
+		content += "Copy the task to:\n\n"
+	case pickForBranch:
+		sections = append(sections, titleStyle.Render("Create Branch"))
+		content += "Create the task's branch in:\n\n"
+	default:
+		sections = append(sections, titleStyle.Render("New Task"))
+		content += "Create the task in:\n\n"
+	}
+	for i, dir := range m.pickerDirs() {
+		cursor := " "
+		if i == m.dirCursor {
+			cursor = cursorStyle.Render(">")
+		}
+		line := fmt.Sprintf("%s %s", cursor, dir)
+		if project, ok := projectForDir(m.projects, dir); ok && m.dirPickFor != pickForBranch {
+			line += "  " + project.style().Render(project.GetCode()) + " " + dimStyle.Render(project.Name)
+		}
+		content += line + "\n"
+	}
+
+	sections = append(sections, mainBoxStyle.
+		MarginLeft(1).
+		MarginRight(1).
+		Render(strings.TrimRight(content, "\n")))
+	footer := m.keys.moveHint("choose") + " • enter: use directory • " + m.keys.hint("back", "cancel")
+	if m.dirPickFor == pickForBranch {
+		footer = m.keys.moveHint("choose") + " • enter: create branch • " + m.keys.hint("back", "cancel")
+	}
+	sections = append(sections, footerStyle.Render(footer))
+
+	return lipgloss.JoinVertical(lipgloss.Left, sections...)
+}
+
+// renderTemplatePicker lists the templates available for a new task
+func (m model) renderTemplatePicker() string {
+	var sections []string
+
+	// Add top padding
+	sections = append(sections, "")
+
+	sections = append(sections, titleStyle.Render("New Task"))
+
+	var content string
+	content += dimStyle.Render("In "+m.targetDir) + "\n\n"
+	content += "Choose a template:\n\n"
+	for i, t := range m.templates {
+		cursor := " "
+		if i == m.templateCursor {
+			cursor = cursorStyle.Render(">")
+		}
+		content += fmt.Sprintf("%s %-20s %s\n", cursor, t.name, dimStyle.Render(t.source))
+	}
+
+	sections = append(sections, mainBoxStyle.
+		MarginLeft(1).
+		MarginRight(1).
+		Render(strings.TrimRight(content, "\n")))
+	sections = append(sections, footerStyle.Render(m.keys.moveHint("choose")+" • enter: use template • "+m.keys.hint("back", "cancel")))
+
+	return lipgloss.JoinVertical(lipgloss.Left, sections...)
+}
+
+// renderViewPicker lists the saved views, with every task first
+func (m model) renderViewPicker() string {
+	var sections []string
+
+	// Add top padding
+	sections = append(sections, "")
+
+	sections = append(sections, titleStyle.Render("Views"))
 
-		default:
-			// No special handling needed for other keys
+	var content string
+	for i := 0; i <= len(m.views); i++ {
+		cursor := " "
+		if i == m.viewCursor {
+			cursor = cursorStyle.Render(">")
+		}
+		name, query := "All tasks", ""
+		if i > 0 {
+			name, query = m.views[i-1].Name, m.views[i-1].Query
 		}
+		key := " "
+		if i <= 9 {
+			key = fmt.Sprint(i)
+		}
+		content += fmt.Sprintf("%s %s %-20s %s\n", cursor, helpKeyStyle.Render(key), name, dimStyle.Render(query))
 	}
 
-	// Return the updated model (and no command)
-	return m, nil
+	sections = append(sections, mainBoxStyle.
+		MarginLeft(1).
+		MarginRight(1).
+		Render(strings.TrimRight(content, "\n")))
+	sections = append(sections, footerStyle.Render(m.keys.moveHint("choose")+" • enter or 0-9: show view • "+m.keys.hint("back", "cancel")))
+
+	return lipgloss.JoinVertical(lipgloss.Left, sections...)
 }
 
-// View renders the UI based on the current model state
-// This function is called after every Update
-func (m model) View() string {
-	// If in help mode, show help screen
-	if m.mode == helpMode {
-		return m.renderHelpView()
-	}
+// renderProjectsView lists the configured projects with their task counts
+func (m model) renderProjectsView() string {
+	var sections []string
 
-	// If in confirmation mode, show confirmation dialog
-	if m.mode == confirmDeleteMode {
-		return m.renderDeleteConfirmation()
-	}
+	// Add top padding
+	sections = append(sections, "")
 
-	// If viewing a task, show task content
-	if m.mode == taskViewMode {
-		return m.renderTaskView()
+	sections = append(sections, titleStyle.Render("Projects"))
+
+	now := time.Now()
+	var content string
+	for i, summary := range summarizeProjects(m.tasks, m.projects, now) {
+		cursor := " "
+		if i == m.projectCursor {
+			cursor = cursorStyle.Render(">")
+		}
+		project := summary.project
+		code := project.style().Render(fmt.Sprintf("%-6s", project.GetCode()))
+		content += fmt.Sprintf("%s %s %-24s %s\n", cursor, code, project.Name, summary.formatCounts())
+		content += "         " + dimStyle.Render(project.Path) + "\n"
 	}
 
-	// Otherwise, show the task list
-	return m.renderListView()
+	footer := m.keys.moveHint("choose") + " • enter: list tasks • " + m.keys.hint("back", "back") + " • " + m.keys.hint("quit", "quit")
+	sections = append(sections, mainBoxStyle.
+		MarginLeft(1).
+		MarginRight(1).
+		Render(strings.TrimRight(content, "\n")))
+	sections = append(sections, footerStyle.Render(footer))
+
+	return lipgloss.JoinVertical(lipgloss.Left, sections...)
 }
 
-// renderHelpView displays keyboard shortcuts and help
-func (m model) renderHelpView() string {
+// renderTagsView lists every tag with its task count. Below the list it
+// asks for a new name, or previews the changes waiting to be confirmed.
+func (m model) renderTagsView() string {
 	var sections []string
 
 	// Add top padding
 	sections = append(sections, "")
 
-	title := titleStyle.Render("Keyboard Shortcuts")
-	sections = append(sections, title)
+	sections = append(sections, titleStyle.Render(fmt.Sprintf("Tags (%d)", len(m.tagCounts))))
 
 	var content string
-	content += headerStyle.Render("LIST VIEW") + "\n"
-	content += "  " + helpKeyStyle.Render("↑/k") + "          " + helpDescStyle.Render("Move cursor up") + "\n"
-	content += "  " + helpKeyStyle.Render("↓/j") + "          " + helpDescStyle.Render("Move cursor down") + "\n"
-	content += "  " + helpKeyStyle.Render("enter") + "        " + helpDescStyle.Render("View selected task") + "\n"
-	content += "  " + helpKeyStyle.Render("/") + "            " + helpDescStyle.Render("Search/filter tasks") + "\n"
-	content += "  " + helpKeyStyle.Render("n") + "            " + helpDescStyle.Render("Create new task") + "\n"
-	content += "  " + helpKeyStyle.Render("?/h") + "          " + helpDescStyle.Render("Show this help screen") + "\n"
-	content += "  " + helpKeyStyle.Render("q") + "            " + helpDescStyle.Render("Quit application") + "\n\n"
-
-	content += headerStyle.Render("SEARCH MODE") + "\n"
-	content += "  " + helpKeyStyle.Render("[type]") + "       " + helpDescStyle.Render("Filter tasks (searches name, title, status, tags)") + "\n"
-	content += "  " + helpKeyStyle.Render("↑/k, ↓/j") + "     " + helpDescStyle.Render("Navigate filtered results") + "\n"
-	content += "  " + helpKeyStyle.Render("enter") + "        " + helpDescStyle.Render("View selected task") + "\n"
-	content += "  " + helpKeyStyle.Render("backspace") + "    " + helpDescStyle.Render("Delete last character") + "\n"
-	content += "  " + helpKeyStyle.Render("esc") + "          " + helpDescStyle.Render("Exit search mode") + "\n\n"
-
-	content += headerStyle.Render("TASK VIEW") + "\n"
-	content += "  " + helpKeyStyle.Render("e") + "            " + helpDescStyle.Render("Edit task in $EDITOR") + "\n"
-	content += "  " + helpKeyStyle.Render("d") + "            " + helpDescStyle.Render("Delete task (with confirmation)") + "\n"
-	content += "  " + helpKeyStyle.Render("esc") + "          " + helpDescStyle.Render("Return to list") + "\n"
-	content += "  " + helpKeyStyle.Render("q") + "            " + helpDescStyle.Render("Quit application") + "\n\n"
+	if len(m.tagCounts) == 0 {
+		content = dimStyle.Render("No tasks have tags yet.")
+	}
+	selected, _ := m.selectedTag()
+	for i, tag := range m.tagRows() {
+		cursor := " "
+		if i == m.tagCursor {
+			cursor = cursorStyle.Render(">")
+		}
 
-	content += headerStyle.Render("DELETE CONFIRMATION") + "\n"
-	content += "  " + helpKeyStyle.Render("y") + "            " + helpDescStyle.Render("Confirm deletion") + "\n"
-	content += "  " + helpKeyStyle.Render("n/esc") + "        " + helpDescStyle.Render("Cancel deletion") + "\n\n"
+		// Nested tags are indented under their parent, showing their last level
+		fold := "  "
+		if tag.children && m.tagCollapsed[strings.ToLower(tag.name)] {
+			fold = "▸ "
+		} else if tag.children {
+			fold = "▾ "
+		}
+		label := tag.name[strings.LastIndex(tag.name, "/")+1:]
+		label = strings.Repeat("  ", tag.depth) + fold + m.config.tagStyle(tag.name).Render("#"+label)
+		content += fmt.Sprintf("%s %s %s\n", cursor, lipgloss.NewStyle().Width(36).Render(label), dimStyle.Render(fmt.Sprint(tag.count)))
+	}
 
-	content += headerStyle.Render("CONFIGURATION") + "\n"
-	content += "  " + helpDescStyle.Render("Config: ~/.config/taskmanager/config.toml") + "\n"
-	content += "  " + helpDescStyle.Render("Customize directories, status indicators, and more")
+	footer := m.keys.moveHint("choose") + " • tab: fold • enter: filter list • r: rename • m: merge • d: delete • " + m.keys.hint("back", "back")
+	if m.tagPreview != nil {
+		from := selected.name
+		to := strings.TrimPrefix(strings.TrimSpace(m.tagInput), "#")
+		summary := describeTagChange(m.tagAction, []string{from}, to, len(m.tagPreview))
+		content += "\n" + headerStyle.Render(strings.ToUpper(summary[:1])+summary[1:]+"?") + "\n"
+		for _, change := range m.tagPreview {
+			content += fmt.Sprintf("  %s: %s → %s\n", displayTitle(change.task), dimStyle.Render(formatTags(change.before)), formatTags(change.after))
+		}
+		footer = "y: apply • n/" + m.keys.label("back") + ": cancel"
+	} else if m.tagAction != "" {
+		prompt := fmt.Sprintf("Rename #%s to: #", selected.name)
+		if m.tagAction == "merge" {
+			prompt = fmt.Sprintf("Merge #%s into: #", selected.name)
+		}
+		content += "\n" + searchPrefixStyle.Render(prompt) + m.tagInput + cursorStyle.Render("_")
+		footer = joinHints("enter: preview changes", m.keys.typingHint("back", "cancel"))
+	}
+	if m.message != "" {
+		footer = m.message + " • " + footer
+	}
 
 	sections = append(sections, mainBoxStyle.
 		MarginLeft(1).
 		MarginRight(1).
-		Render(content))
-	sections = append(sections, footerStyle.Render("esc: close help • q: quit"))
+		Render(strings.TrimRight(content, "\n")))
+	sections = append(sections, footerStyle.Render(footer))
 
 	return lipgloss.JoinVertical(lipgloss.Left, sections...)
 }
 
-// renderDeleteConfirmation shows a confirmation dialog for deleting a task
-func (m model) renderDeleteConfirmation() string {
+// renderTemplatePrompt asks for the next field of the chosen template
+func (m model) renderTemplatePrompt() string {
 	var sections []string
 
 	// Add top padding
 	sections = append(sections, "")
 
-	title := titleStyle.Render("Delete Task")
-	sections = append(sections, title)
+	sections = append(sections, titleStyle.Render("New Task: "+m.pendingTemplate.name))
 
 	var content string
-	if m.cursor < len(m.tasks) {
-		content += "Are you sure you want to delete this task?\n\n"
-		content += fmt.Sprintf("File: %s\n", m.tasks[m.cursor].name)
-		if m.tasks[m.cursor].metadata.Title != "" {
-			content += fmt.Sprintf("Title: %s\n", m.tasks[m.cursor].metadata.Title)
+	for _, field := range m.promptFields {
+		answer, answered := m.promptAnswers[field]
+		if answered {
+			content += dimStyle.Render(fmt.Sprintf("%s: %s", field, answer)) + "\n"
+			continue
 		}
-		content += fmt.Sprintf("Path: %s\n", m.tasks[m.cursor].fullPath)
+		content += searchPrefixStyle.Render(field+": ") + m.promptInput + cursorStyle.Render("_")
+		break
 	}
 
-	content += "\n" + errorStyle.Render("This action cannot be undone!")
-
 	sections = append(sections, mainBoxStyle.
 		MarginLeft(1).
 		MarginRight(1).
 		Render(content))
-	sections = append(sections, footerStyle.Render("y: yes, delete • esc/n: cancel • q: quit"))
+	sections = append(sections, footerStyle.Render(joinHints(fmt.Sprintf("Field %d of %d", len(m.promptAnswers)+1, len(m.promptFields)), "enter: next", m.keys.typingHint("back", "cancel"))))
 
 	return lipgloss.JoinVertical(lipgloss.Left, sections...)
 }
@@ -772,7 +2418,16 @@ func (m model) renderTaskView() string {
 	var content string
 	if m.cursor < len(m.tasks) {
 		content += dimStyle.Render(fmt.Sprintf("File: %s", m.tasks[m.cursor].name)) + "\n"
-		content += dimStyle.Render(fmt.Sprintf("Path: %s", m.tasks[m.cursor].fullPath)) + "\n\n"
+		content += dimStyle.Render(fmt.Sprintf("Path: %s", m.tasks[m.cursor].fullPath)) + "\n"
+		if meta := m.tasks[m.cursor].metadata; !meta.Completed.IsZero() {
+			completed := fmt.Sprintf("Completed: %s", meta.Completed.Local().Format("2006-01-02 15:04"))
+			if d, ok := meta.cycleTime(); ok {
+				completed += fmt.Sprintf(" (cycle time %s)", formatCycleTime(d))
+			}
+			content += dimStyle.Render(completed) + "\n"
+		}
+		content += m.renderTaskCommits()
+		content += m.renderStatusTimeline() + "\n"
 	}
 
 	content += m.taskContent
@@ -781,11 +2436,275 @@ func (m model) renderTaskView() string {
 		MarginLeft(1).
 		MarginRight(1).
 		Render(content))
-	sections = append(sections, footerStyle.Render("esc: back • e: edit • d: delete • q: quit"))
+	k := m.keys
+	footer := k.hint("back", "back") + " • " + k.hint("edit", "edit") + " • " + k.hint("move", "move") + " • " + k.hint("copy", "copy") + " • " + k.hint("delete", "delete")
+	if len(m.gitRepos) > 0 {
+		footer += " • " + k.hint("branch", "branch")
+	}
+	if len(m.gitRoots) > 0 {
+		footer += " • " + k.hint("history", "history")
+	}
+	footer += " • " + k.hint("status", "status") + " • " + k.hint("status_note", "status with note") + " • " + k.hint("done", "done") + " • " + k.hint("timer", "timer") + " • " + k.hint("quit", "quit")
+	if m.message != "" {
+		footer = m.message + " • " + footer
+	}
+	if m.timer != nil {
+		footer = m.timerIndicator() + " • " + footer
+	}
+	sections = append(sections, footerStyle.Render(footer))
+
+	return lipgloss.JoinVertical(lipgloss.Left, sections...)
+}
+
+// renderStatsView shows counts, throughput and charts for every task
+func (m model) renderStatsView() string {
+	var sections []string
+
+	// Add top padding
+	sections = append(sections, "")
+
+	sections = append(sections, titleStyle.Render("Statistics"))
+
+	stats := collectStats(m.tasks, m.config.GetDefaultStatus(), time.Now())
+	lines := strings.Split(stats.formatText(), "\n")
+
+	// Fit the text into the screen: padding, title and margin, box frame, footer
+	height := max(m.height-1-2-4-1, 3)
+	scroll := min(m.historyScroll, max(len(lines)-height, 0))
+	lines = lines[scroll:min(scroll+height, len(lines))]
+
+	// Highlight the section headings
+	for i, line := range lines {
+		if line != "" && !strings.HasPrefix(line, " ") && !strings.Contains(line, ":") {
+			lines[i] = headerStyle.Render(line)
+		}
+	}
+
+	sections = append(sections, mainBoxStyle.
+		MarginLeft(1).
+		MarginRight(1).
+		Render(strings.Join(lines, "\n")))
+	sections = append(sections, footerStyle.Render(m.keys.moveHint("scroll")+" • taskmanager report --format markdown|json to export • "+m.keys.hint("back", "back")+" • "+m.keys.hint("quit", "quit")))
+
+	return lipgloss.JoinVertical(lipgloss.Left, sections...)
+}
+
+// renderTimeReport shows the time logged on tasks, grouped by task, tag,
+// directory or day
+func (m model) renderTimeReport() string {
+	var sections []string
+
+	// Add top padding
+	sections = append(sections, "")
+
+	group := timeReportGroups[m.reportGroup]
+	period := timeReportPeriods[m.reportSince]
+	since, _ := parseSince(period, time.Now())
+	if period == "" {
+		period = "all time"
+	}
+	sections = append(sections, titleStyle.Render(fmt.Sprintf("Time by %s (%s)", group, period)))
+
+	var content string
+	rows, err := timeReport(m.tasks, group, since)
+	if err != nil {
+		content = errorStyle.Render(err.Error())
+	} else if len(rows) == 0 {
+		content = dimStyle.Render("No time logged yet. Press t on a task to start a timer.")
+	} else {
+		var total time.Duration
+		for _, row := range rows {
+			content += fmt.Sprintf("%8s  %s\n", formatDuration(row.total), row.label)
+			total += row.total
+		}
+		if group != "tag" {
+			// Tags overlap, so their total would count some time twice
+			content += headerStyle.Render(fmt.Sprintf("%8s", formatDuration(total))) + "  total"
+		}
+	}
+
+	sections = append(sections, mainBoxStyle.
+		MarginLeft(1).
+		MarginRight(1).
+		Render(strings.TrimRight(content, "\n")))
+
+	footer := "tab: task/tag/dir/day • s: all time/today/week/month • " + m.keys.hint("back", "back") + " • " + m.keys.hint("quit", "quit")
+	if m.timer != nil {
+		footer = m.timerIndicator() + " • " + footer
+	}
+	sections = append(sections, footerStyle.Render(footer))
+
+	return lipgloss.JoinVertical(lipgloss.Left, sections...)
+}
+
+// renderStatusPicker asks for a task's new status and an optional note
+func (m model) renderStatusPicker() string {
+	var sections []string
+
+	// Add top padding
+	sections = append(sections, "")
+
+	task := m.tasks[m.cursor]
+	title := task.metadata.Title
+	if title == "" {
+		title = task.name
+	}
+	sections = append(sections, titleStyle.Render("Change Status: "+title))
+
+	var content string
+	for i, status := range statusCycle {
+		cursor := " "
+		if i == m.statusCursor {
+			cursor = cursorStyle.Render(">")
+		}
+		line := fmt.Sprintf("%s %s", cursor, status)
+		if status == task.metadata.Status {
+			line += dimStyle.Render(" (current)")
+		}
+		content += line + "\n"
+	}
+	content += "\n" + searchPrefixStyle.Render("Note: ") + m.statusNote + cursorStyle.Render("_")
+
+	sections = append(sections, mainBoxStyle.
+		MarginLeft(1).
+		MarginRight(1).
+		Render(content))
+	sections = append(sections, footerStyle.Render(joinHints("↑ ↓: choose status", "type a note (optional)", "enter: change", m.keys.typingHint("back", "cancel"))))
+
+	return lipgloss.JoinVertical(lipgloss.Left, sections...)
+}
+
+// renderStatusTimeline lists the viewed task's status changes, oldest
+// first, with the time spent in each status
+func (m model) renderStatusTimeline() string {
+	meta := m.tasks[m.cursor].metadata
+	if len(meta.StatusHistory) == 0 {
+		return ""
+	}
+
+	content := "\n" + headerStyle.Render("Status History") + "\n"
+	for _, change := range meta.StatusHistory {
+		content += "  " + formatStatusChange(change) + "\n"
+	}
+
+	var parts []string
+	durations := meta.timeInStatus(time.Now())
+	for _, status := range (statusMetrics{AverageInStatus: durations}).statuses() {
+		parts = append(parts, fmt.Sprintf("%s %s", status, formatCycleTime(durations[status])))
+	}
+	if len(parts) > 0 {
+		content += dimStyle.Render("  Time in status: "+strings.Join(parts, ", ")) + "\n"
+	}
+	return content
+}
+
+// renderTaskCommits lists the commits that mention the viewed task
+func (m model) renderTaskCommits() string {
+	const maxCommits = 5
+	if len(m.taskCommits) == 0 {
+		return ""
+	}
+
+	content := "\n" + headerStyle.Render("Commits") + "\n"
+	for i, commit := range m.taskCommits {
+		if i == maxCommits {
+			content += dimStyle.Render(fmt.Sprintf("  and %d more", len(m.taskCommits)-maxCommits)) + "\n"
+			break
+		}
+		line := fmt.Sprintf("  %s %s %s", diffHunkStyle.Render(commit.shortHash()),
+			dimStyle.Render(filepath.Base(commit.repo)), commit.subject)
+		if commit.closes {
+			line += " " + statusDoneStyle.Render("(closes)")
+		}
+		content += line + "\n"
+	}
+	return content
+}
+
+// renderHistoryView lists the commits that touched a task, with the diff
+// of the selected one below
+func (m model) renderHistoryView() string {
+	var sections []string
+
+	// Add top padding
+	sections = append(sections, "")
+
+	sections = append(sections, titleStyle.Render("History: "+m.tasks[m.cursor].name))
+
+	// Show a window of revisions around the cursor
+	const maxRevisions = 8
+	start := max(m.historyCursor-maxRevisions/2, 0)
+	end := min(start+maxRevisions, len(m.history))
+	start = max(end-maxRevisions, 0)
+
+	var revisions string
+	for i := start; i < end; i++ {
+		rev := m.history[i]
+		cursor := " "
+		if i == m.historyCursor {
+			cursor = cursorStyle.Render(">")
+		}
+		revisions += fmt.Sprintf("%s %s %s %-16s %s\n", cursor,
+			diffHunkStyle.Render(rev.shortHash()),
+			dimStyle.Render(rev.date.Local().Format("2006-01-02 15:04")),
+			rev.author, rev.subject)
+	}
+	sections = append(sections, mainBoxStyle.
+		MarginLeft(1).
+		MarginRight(1).
+		Render(strings.TrimRight(revisions, "\n")))
+
+	// Fit the diff into the remaining height:
+	// padding, title and margin, revisions box, diff box frame, footer
+	diffHeight := m.height - 1 - 2 - (end - start + 4) - 4 - 1
+	if diffHeight < 3 {
+		diffHeight = 3
+	}
+	lines := strings.Split(strings.TrimRight(m.historyDiff, "\n"), "\n")
+	scroll := min(m.historyScroll, max(len(lines)-diffHeight, 0))
+	lines = lines[scroll:min(scroll+diffHeight, len(lines))]
+
+	sections = append(sections, mainBoxStyle.
+		MarginLeft(1).
+		MarginRight(1).
+		Render(colorizeDiff(lines)))
+
+	footer := fmt.Sprintf("Revision %d of %d • ", m.historyCursor+1, len(m.history)) + m.keys.moveHint("choose") + " • pgup/pgdn: scroll diff • " +
+		m.keys.hint("restore", "restore") + " • " + m.keys.hint("back", "back") + " • " + m.keys.hint("quit", "quit")
+	if m.confirmRestore {
+		footer = fmt.Sprintf("Restore this task to %s? y: yes • n/%s: no", m.history[m.historyCursor].shortHash(), m.keys.label("back"))
+	} else if m.message != "" {
+		footer = m.message + " • " + footer
+	}
+	sections = append(sections, footerStyle.Render(footer))
 
 	return lipgloss.JoinVertical(lipgloss.Left, sections...)
 }
 
+// colorizeDiff colors added, removed and hunk header lines of a diff
+func colorizeDiff(lines []string) string {
+	var b strings.Builder
+	for i, line := range lines {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"),
+			strings.HasPrefix(line, "diff "), strings.HasPrefix(line, "index "):
+			b.WriteString(dimStyle.Render(line))
+		case strings.HasPrefix(line, "+"):
+			b.WriteString(diffAddStyle.Render(line))
+		case strings.HasPrefix(line, "-"):
+			b.WriteString(diffRemoveStyle.Render(line))
+		case strings.HasPrefix(line, "@@"):
+			b.WriteString(diffHunkStyle.Render(line))
+		default:
+			b.WriteString(line)
+		}
+	}
+	return b.String()
+}
+
 // renderListView displays the list of tasks
 func (m model) renderListView() string {
 	var sections []string
@@ -813,7 +2732,14 @@ func (m model) renderListView() string {
 		searchBox := searchBoxWithTitle.Render(searchContent)
 		searchBox = embedTitleInBorder(searchBox, "Search")
 		sections = append(sections, searchBox)
-	} // If there was an error loading tasks, display it
+	}
+
+	// Show the quick-add bar if adding a task
+	if m.mode == quickAddMode {
+		sections = append(sections, m.renderQuickAddBox())
+	}
+
+	// If there was an error loading tasks, display it
 	if m.err != nil {
 		content := errorStyle.Render(fmt.Sprintf("Error: %v", m.err)) + "\n\n"
 		content += "Make sure the configured directories exist:\n"
@@ -831,7 +2757,7 @@ func (m model) renderListView() string {
 			Render(content)
 		box = embedTitleInBorder(box, "Tasks")
 		sections = append(sections, box)
-		sections = append(sections, footerStyle.Render("q: quit"))
+		sections = append(sections, footerStyle.Render(m.keys.hint("quit", "quit")))
 		return lipgloss.JoinVertical(lipgloss.Left, sections...)
 	}
 
@@ -869,7 +2795,7 @@ func (m model) renderListView() string {
 		dirBox = embedTitleInBorder(dirBox, "Directories")
 		sections = append(sections, dirBox)
 
-		sections = append(sections, footerStyle.Render("q: quit"))
+		sections = append(sections, footerStyle.Render(m.keys.hint("quit", "quit")))
 		return lipgloss.JoinVertical(lipgloss.Left, sections...)
 	}
 
@@ -885,75 +2811,44 @@ func (m model) renderListView() string {
 			Render(content)
 		box = embedTitleInBorder(box, "Tasks")
 		sections = append(sections, box)
-		sections = append(sections, footerStyle.Render("esc: clear search • q: quit"))
+		sections = append(sections, footerStyle.Render(joinHints(m.keys.typingHint("back", "clear search"), m.keys.typingHint("quit", "quit"))))
 		return lipgloss.JoinVertical(lipgloss.Left, sections...)
 	}
 
 	// Build task list content
 	var content string
+	if len(visibleTasks) == 0 && m.completedOnly > 0 {
+		content = dimStyle.Render(fmt.Sprintf("No tasks completed %s.", completedFilterLabel(completedFilters[m.completedOnly])))
+	} else if len(visibleTasks) == 0 && (m.activeView > 0 || m.tagFilter != "" || m.projectFilter != "") {
+		content = dimStyle.Render("No tasks match this view.")
+	}
 
-	// Render each visible task in our list
-	for i, task := range visibleTasks {
-		// Is the cursor pointing at this task?
-		var cursor string
-		if m.cursor == i {
-			cursor = cursorStyle.Render(">") // cursor!
-		} else {
-			cursor = " " // no cursor
-		}
-
-		// Get status, using default if not set
-		status := task.metadata.Status
-		if status == "" {
-			status = m.config.GetDefaultStatus()
-		}
-
-		// Status indicator with color
-		statusIndicator := m.config.GetStatusIndicator(status)
-		var styledStatus string
-		switch strings.ToLower(status) {
-		case "done", "completed":
-			styledStatus = statusDoneStyle.Render(statusIndicator)
-		case "in-progress", "doing":
-			styledStatus = statusInProgressStyle.Render(statusIndicator)
-		default:
-			styledStatus = statusTodoStyle.Render(statusIndicator)
-		}
-
-		// Priority with color
-		priorityEmoji := getPriorityEmoji(task.metadata.Priority)
-		var styledPriority string
-		if priorityEmoji != "" {
-			switch strings.ToLower(task.metadata.Priority) {
-			case "high":
-				styledPriority = priorityHighStyle.Render(priorityEmoji) + " "
-			case "medium":
-				styledPriority = priorityMediumStyle.Render(priorityEmoji) + " "
-			case "low":
-				styledPriority = priorityLowStyle.Render(priorityEmoji) + " "
-			default:
-				styledPriority = priorityEmoji + " "
+	// Render each visible task in our list, under group headings if the
+	// list is grouped. The cursor stops on tasks and collapsed headings.
+	var columns []string
+	if view, ok := m.currentView(); ok {
+		columns = view.Columns
+	}
+	// Rows fit the box's content width (see tasksBoxStyle below)
+	layout := m.columnLayout(columns, m.width-8)
+	i := 0
+	for _, group := range m.taskGroups() {
+		if group.name != "" && m.collapsed[group.name] {
+			cursor := " "
+			if m.cursor == i {
+				cursor = cursorStyle.Render(">")
 			}
+			content += cursor + " " + headerStyle.Render(fmt.Sprintf("▸ %s (%d)", group.name, len(group.tasks))) + "\n"
+			i++
+			continue
 		}
-
-		// Use title from frontmatter if available, otherwise use filename
-		displayName := task.name
-		if task.metadata.Title != "" {
-			displayName = task.metadata.Title
+		if group.name != "" {
+			content += "  " + headerStyle.Render(fmt.Sprintf("▾ %s (%d)", group.name, len(group.tasks))) + "\n"
 		}
-
-		// Format the modification time nicely
-		modTime := dimStyle.Render(task.modTime.Format("2006-01-02 15:04"))
-
-		// Build the row with status and priority
-		row := fmt.Sprintf("%s %s %s%-40s  %s", cursor, styledStatus, styledPriority, displayName, modTime)
-
-		// If we have multiple directories, show which one this task is from
-		if m.showDirInfo {
-			row += fmt.Sprintf("  [%s]", task.sourceDir)
+		for _, task := range group.tasks {
+			content += m.renderTaskRow(task, m.cursor == i, layout) + "\n"
+			i++
 		}
-
-		content += row + "\n"
 	}
 
 	// Calculate directory box height first
@@ -969,6 +2864,9 @@ func (m model) renderListView() string {
 	if m.mode == searchMode {
 		usedHeight += 3 // Search box: 1 content + 2 border (title embedded)
 	}
+	if m.mode == quickAddMode {
+		usedHeight += 4 // Quick-add box: input + preview + 2 border (title embedded)
+	}
 	usedHeight += 2            // Tasks box: top/bottom border (title embedded in top)
 	usedHeight += 2            // Tasks box: internal padding (1 top + 1 bottom from Padding(1, 2))
 	usedHeight += dirBoxHeight // Directories box: dirLines + 2 border
@@ -991,7 +2889,17 @@ func (m model) renderListView() string {
 
 	// Add the task list box with title embedded in border
 	box := tasksBoxStyle.Render(strings.TrimRight(content, "\n"))
-	box = embedTitleInBorder(box, "Tasks")
+	boxTitle := "Tasks"
+	if view, ok := m.currentView(); ok {
+		boxTitle += " · " + view.Name
+	}
+	if m.projectFilter != "" {
+		boxTitle += " · " + m.projectFilter
+	}
+	if m.tagFilter != "" {
+		boxTitle += " · #" + m.tagFilter
+	}
+	box = embedTitleInBorder(box, boxTitle)
 	sections = append(sections, box)
 
 	// Directory info box with title embedded in border
@@ -1017,22 +2925,125 @@ func (m model) renderListView() string {
 	var footer string
 	if m.mode == searchMode {
 		footer = fmt.Sprintf("Showing %d of %d tasks", len(visibleTasks), len(m.tasks))
-		footer += " • esc: clear search • enter: view • ?: help • q: quit"
+		footer = joinHints(footer, m.keys.typingHint("back", "clear search"), "enter: view", m.keys.typingHint("quit", "quit"))
+	} else if m.mode == quickAddMode {
+		footer = joinHints("enter: create task", m.keys.typingHint("back", "cancel"), "!high #tag due:fri @dir")
 	} else {
 		footer = fmt.Sprintf("Showing %d tasks", len(m.tasks))
-		footer += " • /: search • ↑/k: up • ↓/j: down • enter: view • n: new • ?: help • q: quit"
+		if m.activeView > 0 || m.tagFilter != "" || m.projectFilter != "" {
+			footer = fmt.Sprintf("Showing %d of %d tasks", len(visibleTasks), len(m.tasks))
+		}
+		k := m.keys
+		if m.tagFilter != "" || m.projectFilter != "" {
+			footer += " • " + k.hint("clear_filter", "show all")
+		}
+		if m.completedOnly > 0 {
+			footer = fmt.Sprintf("Completed %s: %d", completedFilterLabel(completedFilters[m.completedOnly]), len(visibleTasks))
+			if avg, ok := averageCycleTime(visibleTasks); ok {
+				footer += fmt.Sprintf(" • avg cycle time %s", formatCycleTime(avg))
+			}
+		}
+		for _, h := range [][2]string{{"search", "search"}, {"up", "up"}, {"down", "down"}, {"open", "view"}, {"new", "new"}, {"quick_add", "quick add"}, {"done", "done"}, {"completed", "completed"}, {"timer", "timer"}} {
+			footer += " • " + k.hint(h[0], h[1])
+		}
+		if len(m.views) > 0 {
+			footer += " • " + k.label("views") + "/1-9: views"
+		}
+		footer += " • " + k.hint("tags", "tags")
+		if len(m.projects) > 0 {
+			footer += " • " + k.hint("projects", "projects")
+		}
+		footer += " • " + k.hint("group", "group")
+		if m.groupBy != "" {
+			footer += " • " + k.hint("fold", "fold")
+		}
+		if len(m.gitRoots) > 0 {
+			footer += " • " + k.hint("sync", "sync")
+		}
+		footer += " • " + k.hint("help", "help") + " • " + k.hint("quit", "quit")
+	}
+	if m.message != "" {
+		footer = m.message + " • " + footer
+	}
+	if m.timer != nil {
+		footer = m.timerIndicator() + " • " + footer
 	}
 	sections = append(sections, footerStyle.Render(footer))
 
 	return lipgloss.JoinVertical(lipgloss.Left, sections...)
 }
 
+// renderQuickAddBox draws the quick-add input with a live preview of how
+// the line will be parsed
+func (m model) renderQuickAddBox() string {
+	input := searchPrefixStyle.Render("+ ")
+	if m.quickAddInput == "" {
+		input += dimStyle.Render("Fix login redirect !high #auth due:fri @project-a")
+	} else {
+		input += m.quickAddInput
+	}
+
+	// Preview the parsed fields, or show why the line can't be used
+	var preview string
+	if m.quickAddErr != nil {
+		preview = errorStyle.Render(m.quickAddErr.Error())
+	} else if strings.TrimSpace(m.quickAddInput) != "" {
+		task, err := parseQuickAdd(m.quickAddInput, time.Now())
+		if err != nil {
+			preview = errorStyle.Render(err.Error())
+		} else {
+			parts := []string{"title: " + task.Title}
+			if task.Priority != "" {
+				parts = append(parts, "priority: "+task.Priority)
+			}
+			if len(task.Tags) > 0 {
+				parts = append(parts, "tags: "+strings.Join(task.Tags, ", "))
+			}
+			if !task.DueDate.IsZero() {
+				parts = append(parts, "due: "+task.DueDate.Format("Mon 2006-01-02"))
+			}
+			dir := m.defaultTaskDir()
+			if task.Dir != "" {
+				if resolved, err := resolveTaskDir(task.Dir, m.configDirs); err != nil {
+					dir = err.Error()
+				} else {
+					dir = resolved
+				}
+			}
+			parts = append(parts, "dir: "+dir)
+			preview = dimStyle.Render(strings.Join(parts, " • "))
+		}
+	}
+
+	// Same sizing as the search box: content_width = m.width - 6
+	box := searchBoxStyle.
+		Width(m.width - 6).
+		MarginLeft(1).
+		MarginRight(1).
+		Render(input + "\n" + preview)
+	return embedTitleInBorder(box, "Quick Add")
+}
+
 func main() {
+	// Parse command-line flags
+	noCache := flag.Bool("no-cache", false, "Parse every task file instead of using the metadata cache")
+	flag.Usage = usage
+	flag.Parse()
+
+	// Run a subcommand instead of the TUI if one was given
+	if flag.NArg() > 0 {
+		if err := runCommand(flag.Args(), !*noCache); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Create a new Bubble Tea program with our model
 	// WithAltScreen() enables alternate screen mode - the app takes over
 	// the full terminal and restores it when you quit (like vim, lazygit, etc.)
 	p := tea.NewProgram(
-		initialModel(),
+		initialModel(!*noCache),
 		tea.WithAltScreen(),       // Use alternate screen buffer
 		tea.WithMouseCellMotion(), // Enable mouse support (optional, but nice!)
 	)
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDeleteTask(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	dir := t.TempDir()
+	for _, name := range []string{"a", "b", "c"} {
+		writeFile(t, filepath.Join(dir, name+".md"), "---\ntitle: Task "+name+"\nstatus: todo\n---\n")
+	}
+	tasks, err := loadTasksFromDirectories([]string{dir}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Deleting the last task, opened from the end of the list, while
+	// timing it
+	m := model{tasks: tasks, keys: defaultKeyMap(), cursor: 2}
+	m.filterTasks()
+	last, _ := m.selectedTask()
+	m, _ = m.viewTask(last)
+	if _, err := startTimer(last.fullPath, []string{dir}); err != nil {
+		t.Fatal(err)
+	}
+	m.timer = loadState().Timer
+	m.mode = confirmDeleteMode
+
+	m = m.deleteTask().(model)
+	if _, err := os.Stat(last.fullPath); !os.IsNotExist(err) {
+		t.Errorf("the file is still there: %v", err)
+	}
+	if m.mode != listMode || len(m.listRows()) != 2 {
+		t.Errorf("mode %d with %d rows after deleting", m.mode, len(m.listRows()))
+	}
+	if m.cursor != 1 {
+		t.Errorf("cursor = %d, want the new last row", m.cursor)
+	}
+	if m.timer != nil || loadState().Timer != nil {
+		t.Error("the deleted task's timer is still running")
+	}
+
+	// Deleting from search results drops the task from them
+	m.searchQuery = "task"
+	m.filterTasks()
+	m.cursor = 0
+	first, _ := m.selectedTask()
+	m, _ = m.viewTask(first)
+	m = m.deleteTask().(model)
+	if len(m.filteredTasks) != 1 || m.filteredTasks[0].fullPath == first.fullPath || m.cursor != 0 {
+		t.Errorf("search results %v, cursor %d after deleting %s", m.filteredTasks, m.cursor, first.name)
+	}
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// markdownLink matches the target of a markdown link or image:
+// [text](target) or [text](target "title")
+var markdownLink = regexp.MustCompile(`\]\(([^)\s]+)((?:\s+"[^"]*")?)\)`)
+
+// wikiLink matches [[name]], [[name|label]] and [[name#heading]]
+var wikiLink = regexp.MustCompile(`\[\[([^\]|#]+)([|#][^\]]*)?\]\]`)
+
+// availablePath returns the first path in dir for name that isn't taken,
+// adding a numeric suffix before the extension if needed
+func availablePath(dir, name string) (string, error) {
+	for i := 1; ; i++ {
+		candidate := filepath.Join(dir, numberedFilename(name, i))
+		if _, err := os.Stat(candidate); os.IsNotExist(err) {
+			return candidate, nil
+		} else if err != nil {
+			return "", fmt.Errorf("failed to check %s: %w", candidate, err)
+		}
+	}
+}
+
+// transferResult describes what a move or copy did
+type transferResult struct {
+	newPath      string   // Where the task ended up
+	updatedPaths []string // Other tasks whose links were updated
+}
+
+// moveTask moves a task file into targetDir, keeping its ID. Relative
+// links inside the task are adjusted for its new location, and links to
+// it from tasks in any of dirs are updated to point at the new path.
+func moveTask(srcPath, targetDir string, dirs []string) (transferResult, error) {
+	var result transferResult
+
+	destPath, err := transferDestination(srcPath, targetDir)
+	if err != nil {
+		return result, err
+	}
+
+	if err := os.Rename(srcPath, destPath); err != nil {
+		// Renaming across filesystems fails, so copy and remove instead
+		if err := copyFile(srcPath, destPath); err != nil {
+			return result, err
+		}
+		if err := os.Remove(srcPath); err != nil {
+			return result, fmt.Errorf("copied task but couldn't remove the original: %w", err)
+		}
+	}
+	result.newPath = destPath
+
+	if err := relinkOutgoing(destPath, filepath.Dir(srcPath)); err != nil {
+		return result, err
+	}
+
+	result.updatedPaths, err = relinkIncoming(srcPath, destPath, dirs)
+	return result, err
+}
+
+// copyTask duplicates a task file into targetDir. The copy gets a new
+// ID, since two tasks can't share one, and relative links inside it are
+// adjusted for its location. Links from other tasks keep pointing at
+// the original.
+func copyTask(srcPath, targetDir string) (transferResult, error) {
+	var result transferResult
+
+	destPath, err := transferDestination(srcPath, targetDir)
+	if err != nil {
+		return result, err
+	}
+
+	if err := copyFile(srcPath, destPath); err != nil {
+		return result, err
+	}
+	result.newPath = destPath
+
+	if meta, err := parseFrontmatter(destPath); err == nil && meta.ID != "" {
+		err := updateFrontmatter(destPath, func(fields *yaml.MapSlice) {
+			setField(fields, "id", newTaskID())
+		})
+		if err != nil {
+			return result, err
+		}
+	}
+
+	return result, relinkOutgoing(destPath, filepath.Dir(srcPath))
+}
+
+// transferDestination works out where a task moved or copied into
+// targetDir should go, avoiding existing files
+func transferDestination(srcPath, targetDir string) (string, error) {
+	expandedDir, err := expandPath(targetDir)
+	if err != nil {
+		return "", err
+	}
+
+	if filepath.Clean(filepath.Dir(srcPath)) == filepath.Clean(expandedDir) {
+		return "", fmt.Errorf("task is already in %s", targetDir)
+	}
+
+	return availablePath(expandedDir, filepath.Base(srcPath))
+}
+
+// copyFile copies src to a new file at dest, failing if dest exists
+func copyFile(src, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", dest, err)
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dest)
+		return fmt.Errorf("failed to copy task: %w", err)
+	}
+	return out.Close()
+}
+
+// relinkOutgoing rewrites the relative markdown links in a task that has
+// just been placed in a new directory, so they still point where they
+// did from oldDir
+func relinkOutgoing(taskPath, oldDir string) error {
+	newDir := filepath.Dir(taskPath)
+	return rewriteLinksInFile(taskPath, func(target string) (string, bool) {
+		return relativeLink(newDir, filepath.Join(oldDir, target))
+	}, nil)
+}
+
+// relinkIncoming updates links to oldPath in every task across dirs so
+// they point at newPath. Markdown links are matched by resolved path,
+// wiki links by filename. It returns the files that were changed.
+func relinkIncoming(oldPath, newPath string, dirs []string) ([]string, error) {
+	oldName := strings.TrimSuffix(filepath.Base(oldPath), ".md")
+	newName := strings.TrimSuffix(filepath.Base(newPath), ".md")
+
+	// Wiki links only break if the filename itself changed
+	var renameWiki func(name string) (string, bool)
+	if oldName != newName {
+		renameWiki = func(name string) (string, bool) {
+			if strings.TrimSuffix(name, ".md") != oldName {
+				return "", false
+			}
+			return newName, true
+		}
+	}
+
+	var updated []string
+	for _, dir := range dirs {
+		expandedDir, err := expandPath(dir)
+		if err != nil {
+			continue
+		}
+		entries, err := os.ReadDir(expandedDir)
+		if err != nil {
+			continue
+		}
+
+		// A wiki link resolves to a file in its own directory first, so
+		// where another task still has the old name, links mean that one
+		_, err = os.Stat(filepath.Join(expandedDir, oldName+".md"))
+		shadowed := err == nil
+
+		for _, entry := range entries {
+			if entry.IsDir() || filepath.Ext(entry.Name()) != ".md" {
+				continue
+			}
+
+			taskPath := filepath.Join(expandedDir, entry.Name())
+			if taskPath == newPath {
+				continue
+			}
+
+			changed := false
+			err := rewriteLinksInFile(taskPath, func(target string) (string, bool) {
+				if filepath.Join(expandedDir, target) != oldPath {
+					return "", false
+				}
+				changed = true
+				return relativeLink(expandedDir, newPath)
+			}, func(name string) (string, bool) {
+				if renameWiki == nil || shadowed {
+					return "", false
+				}
+				newName, ok := renameWiki(name)
+				changed = changed || ok
+				return newName, ok
+			})
+			if err != nil {
+				return updated, err
+			}
+			if changed {
+				updated = append(updated, taskPath)
+			}
+		}
+	}
+
+	return updated, nil
+}
+
+// rewriteLinksInFile passes every local markdown link target (and wiki
+// link name, if wiki is set) in a file through the given functions and
+// writes the file back if anything changed. Targets are given unescaped
+// and without any #fragment, which is kept as it was.
+func rewriteLinksInFile(taskPath string, markdown, wiki func(string) (string, bool)) error {
+	content, err := os.ReadFile(taskPath)
+	if err != nil {
+		return err
+	}
+	text := string(content)
+
+	rewritten := markdownLink.ReplaceAllStringFunc(text, func(match string) string {
+		parts := markdownLink.FindStringSubmatch(match)
+		target, title := parts[1], parts[2]
+
+		// Leave URLs, anchors and absolute paths alone
+		if strings.Contains(target, "://") || strings.HasPrefix(target, "#") ||
+			strings.HasPrefix(target, "mailto:") || filepath.IsAbs(target) {
+			return match
+		}
+
+		path, fragment, _ := strings.Cut(target, "#")
+		if unescaped, err := url.PathUnescape(path); err == nil {
+			path = unescaped
+		}
+		if filepath.Ext(path) != ".md" {
+			return match
+		}
+
+		newTarget, ok := markdown(path)
+		if !ok {
+			return match
+		}
+		if fragment != "" {
+			newTarget += "#" + fragment
+		}
+		return "](" + newTarget + title + ")"
+	})
+
+	if wiki != nil {
+		rewritten = wikiLink.ReplaceAllStringFunc(rewritten, func(match string) string {
+			parts := wikiLink.FindStringSubmatch(match)
+			newName, ok := wiki(strings.TrimSpace(parts[1]))
+			if !ok {
+				return match
+			}
+			return "[[" + newName + parts[2] + "]]"
+		})
+	}
+
+	if rewritten == text {
+		return nil
+	}
+
+	info, err := os.Stat(taskPath)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(taskPath, []byte(rewritten), info.Mode().Perm())
+}
+
+// relativeLink returns a slash-separated link to target from fromDir,
+// with spaces escaped so it stays a valid markdown link
+func relativeLink(fromDir, target string) (string, bool) {
+	rel, err := filepath.Rel(fromDir, target)
+	if err != nil {
+		return "", false
+	}
+	return strings.ReplaceAll(filepath.ToSlash(rel), " ", "%20"), true
+}
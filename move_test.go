@@ -0,0 +1,95 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMoveTaskRewritesLinks(t *testing.T) {
+	root := t.TempDir()
+	inbox := filepath.Join(root, "inbox")
+	archive := filepath.Join(root, "archive", "2025 q1")
+	notes := filepath.Join(root, "notes")
+	files := map[string]string{
+		"inbox/task.md": "# Task\n\n" +
+			"[spec](spec.md) [notes](../notes/plan.md#goals \"Plan\") [spaced](my%20spec.md)\n" +
+			"[site](https://example.com/a.md) [abs](/srv/a.md) [top](#task) [image](diagram.png) [[spec]]\n",
+		"inbox/spec.md": "# Spec\n\n[task](task.md) [[task]] [[task|the task]] [[task#Steps]] [[spec]]\n",
+		"notes/plan.md": "# Plan\n\n[task](../inbox/task.md#steps) [other](../inbox/spec.md) [[task]]\n",
+		// A different task.md already in the archive, so the moved one is
+		// renamed and wiki links there keep meaning this one
+		"archive/2025 q1/task.md":  "# Old task\n",
+		"archive/2025 q1/index.md": "[[task]] [inbox](../../inbox/task.md)\n",
+	}
+	for name, content := range files {
+		writeFile(t, filepath.Join(root, name), content)
+	}
+
+	result, err := moveTask(filepath.Join(inbox, "task.md"), archive, []string{inbox, notes, archive})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(archive, "task-2.md"); result.newPath != want {
+		t.Fatalf("moved to %s, want %s", result.newPath, want)
+	}
+	if len(result.updatedPaths) != 3 {
+		t.Errorf("updated %v, want spec.md, plan.md and index.md", result.updatedPaths)
+	}
+
+	for _, test := range []struct {
+		name, want string
+	}{
+		{
+			// Relative links follow the task; others are left alone
+			"archive/2025 q1/task-2.md", "# Task\n\n" +
+				"[spec](../../inbox/spec.md) [notes](../../notes/plan.md#goals \"Plan\") [spaced](../../inbox/my%20spec.md)\n" +
+				"[site](https://example.com/a.md) [abs](/srv/a.md) [top](#task) [image](diagram.png) [[spec]]\n",
+		},
+		{
+			"inbox/spec.md", "# Spec\n\n[task](../archive/2025%20q1/task-2.md) [[task-2]] [[task-2|the task]] [[task-2#Steps]] [[spec]]\n",
+		},
+		{
+			"notes/plan.md", "# Plan\n\n[task](../archive/2025%20q1/task-2.md#steps) [other](../inbox/spec.md) [[task-2]]\n",
+		},
+		{
+			"archive/2025 q1/index.md", "[[task]] [inbox](task-2.md)\n",
+		},
+		{
+			"archive/2025 q1/task.md", "# Old task\n",
+		},
+	} {
+		content, err := os.ReadFile(filepath.Join(root, test.name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != test.want {
+			t.Errorf("%s =\n%s\nwant\n%s", test.name, content, test.want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(inbox, "task.md")); !os.IsNotExist(err) {
+		t.Errorf("the original is still there: %v", err)
+	}
+}
+
+func TestMoveTaskKeepsWikiLinksWhenTheNameStays(t *testing.T) {
+	root := t.TempDir()
+	inbox, done := filepath.Join(root, "inbox"), filepath.Join(root, "done")
+	writeFile(t, filepath.Join(inbox, "task.md"), "# Task\n")
+	writeFile(t, filepath.Join(inbox, "other.md"), "[[task]] [task](./task.md)\n")
+	if err := os.MkdirAll(done, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := moveTask(filepath.Join(inbox, "task.md"), done, []string{inbox, done}); err != nil {
+		t.Fatal(err)
+	}
+	content, _ := os.ReadFile(filepath.Join(inbox, "other.md"))
+	if want := "[[task]] [task](../done/task.md)\n"; string(content) != want {
+		t.Errorf("other.md = %q, want %q", content, want)
+	}
+
+	if _, err := moveTask(filepath.Join(done, "task.md"), done, []string{inbox, done}); err == nil {
+		t.Error("moving a task into its own directory succeeded")
+	}
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"crypto/rand"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"gopkg.in/yaml.v2"
+)
+
+// defaultFilenamePattern is used for new task files unless the config
+// sets filename_pattern
+const defaultFilenamePattern = "{{date}}-{{slug}}.md"
+
+// maxSlugLength keeps generated filenames a sensible length
+const maxSlugLength = 50
+
+// timestampFilename matches the names new tasks used to be given,
+// e.g. task-20251203-150405.md or task-20251203-150405-2.md
+var timestampFilename = regexp.MustCompile(`^task-(\d{8}-\d{6})(-\d+)?\.md$`)
+
+// slugify turns a title into a lowercase, dash-separated filename part.
+// Letters and digits are kept (including non-ASCII ones), everything
+// else becomes a single dash.
+func slugify(title string) string {
+	var b strings.Builder
+	dash := false
+
+	for _, r := range strings.ToLower(title) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteRune('-')
+			dash = true
+		}
+	}
+
+	slug := strings.TrimRight(b.String(), "-")
+
+	// Shorten long titles, preferably at a word boundary
+	if runes := []rune(slug); len(runes) > maxSlugLength {
+		slug = string(runes[:maxSlugLength])
+		if i := strings.LastIndex(slug, "-"); i > maxSlugLength/2 {
+			slug = slug[:i]
+		}
+		slug = strings.TrimRight(slug, "-")
+	}
+
+	if slug == "" {
+		return "task"
+	}
+	return slug
+}
+
+// newTaskID returns a short random ID for a new task, e.g. "k3x9p2".
+// IDs are stored in the frontmatter so they stay stable when the file
+// is renamed or moved.
+func newTaskID() string {
+	const alphabet = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+	buf := make([]byte, 6)
+	rand.Read(buf)
+	for i, b := range buf {
+		buf[i] = alphabet[int(b)%len(alphabet)]
+	}
+	return string(buf)
+}
+
+// renderFilename builds a task filename from the configured pattern.
+// Supported variables: {{slug}}, {{id}}, {{date}} (2006-01-02),
+// {{time}} (150405) and {{timestamp}} (20060102-150405). The date is
+// taken from the task's created field, or from now if it has none.
+// Callers must make sure the task has an ID if the pattern uses one.
+func renderFilename(pattern string, meta TaskMetadata, now time.Time) string {
+	when := meta.Created
+	if when.IsZero() {
+		when = now
+	}
+
+	name := strings.NewReplacer(
+		"{{slug}}", slugify(meta.Title),
+		"{{id}}", meta.ID,
+		"{{date}}", when.Format("2006-01-02"),
+		"{{time}}", when.Format("150405"),
+		"{{timestamp}}", when.Format("20060102-150405"),
+	).Replace(pattern)
+
+	// Never let the pattern escape the task directory
+	name = strings.NewReplacer("/", "-", `\`, "-").Replace(name)
+
+	if filepath.Ext(name) != ".md" {
+		name += ".md"
+	}
+	return name
+}
+
+// patternNeedsID reports whether filenames built from pattern include
+// the task ID
+func patternNeedsID(pattern string) bool {
+	return strings.Contains(pattern, "{{id}}")
+}
+
+// ensureTaskID gives a task file an ID if it doesn't have one yet and
+// returns its metadata
+func ensureTaskID(taskPath string) (TaskMetadata, error) {
+	meta, err := parseFrontmatter(taskPath)
+	if err != nil || meta.ID != "" {
+		return meta, err
+	}
+
+	meta.ID = newTaskID()
+	err = updateFrontmatter(taskPath, func(fields *yaml.MapSlice) {
+		setField(fields, "id", meta.ID)
+	})
+	return meta, err
+}
+
+// numberedFilename adds a numeric suffix before the extension,
+// e.g. fix-login.md -> fix-login-2.md
+func numberedFilename(name string, n int) string {
+	if n <= 1 {
+		return name
+	}
+	ext := filepath.Ext(name)
+	return fmt.Sprintf("%s-%d%s", strings.TrimSuffix(name, ext), n, ext)
+}
+
+// renameTaskFile moves a task to newName within its directory, adding a
+// numeric suffix if that name is taken. It returns the new path, which is
+// the old one if the name is already right.
+func renameTaskFile(oldPath, newName string) (string, error) {
+	dir := filepath.Dir(oldPath)
+	if filepath.Base(oldPath) == newName {
+		return oldPath, nil
+	}
+
+	for i := 1; ; i++ {
+		candidate := filepath.Join(dir, numberedFilename(newName, i))
+		if candidate == oldPath {
+			return oldPath, nil
+		}
+		if _, err := os.Stat(candidate); err == nil {
+			continue
+		} else if !os.IsNotExist(err) {
+			return "", fmt.Errorf("failed to check %s: %w", candidate, err)
+		}
+
+		if err := os.Rename(oldPath, candidate); err != nil {
+			return "", fmt.Errorf("failed to rename task: %w", err)
+		}
+		return candidate, nil
+	}
+}
+
+// renameForTitle renames a task file after its (new) title
+func renameForTitle(taskPath, pattern string) (string, error) {
+	meta, err := parseFrontmatter(taskPath)
+	if err != nil {
+		return "", err
+	}
+	if meta.Title == "" {
+		return taskPath, nil
+	}
+
+	if patternNeedsID(pattern) {
+		if meta, err = ensureTaskID(taskPath); err != nil {
+			return "", err
+		}
+	}
+
+	return renameTaskFile(taskPath, renderFilename(pattern, meta, time.Now()))
+}
+
+// timestampRename is a planned rename of an old timestamp-named file
+type timestampRename struct {
+	oldPath string       // The timestamp-named file
+	meta    TaskMetadata // Its metadata, used to build the new name
+}
+
+// planTimestampRenames finds task-YYYYMMDD-HHMMSS.md files that have a
+// title, so they can be renamed after it
+func planTimestampRenames(dirs []string) ([]timestampRename, error) {
+	var plans []timestampRename
+
+	for _, dir := range dirs {
+		expandedDir, err := expandPath(dir)
+		if err != nil {
+			return nil, err
+		}
+
+		entries, err := os.ReadDir(expandedDir)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read directory %s: %w", dir, err)
+		}
+
+		for _, entry := range entries {
+			match := timestampFilename.FindStringSubmatch(entry.Name())
+			if entry.IsDir() || match == nil {
+				continue
+			}
+
+			fullPath := filepath.Join(expandedDir, entry.Name())
+			meta, _ := parseFrontmatter(fullPath)
+			if meta.Title == "" {
+				continue
+			}
+
+			// Files without a created field keep the date from their name
+			if meta.Created.IsZero() {
+				if t, err := time.ParseInLocation("20060102-150405", match[1], time.Local); err == nil {
+					meta.Created = t
+				}
+			}
+
+			plans = append(plans, timestampRename{
+				oldPath: fullPath,
+				meta:    meta,
+			})
+		}
+	}
+
+	return plans, nil
+}
+
+// newName returns the filename the task should get under pattern.
+// Tasks without an ID show a placeholder, as one is only assigned when
+// the rename is applied.
+func (r timestampRename) newName(pattern string) string {
+	meta := r.meta
+	if meta.ID == "" {
+		meta.ID = "<new-id>"
+	}
+	return renderFilename(pattern, meta, time.Now())
+}
+
+// apply performs the rename, assigning an ID first if the pattern needs one
+func (r timestampRename) apply(pattern string) (string, error) {
+	meta := r.meta
+	if patternNeedsID(pattern) && meta.ID == "" {
+		withID, err := ensureTaskID(r.oldPath)
+		if err != nil {
+			return "", err
+		}
+		meta.ID = withID.ID
+	}
+	return renameTaskFile(r.oldPath, renderFilename(pattern, meta, time.Now()))
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"gopkg.in/yaml.v2"
+)
+
+// ProjectConfig describes a project: a task directory with a name, a
+// short code shown in the list and defaults for new tasks
+type ProjectConfig struct {
+	Name            string   `toml:"name"`
+	Code            string   `toml:"code"`             // Short code shown in the list, e.g. "WEB"
+	Path            string   `toml:"path"`             // Directory holding the project's tasks
+	Color           string   `toml:"color"`            // Color of the code (ANSI number or hex)
+	DefaultTemplate string   `toml:"default_template"` // Template preselected for new tasks
+	DefaultTags     []string `toml:"default_tags"`     // Tags added to new tasks
+}
+
+// GetCode returns the project's code, or its name if it has none
+func (p ProjectConfig) GetCode() string {
+	if p.Code != "" {
+		return p.Code
+	}
+	return p.Name
+}
+
+// style returns the style for the project's code
+func (p ProjectConfig) style() lipgloss.Style {
+	if p.Color == "" {
+		return lipgloss.NewStyle().Bold(true)
+	}
+	return lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color(p.Color))
+}
+
+// addProjectDirs adds each project's path to the task directories unless
+// it's already there. Directories from directory or directories are kept
+// first, and the ~/.tasks fallback only applies without any projects.
+func (c *Config) addProjectDirs() {
+	if len(c.Projects) == 0 {
+		return
+	}
+
+	var dirs []string
+	if len(c.TaskManager.Directories) > 0 || c.TaskManager.Directory != "" {
+		dirs = c.TaskManager.GetDirectories()
+	}
+	for _, project := range c.Projects {
+		if project.Path != "" && !containsDir(dirs, project.Path) {
+			dirs = append(dirs, project.Path)
+		}
+	}
+	if len(dirs) > 0 {
+		c.TaskManager.Directories = dirs
+	}
+}
+
+// sameDir reports whether two configured paths point at the same
+// directory, once ~ is expanded
+func sameDir(a, b string) bool {
+	ea, errA := expandPath(a)
+	eb, errB := expandPath(b)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+	return filepath.Clean(ea) == filepath.Clean(eb)
+}
+
+// containsDir reports whether dirs includes dir
+func containsDir(dirs []string, dir string) bool {
+	for _, d := range dirs {
+		if sameDir(d, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// projectForDir returns the project whose path is dir
+func projectForDir(projects []ProjectConfig, dir string) (ProjectConfig, bool) {
+	for _, project := range projects {
+		if project.Path != "" && sameDir(project.Path, dir) {
+			return project, true
+		}
+	}
+	return ProjectConfig{}, false
+}
+
+// findProject returns the project with the given code or name, ignoring
+// case
+func findProject(projects []ProjectConfig, name string) (ProjectConfig, bool) {
+	for _, project := range projects {
+		if strings.EqualFold(project.GetCode(), name) || strings.EqualFold(project.Name, name) {
+			return project, true
+		}
+	}
+	return ProjectConfig{}, false
+}
+
+// assignProjects records each task's project code, from the directory it
+// was loaded from
+func assignProjects(tasks []taskFile, projects []ProjectConfig) {
+	if len(projects) == 0 {
+		return
+	}
+	codes := map[string]string{}
+	for i, task := range tasks {
+		code, ok := codes[task.sourceDir]
+		if !ok {
+			if project, found := projectForDir(projects, task.sourceDir); found {
+				code = project.GetCode()
+			}
+			codes[task.sourceDir] = code
+		}
+		tasks[i].project = code
+	}
+}
+
+// addDefaultTags adds a project's default tags to a new task file that
+// doesn't have them yet
+func addDefaultTags(taskPath string, project ProjectConfig) error {
+	if len(project.DefaultTags) == 0 {
+		return nil
+	}
+	meta, err := parseFrontmatter(taskPath)
+	if err != nil {
+		return err
+	}
+	tags := mergeTags(meta.Tags, project.DefaultTags)
+	if len(tags) == len(meta.Tags) {
+		return nil
+	}
+	return updateFrontmatter(taskPath, func(fields *yaml.MapSlice) {
+		setField(fields, "tags", tags)
+	})
+}
+
+// mergeTags appends the extra tags that aren't in tags already
+func mergeTags(tags, extra []string) []string {
+	merged := append([]string{}, tags...)
+	for _, tag := range extra {
+		if !containsFold(merged, tag) {
+			merged = append(merged, tag)
+		}
+	}
+	return merged
+}
+
+// projectSummary is a project's row in the projects overview
+type projectSummary struct {
+	project    ProjectConfig
+	total      int
+	open       int
+	inProgress int
+	done       int
+	overdue    int
+}
+
+// summarizeProjects counts each project's tasks by state
+func summarizeProjects(tasks []taskFile, projects []ProjectConfig, now time.Time) []projectSummary {
+	var summaries []projectSummary
+	for _, project := range projects {
+		summary := projectSummary{project: project}
+		for _, task := range tasks {
+			if task.project != project.GetCode() {
+				continue
+			}
+			summary.total++
+			switch {
+			case isDoneStatus(task.metadata.Status):
+				summary.done++
+			case isInProgressStatus(task.metadata.Status):
+				summary.inProgress++
+				summary.open++
+			default:
+				summary.open++
+			}
+			if dueMatches(task.metadata, "overdue", now) {
+				summary.overdue++
+			}
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries
+}
+
+// formatCounts describes a project's task counts in one line
+func (s projectSummary) formatCounts() string {
+	line := fmt.Sprintf("%d open, %d in progress, %d done", s.open, s.inProgress, s.done)
+	if s.overdue > 0 {
+		line += fmt.Sprintf(", %d overdue", s.overdue)
+	}
+	return line
+}
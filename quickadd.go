@@ -0,0 +1,234 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// quickAddTask is the result of parsing a quick-add line such as
+// "Fix login redirect !high #auth #web due:fri @project-a"
+type quickAddTask struct {
+	Title    string    // Everything that isn't a special token
+	Priority string    // From !high, !med, !low
+	Tags     []string  // From #tag
+	DueDate  time.Time // From due:<date>
+	Dir      string    // From @name, empty means the default directory
+}
+
+// parseQuickAdd splits a quick-add line into its parts.
+// Recognised tokens (anywhere in the line):
+//
+//	!high !medium !low  (or !h/!hi, !m/!med, !l/!lo)  priority
+//	#tag                                              tag, may repeat
+//	due:<date>                                        due date, see parseDueDate
+//	@name                                             target directory
+//
+// Every other word becomes part of the title.
+func parseQuickAdd(line string, now time.Time) (quickAddTask, error) {
+	var task quickAddTask
+	var titleWords []string
+
+	for _, word := range strings.Fields(line) {
+		lower := strings.ToLower(word)
+
+		switch {
+		case len(word) > 1 && word[0] == '!':
+			priority, ok := parsePriorityToken(lower[1:])
+			if !ok {
+				return task, fmt.Errorf("unknown priority %q (use !high, !medium or !low)", word)
+			}
+			task.Priority = priority
+
+		case len(word) > 1 && word[0] == '#':
+			tag := word[1:]
+			if !containsFold(task.Tags, tag) {
+				task.Tags = append(task.Tags, tag)
+			}
+
+		case strings.HasPrefix(lower, "due:"):
+			due, err := parseDueDate(word[len("due:"):], now)
+			if err != nil {
+				return task, err
+			}
+			task.DueDate = due
+
+		case len(word) > 1 && word[0] == '@':
+			task.Dir = word[1:]
+
+		default:
+			titleWords = append(titleWords, word)
+		}
+	}
+
+	task.Title = strings.Join(titleWords, " ")
+	if task.Title == "" {
+		return task, fmt.Errorf("a task needs a title")
+	}
+
+	return task, nil
+}
+
+// parsePriorityToken maps the text after "!" to a priority value
+func parsePriorityToken(s string) (string, bool) {
+	switch s {
+	case "high", "hi", "h":
+		return "high", true
+	case "medium", "med", "m":
+		return "medium", true
+	case "low", "lo", "l":
+		return "low", true
+	default:
+		return "", false
+	}
+}
+
+// parseDueDate understands:
+//
+//	today, tomorrow (or tom)
+//	weekday names (mon, monday, ...) - the next such day, today included
+//	relative offsets: 3d, 2w (or +3d, +2w)
+//	absolute dates: 2006-01-02
+//
+// The result is midnight local time on that day.
+func parseDueDate(s string, now time.Time) (time.Time, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	lower := strings.ToLower(s)
+
+	switch lower {
+	case "":
+		return time.Time{}, fmt.Errorf("due: needs a date")
+	case "today":
+		return today, nil
+	case "tomorrow", "tom":
+		return today.AddDate(0, 0, 1), nil
+	}
+
+	// Weekday names, full or abbreviated to three letters
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		name := strings.ToLower(d.String())
+		if lower == name || lower == name[:3] {
+			offset := (int(d) - int(today.Weekday()) + 7) % 7
+			return today.AddDate(0, 0, offset), nil
+		}
+	}
+
+	// Relative offsets in days or weeks
+	rel := strings.TrimPrefix(lower, "+")
+	if len(rel) > 1 {
+		if n, err := strconv.Atoi(rel[:len(rel)-1]); err == nil && n >= 0 {
+			switch rel[len(rel)-1] {
+			case 'd':
+				return today.AddDate(0, 0, n), nil
+			case 'w':
+				return today.AddDate(0, 0, 7*n), nil
+			}
+		}
+	}
+
+	// Absolute date
+	if t, err := time.ParseInLocation("2006-01-02", s, now.Location()); err == nil {
+		return t, nil
+	}
+
+	return time.Time{}, fmt.Errorf("can't understand due date %q", s)
+}
+
+// resolveTaskDir finds the configured directory referred to by an @name.
+// The name may be the configured path itself, or any single component
+// of it, so "@project-a" matches "~/Projects/project-a/tasks".
+func resolveTaskDir(name string, dirs []string) (string, error) {
+	// Exact match on the configured path first
+	for _, dir := range dirs {
+		if dir == name {
+			return dir, nil
+		}
+	}
+
+	// Then any single path component
+	var matches []string
+	for _, dir := range dirs {
+		parts := strings.Split(filepath.ToSlash(dir), "/")
+		for _, part := range parts {
+			if strings.EqualFold(part, name) {
+				matches = append(matches, dir)
+				break
+			}
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("no configured directory matches @%s", name)
+	case 1:
+		return matches[0], nil
+	default:
+		return "", fmt.Errorf("@%s is ambiguous: %s", name, strings.Join(matches, ", "))
+	}
+}
+
+// toMetadata converts the parsed line into frontmatter for a new task
+func (q quickAddTask) toMetadata(defaultStatus string, now time.Time) TaskMetadata {
+	return TaskMetadata{
+		ID:       newTaskID(),
+		Title:    q.Title,
+		Status:   defaultStatus,
+		Priority: q.Priority,
+		DueDate:  q.DueDate,
+		Tags:     q.Tags,
+		Created:  now.Truncate(time.Second),
+	}
+}
+
+// quickAdd parses a quick-add line and writes the task straight to disk,
+// without opening an editor. The task goes into defaultDir unless the
+// line names another with @name, which can also be a project's code or
+// name. Tasks in a project get its default tags. The filename is built
+// from pattern. It returns the path of the new file.
+func quickAdd(line string, dirs []string, projects []ProjectConfig, defaultDir, defaultStatus, pattern string) (string, error) {
+	now := time.Now()
+
+	task, err := parseQuickAdd(line, now)
+	if err != nil {
+		return "", err
+	}
+
+	// Pick the target directory (the default unless @name was given)
+	dir := defaultDir
+	if project, ok := findProject(projects, task.Dir); ok && project.Path != "" {
+		dir = project.Path
+	} else if task.Dir != "" {
+		dir, err = resolveTaskDir(task.Dir, dirs)
+		if err != nil {
+			return "", err
+		}
+	}
+	if project, ok := projectForDir(projects, dir); ok {
+		task.Tags = mergeTags(task.Tags, project.DefaultTags)
+	}
+
+	expandedDir, err := expandPath(dir)
+	if err != nil {
+		return "", err
+	}
+
+	meta := task.toMetadata(defaultStatus, now)
+	content, err := renderTaskFile(meta, "# "+task.Title+"\n")
+	if err != nil {
+		return "", err
+	}
+
+	return writeNewTaskFile(expandedDir, renderFilename(pattern, meta, now), content)
+}
+
+// containsFold reports whether list contains s, ignoring case
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseDueDate(t *testing.T) {
+	now := time.Date(2025, 1, 8, 15, 30, 0, 0, time.Local) // A Wednesday
+	for _, test := range []struct {
+		value string
+		want  string // Date, or "" for an error
+	}{
+		{"today", "2025-01-08"},
+		{"Tomorrow", "2025-01-09"},
+		{"tom", "2025-01-09"},
+		{"wed", "2025-01-08"}, // Today counts
+		{"thursday", "2025-01-09"},
+		{"Fri", "2025-01-10"},
+		{"sun", "2025-01-12"},
+		{"mon", "2025-01-13"}, // Wraps around to next week
+		{"tue", "2025-01-14"},
+		{"0d", "2025-01-08"},
+		{"3d", "2025-01-11"},
+		{"+30d", "2025-02-07"},
+		{"2w", "2025-01-22"},
+		{"+1W", "2025-01-15"},
+		{"2025-03-01", "2025-03-01"},
+		{"", ""},
+		{"d", ""},
+		{"-3d", ""},
+		{"3m", ""},
+		{"2025-13-01", ""},
+		{"someday", ""},
+	} {
+		got, err := parseDueDate(test.value, now)
+		switch {
+		case test.want == "" && err == nil:
+			t.Errorf("parseDueDate(%q) = %s, want an error", test.value, got)
+		case test.want != "" && err != nil:
+			t.Errorf("parseDueDate(%q): %v", test.value, err)
+		case test.want != "" && (got.Format("2006-01-02") != test.want || !isMidnight(got)):
+			t.Errorf("parseDueDate(%q) = %s, want midnight on %s", test.value, got, test.want)
+		}
+	}
+}
+
+func TestParseQuickAdd(t *testing.T) {
+	now := time.Date(2025, 1, 8, 15, 30, 0, 0, time.Local)
+	for _, test := range []struct {
+		line string
+		want quickAddTask
+		err  string // Part of the expected error
+	}{
+		{
+			line: "Fix login redirect !high #auth #web due:fri @project-a",
+			want: quickAddTask{Title: "Fix login redirect", Priority: "high", Tags: []string{"auth", "web"}, DueDate: time.Date(2025, 1, 10, 0, 0, 0, 0, time.Local), Dir: "project-a"},
+		},
+		{
+			line: "!lo #Docs Write the docs #docs due:2w",
+			want: quickAddTask{Title: "Write the docs", Priority: "low", Tags: []string{"Docs"}, DueDate: time.Date(2025, 1, 22, 0, 0, 0, 0, time.Local)},
+		},
+		{
+			// Lone symbols and emails stay in the title
+			line: "Email bob@example.com ! # @ about it !M",
+			want: quickAddTask{Title: "Email bob@example.com ! # @ about it", Priority: "medium"},
+		},
+		{line: "Ship it !urgent", err: "unknown priority"},
+		{line: "Ship it due:someday", err: "can't understand due date"},
+		{line: "Ship it due:", err: "needs a date"},
+		{line: "!high #tag due:today", err: "needs a title"},
+	} {
+		got, err := parseQuickAdd(test.line, now)
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("parseQuickAdd(%q) error = %v, want %q", test.line, err, test.err)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("parseQuickAdd(%q) = %+v, %v, want %+v", test.line, got, err, test.want)
+		}
+	}
+}
+
+func TestResolveTaskDir(t *testing.T) {
+	dirs := []string{"~/Projects/project-a/tasks", "~/Projects/project-b/tasks", "~/notes"}
+	for _, test := range []struct{ name, want string }{
+		{"~/notes", "~/notes"},
+		{"project-a", "~/Projects/project-a/tasks"},
+		{"NOTES", "~/notes"},
+		{"tasks", ""}, // Ambiguous
+		{"project-c", ""},
+	} {
+		got, err := resolveTaskDir(test.name, dirs)
+		if got != test.want || (err == nil) != (test.want != "") {
+			t.Errorf("resolveTaskDir(%q) = %q, %v, want %q", test.name, got, err, test.want)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// appState holds small bits of state remembered between runs.
+// Unlike the config, it's written by the app and never edited by hand.
+type appState struct {
+	LastDir string       `json:"last_dir,omitempty"` // Directory last chosen for a new task
+	Timer   *activeTimer `json:"timer,omitempty"`    // The running timer, if any
+
+	// Lines of each synced todo.txt file as of its last sync, by file
+	// path and then by task ID
+	TodoTxt map[string]map[string]string `json:"todotxt,omitempty"`
+
+	// Commits each linked repository's branches and tags pointed to when
+	// it was last checked for task IDs, by repository path
+	CommitTips map[string][]string `json:"commit_tips,omitempty"`
+}
+
+// getStatePath returns the path to the state file
+func getStatePath() (string, error) {
+	var stateDir string
+
+	// XDG state directory on Unix-like systems (macOS and Linux),
+	// the standard config location on Windows
+	if runtime.GOOS == "windows" {
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return "", fmt.Errorf("couldn't get config directory: %w", err)
+		}
+		stateDir = dir
+	} else if xdg := os.Getenv("XDG_STATE_HOME"); xdg != "" {
+		stateDir = xdg
+	} else {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("couldn't get home directory: %w", err)
+		}
+		stateDir = filepath.Join(homeDir, ".local", "state")
+	}
+
+	return filepath.Join(stateDir, "taskmanager", "state.json"), nil
+}
+
+// loadState reads the state file. A missing or unreadable file just
+// means nothing has been remembered yet.
+func loadState() appState {
+	var state appState
+
+	statePath, err := getStatePath()
+	if err != nil {
+		return state
+	}
+
+	data, err := os.ReadFile(statePath)
+	if err != nil {
+		return state
+	}
+
+	if err := json.Unmarshal(data, &state); err != nil {
+		return appState{}
+	}
+	return state
+}
+
+// saveState writes the state file
+func saveState(state appState) error {
+	statePath, err := getStatePath()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(statePath), 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %w", err)
+	}
+
+	if err := os.WriteFile(statePath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+	return nil
+}
+
+// rememberLastDir records the directory chosen for a new task
+func rememberLastDir(dir string) error {
+	state := loadState()
+	state.LastDir = dir
+	return saveState(state)
+}
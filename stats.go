@@ -0,0 +1,297 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// statsWeeks is how many weeks of throughput the stats cover
+const statsWeeks = 8
+
+// sparkBlocks draw sparklines, from lowest to highest
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// taskStats is an overview of the workload, as shown in the stats view
+// and printed or exported by the report command
+type taskStats struct {
+	Generated    time.Time         `json:"generated"`
+	Total        int               `json:"total"`
+	Open         int               `json:"open"`
+	Overdue      int               `json:"overdue"`
+	ByStatus     map[string]int    `json:"by_status"`
+	ByPriority   map[string]int    `json:"by_priority"`
+	ByTag        map[string]int    `json:"by_tag"`
+	ByDir        map[string]int    `json:"by_dir"`
+	Weeks        []weekStats       `json:"weeks"`
+	CycleTime    string            `json:"average_cycle_time,omitempty"`
+	LeadTime     string            `json:"average_lead_time,omitempty"`
+	TimeInStatus map[string]string `json:"average_time_in_status,omitempty"`
+}
+
+// weekStats is one week of throughput, plus the open tasks at its end
+type weekStats struct {
+	Start     string `json:"week"` // Monday, 2006-01-02
+	Created   int    `json:"created"`
+	Completed int    `json:"completed"`
+	Open      int    `json:"open"` // Tasks still open at the end of the week
+}
+
+// collectStats counts tasks by status, priority, tag and directory, and
+// works out weekly throughput for the last statsWeeks weeks
+func collectStats(tasks []taskFile, defaultStatus string, now time.Time) taskStats {
+	stats := taskStats{
+		Generated:  now.Truncate(time.Second),
+		Total:      len(tasks),
+		ByStatus:   make(map[string]int),
+		ByPriority: make(map[string]int),
+		ByTag:      make(map[string]int),
+		ByDir:      make(map[string]int),
+	}
+	today, _ := parseSince("today", now)
+
+	for _, task := range tasks {
+		meta := task.metadata
+		status := meta.Status
+		if status == "" {
+			status = defaultStatus
+		}
+		stats.ByStatus[status]++
+
+		priority := meta.Priority
+		if priority == "" {
+			priority = "none"
+		}
+		stats.ByPriority[priority]++
+
+		for _, tag := range meta.Tags {
+			stats.ByTag[tag]++
+		}
+		stats.ByDir[task.sourceDir]++
+
+		if !isDoneStatus(status) {
+			stats.Open++
+			if !meta.DueDate.IsZero() && meta.DueDate.Before(today) {
+				stats.Overdue++
+			}
+		}
+	}
+
+	// Weekly throughput, oldest week first
+	thisWeek, _ := parseSince("week", now)
+	for i := statsWeeks - 1; i >= 0; i-- {
+		start := thisWeek.AddDate(0, 0, -7*i)
+		end := start.AddDate(0, 0, 7)
+		week := weekStats{Start: start.Format("2006-01-02")}
+
+		for _, task := range tasks {
+			meta := task.metadata
+			if inRange(meta.Created, start, end) {
+				week.Created++
+			}
+			if inRange(meta.Completed, start, end) {
+				week.Completed++
+			}
+
+			// Open at the end of the week: created by then (tasks without a
+			// created date are assumed to have always existed) and not
+			// completed by then
+			createdBy := meta.Created.IsZero() || meta.Created.Before(end)
+			completedBy := !meta.Completed.IsZero() && meta.Completed.Before(end)
+			if meta.Completed.IsZero() && isDoneStatus(meta.Status) {
+				completedBy = true // Done before completion was tracked
+			}
+			if createdBy && !completedBy {
+				week.Open++
+			}
+		}
+		stats.Weeks = append(stats.Weeks, week)
+	}
+
+	if d, ok := averageCycleTime(tasks); ok {
+		stats.CycleTime = formatCycleTime(d)
+	}
+	metrics := collectStatusMetrics(tasks, now)
+	if metrics.LeadTimeTasks > 0 {
+		stats.LeadTime = formatCycleTime(metrics.AverageLeadTime)
+	}
+	if len(metrics.AverageInStatus) > 0 {
+		stats.TimeInStatus = make(map[string]string)
+		for status, d := range metrics.AverageInStatus {
+			stats.TimeInStatus[status] = formatCycleTime(d)
+		}
+	}
+
+	return stats
+}
+
+// inRange reports whether t is set and falls in [start, end)
+func inRange(t, start, end time.Time) bool {
+	return !t.IsZero() && !t.Before(start) && t.Before(end)
+}
+
+// sparkline draws values as a row of block characters
+func sparkline(values []int) string {
+	top := 0
+	for _, v := range values {
+		top = max(top, v)
+	}
+
+	var b strings.Builder
+	for _, v := range values {
+		if top == 0 {
+			b.WriteRune(sparkBlocks[0])
+			continue
+		}
+		b.WriteRune(sparkBlocks[v*(len(sparkBlocks)-1)/top])
+	}
+	return b.String()
+}
+
+// bar draws n as a horizontal bar scaled so that top fills width
+func bar(n, top, width int) string {
+	if top == 0 || n == 0 {
+		return ""
+	}
+	return strings.Repeat("█", max(n*width/top, 1))
+}
+
+// sortedCounts returns the keys of counts, largest count first
+func sortedCounts(counts map[string]int) []string {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+// weekValues picks one number out of each week
+func (s taskStats) weekValues(pick func(weekStats) int) []int {
+	values := make([]int, len(s.Weeks))
+	for i, week := range s.Weeks {
+		values[i] = pick(week)
+	}
+	return values
+}
+
+// formatText renders the stats for a terminal, with bar charts and
+// sparklines
+func (s taskStats) formatText() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Tasks: %d total, %d open, %d overdue\n", s.Total, s.Open, s.Overdue)
+	if s.CycleTime != "" {
+		fmt.Fprintf(&b, "Average cycle time: %s\n", s.CycleTime)
+	}
+	if s.LeadTime != "" {
+		fmt.Fprintf(&b, "Average lead time: %s\n", s.LeadTime)
+	}
+
+	sectionsOf := []struct {
+		name   string
+		counts map[string]int
+	}{
+		{"By status", s.ByStatus},
+		{"By priority", s.ByPriority},
+		{"By tag", s.ByTag},
+		{"By directory", s.ByDir},
+	}
+	for _, section := range sectionsOf {
+		if len(section.counts) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n%s\n", section.name)
+		keys := sortedCounts(section.counts)
+		top := section.counts[keys[0]]
+		for _, key := range keys {
+			fmt.Fprintf(&b, "  %-20s %4d %s\n", key, section.counts[key], bar(section.counts[key], top, 30))
+		}
+	}
+
+	fmt.Fprintf(&b, "\nLast %d weeks (oldest first)\n", len(s.Weeks))
+	fmt.Fprintf(&b, "  Created    %s\n", sparkline(s.weekValues(func(w weekStats) int { return w.Created })))
+	fmt.Fprintf(&b, "  Completed  %s\n", sparkline(s.weekValues(func(w weekStats) int { return w.Completed })))
+	fmt.Fprintf(&b, "  Open       %s (burndown)\n", sparkline(s.weekValues(func(w weekStats) int { return w.Open })))
+	for _, week := range s.Weeks {
+		fmt.Fprintf(&b, "  %s  +%-3d -%-3d open %d\n", week.Start, week.Created, week.Completed, week.Open)
+	}
+
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// formatMarkdown renders the stats as a markdown document
+func (s taskStats) formatMarkdown() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# Task Report\n\n")
+	fmt.Fprintf(&b, "Generated %s\n\n", s.Generated.Format("2006-01-02 15:04"))
+	fmt.Fprintf(&b, "- Total: %d\n- Open: %d\n- Overdue: %d\n", s.Total, s.Open, s.Overdue)
+	if s.CycleTime != "" {
+		fmt.Fprintf(&b, "- Average cycle time: %s\n", s.CycleTime)
+	}
+	if s.LeadTime != "" {
+		fmt.Fprintf(&b, "- Average lead time: %s\n", s.LeadTime)
+	}
+
+	sectionsOf := []struct {
+		name, column string
+		counts       map[string]int
+	}{
+		{"By Status", "Status", s.ByStatus},
+		{"By Priority", "Priority", s.ByPriority},
+		{"By Tag", "Tag", s.ByTag},
+		{"By Directory", "Directory", s.ByDir},
+	}
+	for _, section := range sectionsOf {
+		if len(section.counts) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n## %s\n\n| %s | Tasks |\n|---|---:|\n", section.name, section.column)
+		for _, key := range sortedCounts(section.counts) {
+			fmt.Fprintf(&b, "| %s | %d |\n", key, section.counts[key])
+		}
+	}
+
+	if len(s.TimeInStatus) > 0 {
+		fmt.Fprintf(&b, "\n## Average Time in Status\n\n| Status | Time |\n|---|---:|\n")
+		for _, status := range sortedKeys(s.TimeInStatus) {
+			fmt.Fprintf(&b, "| %s | %s |\n", status, s.TimeInStatus[status])
+		}
+	}
+
+	fmt.Fprintf(&b, "\n## Weekly Throughput\n\n")
+	fmt.Fprintf(&b, "Open tasks: `%s`\n\n", sparkline(s.weekValues(func(w weekStats) int { return w.Open })))
+	fmt.Fprintf(&b, "| Week | Created | Completed | Open |\n|---|---:|---:|---:|\n")
+	for _, week := range s.Weeks {
+		fmt.Fprintf(&b, "| %s | %d | %d | %d |\n", week.Start, week.Created, week.Completed, week.Open)
+	}
+
+	return b.String()
+}
+
+// formatJSON renders the stats as indented JSON
+func (s taskStats) formatJSON() (string, error) {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode report: %w", err)
+	}
+	return string(data) + "\n", nil
+}
+
+// sortedKeys returns the keys of a string map in order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// statusCycle is the order the status key steps through
+var statusCycle = []string{"todo", "in-progress", "done"}
+
+// Completed filters for the list, as understood by parseSince
+var completedFilters = []string{"", "today", "week"}
+
+// nextStatus returns the status after current in statusCycle. Statuses
+// outside the cycle start again from the beginning.
+func nextStatus(current string) string {
+	for i, status := range statusCycle {
+		if status == current {
+			return statusCycle[(i+1)%len(statusCycle)]
+		}
+	}
+	if isInProgressStatus(current) {
+		return "done"
+	}
+	return statusCycle[0]
+}
+
+// setTaskStatus changes a task's status, recording the change in its
+// status_history (with an optional note) and keeping its completed field
+// in step: it's set when the task enters a done status and cleared when
+// the task is reopened
+func setTaskStatus(taskPath, status, note string, now time.Time) error {
+	return updateFrontmatter(taskPath, func(fields *yaml.MapSlice) {
+		oldStatus, _ := getField(*fields, "status")
+		old, _ := oldStatus.(string)
+		setField(fields, "status", status)
+		updateStatusFields(fields, old, status, note, now)
+	})
+}
+
+// updateStatusFields records a status change in the history and sets or
+// clears the completed field to match
+func updateStatusFields(fields *yaml.MapSlice, oldStatus, newStatus, note string, now time.Time) {
+	if oldStatus == newStatus {
+		return
+	}
+	appendStatusChange(fields, statusChange{From: oldStatus, Status: newStatus, At: now, Note: note})
+
+	switch {
+	case isDoneStatus(newStatus) && !isDoneStatus(oldStatus):
+		setField(fields, "completed", now.Truncate(time.Second))
+	case !isDoneStatus(newStatus):
+		deleteField(fields, "completed")
+	}
+}
+
+// recordStatusChange records a status change made outside the app's
+// status keys (e.g. in the editor) in the task's history and completed
+// field
+func recordStatusChange(taskPath, oldStatus, newStatus string, now time.Time) error {
+	if oldStatus == newStatus {
+		return nil
+	}
+	return updateFrontmatter(taskPath, func(fields *yaml.MapSlice) {
+		updateStatusFields(fields, oldStatus, newStatus, "", now)
+	})
+}
+
+// cycleTime returns how long a completed task took from created to
+// completed
+func (meta TaskMetadata) cycleTime() (time.Duration, bool) {
+	if meta.Created.IsZero() || meta.Completed.IsZero() || meta.Completed.Before(meta.Created) {
+		return 0, false
+	}
+	return meta.Completed.Sub(meta.Created), true
+}
+
+// completedSince returns the tasks completed at or after since
+func completedSince(tasks []taskFile, since time.Time) []taskFile {
+	var completed []taskFile
+	for _, task := range tasks {
+		if !task.metadata.Completed.IsZero() && !task.metadata.Completed.Before(since) {
+			completed = append(completed, task)
+		}
+	}
+	return completed
+}
+
+// averageCycleTime returns the mean cycle time of the tasks that have one
+func averageCycleTime(tasks []taskFile) (time.Duration, bool) {
+	var total time.Duration
+	count := 0
+	for _, task := range tasks {
+		if d, ok := task.metadata.cycleTime(); ok {
+			total += d
+			count++
+		}
+	}
+	if count == 0 {
+		return 0, false
+	}
+	return total / time.Duration(count), true
+}
+
+// formatCycleTime formats a cycle time in days and hours, e.g. "3d 4h"
+func formatCycleTime(d time.Duration) string {
+	days := int(d.Hours()) / 24
+	hours := int(d.Hours()) % 24
+	if days == 0 {
+		return formatDuration(d)
+	}
+	return fmt.Sprintf("%dd %dh", days, hours)
+}
+
+// completedFilterLabel describes a completed filter for the footer
+func completedFilterLabel(filter string) string {
+	if filter == "week" {
+		return "this week"
+	}
+	return filter
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// statusChange is one status transition, stored in the task's
+// status_history frontmatter field
+type statusChange struct {
+	Status string    `yaml:"status"`         // The new status
+	From   string    `yaml:"from,omitempty"` // The status before the change
+	At     time.Time `yaml:"at"`             // When the change was made
+	Note   string    `yaml:"note,omitempty"` // Why, if given
+}
+
+// appendStatusChange adds a change to the status_history field
+func appendStatusChange(fields *yaml.MapSlice, change statusChange) {
+	var history []interface{}
+	if existing, ok := getField(*fields, "status_history"); ok {
+		history, _ = existing.([]interface{})
+	}
+
+	entry := yaml.MapSlice{{Key: "status", Value: change.Status}}
+	if change.From != "" {
+		entry = append(entry, yaml.MapItem{Key: "from", Value: change.From})
+	}
+	entry = append(entry, yaml.MapItem{Key: "at", Value: change.At.Truncate(time.Second)})
+	if change.Note != "" {
+		entry = append(entry, yaml.MapItem{Key: "note", Value: change.Note})
+	}
+
+	setField(fields, "status_history", append(history, entry))
+}
+
+// timeInStatus works out how long a task has spent in each status, from
+// its created time (or first recorded change) up to now. Time before the
+// first recorded change counts towards that change's from status.
+func (meta TaskMetadata) timeInStatus(now time.Time) map[string]time.Duration {
+	durations := make(map[string]time.Duration)
+	history := meta.StatusHistory
+	if len(history) == 0 {
+		return durations
+	}
+
+	// The status before the first change, from when the task was created
+	if first := history[0]; first.From != "" && !meta.Created.IsZero() && first.At.After(meta.Created) {
+		durations[first.From] += first.At.Sub(meta.Created)
+	}
+
+	for i, change := range history {
+		end := now
+		if i+1 < len(history) {
+			end = history[i+1].At
+		} else if isDoneStatus(change.Status) {
+			// Finished tasks stop accumulating time
+			continue
+		}
+		if end.After(change.At) {
+			durations[change.Status] += end.Sub(change.At)
+		}
+	}
+	return durations
+}
+
+// leadTime returns how long a task took from being created until it was
+// last moved to a done status, according to its history
+func (meta TaskMetadata) leadTime() (time.Duration, bool) {
+	if meta.Created.IsZero() {
+		return 0, false
+	}
+	for i := len(meta.StatusHistory) - 1; i >= 0; i-- {
+		change := meta.StatusHistory[i]
+		if isDoneStatus(change.Status) && change.At.After(meta.Created) {
+			return change.At.Sub(meta.Created), true
+		}
+	}
+	return 0, false
+}
+
+// reopenCount returns how many times a task was moved out of a done status
+func (meta TaskMetadata) reopenCount() int {
+	count := 0
+	for _, change := range meta.StatusHistory {
+		if isDoneStatus(change.From) && !isDoneStatus(change.Status) {
+			count++
+		}
+	}
+	return count
+}
+
+// statusMetrics summarises status history across tasks
+type statusMetrics struct {
+	AverageInStatus map[string]time.Duration // Mean time a task spends in each status
+	AverageLeadTime time.Duration            // Mean time from created to done
+	LeadTimeTasks   int                      // Tasks the lead time is averaged over
+	Reopened        int                      // Tasks reopened at least once
+}
+
+// collectStatusMetrics averages time-in-status and lead time over every
+// task that has a status history
+func collectStatusMetrics(tasks []taskFile, now time.Time) statusMetrics {
+	metrics := statusMetrics{AverageInStatus: make(map[string]time.Duration)}
+	totals := make(map[string]time.Duration)
+	counts := make(map[string]int)
+	var leadTotal time.Duration
+
+	for _, task := range tasks {
+		for status, d := range task.metadata.timeInStatus(now) {
+			totals[status] += d
+			counts[status]++
+		}
+		if d, ok := task.metadata.leadTime(); ok {
+			leadTotal += d
+			metrics.LeadTimeTasks++
+		}
+		if task.metadata.reopenCount() > 0 {
+			metrics.Reopened++
+		}
+	}
+
+	for status, total := range totals {
+		metrics.AverageInStatus[status] = total / time.Duration(counts[status])
+	}
+	if metrics.LeadTimeTasks > 0 {
+		metrics.AverageLeadTime = leadTotal / time.Duration(metrics.LeadTimeTasks)
+	}
+	return metrics
+}
+
+// statuses returns the statuses in the metrics, in statusCycle order
+// first and then alphabetically
+func (s statusMetrics) statuses() []string {
+	var names []string
+	for status := range s.AverageInStatus {
+		names = append(names, status)
+	}
+	rank := func(status string) int {
+		for i, known := range statusCycle {
+			if known == status {
+				return i
+			}
+		}
+		return len(statusCycle)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if rank(names[i]) != rank(names[j]) {
+			return rank(names[i]) < rank(names[j])
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+// formatStatusChange describes a change for the task view timeline
+func formatStatusChange(change statusChange) string {
+	line := change.At.Local().Format("2006-01-02 15:04") + "  "
+	if change.From != "" {
+		line += change.From + " → "
+	}
+	line += change.Status
+	if change.Note != "" {
+		line += fmt.Sprintf(" (%s)", change.Note)
+	}
+	return line
+}
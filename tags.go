@@ -0,0 +1,362 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"gopkg.in/yaml.v2"
+)
+
+// tagCount is a tag and the number of tasks that have it or any tag
+// below it. Tags are nested with /, e.g. work/client-a.
+type tagCount struct {
+	name     string // Full tag path
+	count    int    // Tasks with this tag or a descendant
+	depth    int    // Number of parent tags
+	children bool   // Whether other tags are nested below this one
+}
+
+// tagAncestors returns a tag followed by each of its parents, e.g.
+// work/client-a/web, work/client-a and work
+func tagAncestors(tag string) []string {
+	tags := []string{tag}
+	for i := len(tag) - 1; i > 0; i-- {
+		if tag[i] == '/' {
+			tags = append(tags, tag[:i])
+		}
+	}
+	return tags
+}
+
+// tagMatches reports whether tag is filter or nested below it, ignoring
+// case
+func tagMatches(tag, filter string) bool {
+	return strings.EqualFold(tag, filter) ||
+		(len(tag) > len(filter) && tag[len(filter)] == '/' && strings.EqualFold(tag[:len(filter)], filter))
+}
+
+// countTags lists every tag used by the tasks as a tree: parents first,
+// then their children alphabetically. Parents that no task uses directly
+// are included, and every count includes the tasks of nested tags. Tags
+// that differ only in case are counted together under the first
+// spelling seen.
+func countTags(tasks []taskFile) []tagCount {
+	spelling := map[string]string{}
+	counts := map[string]int{}
+	for _, task := range tasks {
+		seen := map[string]bool{}
+		for _, tag := range task.metadata.Tags {
+			for _, name := range tagAncestors(strings.Trim(tag, "/")) {
+				key := strings.ToLower(name)
+				if key == "" || seen[key] {
+					continue
+				}
+				seen[key] = true
+				if _, ok := spelling[key]; !ok {
+					spelling[key] = name
+				}
+				counts[key]++
+			}
+		}
+	}
+
+	// Sorting on the path segments keeps children right after their parent
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return strings.ReplaceAll(keys[i], "/", "\x00") < strings.ReplaceAll(keys[j], "/", "\x00")
+	})
+
+	var tags []tagCount
+	for i, key := range keys {
+		tags = append(tags, tagCount{
+			name:     spelling[key],
+			count:    counts[key],
+			depth:    strings.Count(key, "/"),
+			children: i+1 < len(keys) && strings.HasPrefix(keys[i+1], key+"/"),
+		})
+	}
+	return tags
+}
+
+// taggedWith returns the tasks with any of the tags or a tag nested
+// below one of them
+func taggedWith(tasks []taskFile, tags []string) []taskFile {
+	var tagged []taskFile
+	for _, task := range tasks {
+		if slices.ContainsFunc(task.metadata.Tags, func(tag string) bool {
+			return slices.ContainsFunc(tags, func(f string) bool { return tagMatches(tag, f) })
+		}) {
+			tagged = append(tagged, task)
+		}
+	}
+	return tagged
+}
+
+// tagChange is the planned rewrite of one task's tags
+type tagChange struct {
+	task   taskFile
+	before []string
+	after  []string
+}
+
+// planTagChange works out how each task's tags change when the from tags
+// are replaced by to, or removed if to is empty. Nested tags move along
+// with their parent, so renaming work to job turns work/web into job/web.
+// Tags that end up duplicated are only kept once, so renaming onto an
+// existing tag merges the two. Tasks that don't change, such as those
+// already spelling a tag the way it's renamed to, are left out.
+func planTagChange(tasks []taskFile, from []string, to string) []tagChange {
+	var changes []tagChange
+	for _, task := range tasks {
+		var after []string
+		seen := map[string]bool{}
+		matched := false
+		for _, tag := range task.metadata.Tags {
+			for _, f := range from {
+				if tagMatches(tag, f) {
+					matched = true
+					if to == "" {
+						tag = ""
+					} else {
+						tag = to + tag[len(f):]
+					}
+					break
+				}
+			}
+			if key := strings.ToLower(tag); tag != "" && !seen[key] {
+				seen[key] = true
+				after = append(after, tag)
+			}
+		}
+		if matched && !slices.Equal(after, task.metadata.Tags) {
+			changes = append(changes, tagChange{task: task, before: task.metadata.Tags, after: after})
+		}
+	}
+	return changes
+}
+
+// applyTagChanges writes the new tags into each task's frontmatter and
+// returns the paths that were changed
+func applyTagChanges(changes []tagChange) ([]string, error) {
+	var paths []string
+	for _, change := range changes {
+		err := updateFrontmatter(change.task.fullPath, func(fields *yaml.MapSlice) {
+			if len(change.after) == 0 {
+				deleteField(fields, "tags")
+			} else {
+				setField(fields, "tags", change.after)
+			}
+		})
+		if err != nil {
+			return paths, fmt.Errorf("failed to update tags in %s: %w", change.task.name, err)
+		}
+		paths = append(paths, change.task.fullPath)
+	}
+	return paths, nil
+}
+
+// validTagName checks a new tag name
+func validTagName(name string) error {
+	if name == "" {
+		return fmt.Errorf("tag name can't be empty")
+	}
+	if strings.ContainsAny(name, " \t,#") {
+		return fmt.Errorf("tag %q can't contain spaces, commas or #", name)
+	}
+	if strings.HasPrefix(name, "/") || strings.HasSuffix(name, "/") || strings.Contains(name, "//") {
+		return fmt.Errorf("tag %q has an empty level", name)
+	}
+	return nil
+}
+
+// formatTags renders tags as "#a #b", or "(none)"
+func formatTags(tags []string) string {
+	if len(tags) == 0 {
+		return "(none)"
+	}
+	var out []string
+	for _, tag := range tags {
+		out = append(out, "#"+tag)
+	}
+	return strings.Join(out, " ")
+}
+
+// describeTagChange summarizes a rename, merge or delete for messages
+// and commits
+func describeTagChange(action string, from []string, to string, count int) string {
+	plural := "s"
+	if count == 1 {
+		plural = ""
+	}
+	switch action {
+	case "delete":
+		return fmt.Sprintf("delete tag %s from %d task%s", formatTags(from), count, plural)
+	case "merge":
+		return fmt.Sprintf("merge tag %s into #%s in %d task%s", formatTags(from), to, count, plural)
+	}
+	return fmt.Sprintf("rename tag %s to #%s in %d task%s", formatTags(from), to, count, plural)
+}
+
+// openTags shows every tag with its task count
+func (m model) openTags() model {
+	m.tagCounts = countTags(m.tasks)
+	m.mode = tagsMode
+	m.tagAction = ""
+	m.tagInput = ""
+	m.tagPreview = nil
+	m.tagCursor = 0
+	for i, tag := range m.tagRows() {
+		if strings.EqualFold(tag.name, m.tagFilter) {
+			m.tagCursor = i
+		}
+	}
+	return m
+}
+
+// tagRows returns the tags shown in the tags view, leaving out those
+// inside a collapsed parent
+func (m model) tagRows() []tagCount {
+	var rows []tagCount
+	for _, tag := range m.tagCounts {
+		hidden := false
+		for _, parent := range tagAncestors(tag.name)[1:] {
+			if m.tagCollapsed[strings.ToLower(parent)] {
+				hidden = true
+			}
+		}
+		if !hidden {
+			rows = append(rows, tag)
+		}
+	}
+	return rows
+}
+
+// selectedTag returns the tag under the cursor in the tags view
+func (m model) selectedTag() (tagCount, bool) {
+	rows := m.tagRows()
+	if m.tagCursor < len(rows) {
+		return rows[m.tagCursor], true
+	}
+	return tagCount{}, false
+}
+
+// toggleTag collapses or expands the nested tags of the selected tag
+func (m model) toggleTag() model {
+	tag, ok := m.selectedTag()
+	if !ok || !tag.children {
+		return m
+	}
+	if m.tagCollapsed == nil {
+		m.tagCollapsed = map[string]bool{}
+	}
+	key := strings.ToLower(tag.name)
+	m.tagCollapsed[key] = !m.tagCollapsed[key]
+	return m
+}
+
+// previewTagAction works out the changes for the pending rename, merge
+// or delete of the selected tag so they can be confirmed
+func (m model) previewTagAction() model {
+	tag, ok := m.selectedTag()
+	if !ok {
+		return m
+	}
+	from := tag.name
+	to := strings.TrimPrefix(strings.TrimSpace(m.tagInput), "#")
+
+	switch m.tagAction {
+	case "rename", "merge":
+		if err := validTagName(to); err != nil {
+			m.message = err.Error()
+			return m
+		}
+		// Renaming to another case of the same tag normalizes the tasks
+		// that spell it differently
+		if strings.EqualFold(from, to) {
+			if len(planTagChange(m.tasks, []string{from}, to)) == 0 {
+				m.message = fmt.Sprintf("Every task already uses #%s", to)
+				return m
+			}
+			m.tagAction = "rename"
+		}
+		exists := false
+		for _, tag := range m.tagCounts {
+			if strings.EqualFold(tag.name, to) && !strings.EqualFold(tag.name, from) {
+				exists = true
+			}
+		}
+		if m.tagAction == "merge" && !exists {
+			m.message = fmt.Sprintf("No tag #%s to merge into", to)
+			return m
+		}
+		if exists {
+			m.tagAction = "merge"
+		}
+	case "delete":
+		to = ""
+	}
+
+	m.tagPreview = planTagChange(m.tasks, []string{from}, to)
+	return m
+}
+
+// applyTagAction writes the previewed tag changes and refreshes the list
+func (m model) applyTagAction() (tea.Model, tea.Cmd) {
+	tag, _ := m.selectedTag()
+	from := tag.name
+	to := strings.TrimPrefix(strings.TrimSpace(m.tagInput), "#")
+	summary := describeTagChange(m.tagAction, []string{from}, to, len(m.tagPreview))
+
+	paths, err := applyTagChanges(m.tagPreview)
+	for _, path := range paths {
+		m.refreshTask(path)
+	}
+	if err != nil {
+		m.message = err.Error()
+	} else if err := m.gitConfig.autoCommit("tags", TaskMetadata{Title: summary}, "", paths...); err != nil {
+		m.message = err.Error()
+	} else {
+		m.message = strings.ToUpper(summary[:1]) + summary[1:]
+	}
+
+	if tagMatches(m.tagFilter, from) {
+		if m.tagAction == "delete" {
+			m.tagFilter = ""
+		} else {
+			m.tagFilter = to + m.tagFilter[len(from):]
+		}
+	}
+	message := m.message
+	m = m.openTags()
+	m.message = message
+	return m, nil
+}
+
+// tagStyle returns the style for a tag: the color configured for it or
+// for its nearest parent, or the dim style
+func (c *DisplayConfig) tagStyle(tag string) lipgloss.Style {
+	for _, name := range tagAncestors(tag) {
+		for configured, color := range c.TagColors {
+			if strings.EqualFold(configured, name) {
+				return lipgloss.NewStyle().Foreground(lipgloss.Color(color))
+			}
+		}
+	}
+	return dimStyle
+}
+
+// renderTags renders tags as "#a #b", each in its color
+func (c *DisplayConfig) renderTags(tags []string) string {
+	var out []string
+	for _, tag := range tags {
+		out = append(out, c.tagStyle(tag).Render("#"+tag))
+	}
+	return strings.Join(out, " ")
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// taggedTasks returns one task per tag list, named after its position
+func taggedTasks(tags ...[]string) []taskFile {
+	var tasks []taskFile
+	for i, t := range tags {
+		tasks = append(tasks, taskFile{name: string(rune('a'+i)) + ".md", metadata: TaskMetadata{Tags: t}})
+	}
+	return tasks
+}
+
+func TestPlanTagChange(t *testing.T) {
+	for _, test := range []struct {
+		name  string
+		tags  [][]string
+		from  string
+		to    string
+		after map[string][]string // New tags by task name; others are unchanged
+	}{
+		{
+			name:  "rename with nested tags",
+			tags:  [][]string{{"work", "urgent"}, {"work/web"}, {"home"}},
+			from:  "work",
+			to:    "job",
+			after: map[string][]string{"a.md": {"job", "urgent"}, "b.md": {"job/web"}},
+		},
+		{
+			name:  "normalize case",
+			tags:  [][]string{{"backend"}, {"Backend", "api"}, {"BACKEND/db"}},
+			from:  "backend",
+			to:    "backend",
+			after: map[string][]string{"b.md": {"backend", "api"}, "c.md": {"backend/db"}},
+		},
+		{
+			name:  "merge drops duplicates",
+			tags:  [][]string{{"bug", "defect"}, {"defect"}},
+			from:  "defect",
+			to:    "bug",
+			after: map[string][]string{"a.md": {"bug"}, "b.md": {"bug"}},
+		},
+		{
+			name:  "delete",
+			tags:  [][]string{{"old"}, {"old/x", "keep"}, {"older"}},
+			from:  "old",
+			after: map[string][]string{"a.md": nil, "b.md": {"keep"}},
+		},
+		{
+			name:  "unrelated duplicates are left alone",
+			tags:  [][]string{{"x", "X"}},
+			from:  "y",
+			to:    "z",
+			after: map[string][]string{},
+		},
+	} {
+		changes := planTagChange(taggedTasks(test.tags...), []string{test.from}, test.to)
+		got := map[string][]string{}
+		for _, change := range changes {
+			got[change.task.name] = change.after
+		}
+		if !reflect.DeepEqual(got, test.after) {
+			t.Errorf("%s: changes = %v, want %v", test.name, got, test.after)
+		}
+	}
+}
+
+func TestPreviewTagActionNormalizesCase(t *testing.T) {
+	m := model{tasks: taggedTasks([]string{"backend"}, []string{"Backend"})}
+	m = m.openTags()
+	m.tagAction = "rename"
+
+	// Whichever spelling is listed, renaming to it fixes the other
+	for _, to := range []string{"backend", "Backend"} {
+		m.tagInput = to
+		m.tagPreview = nil
+		m.message = ""
+		m = m.previewTagAction()
+		if len(m.tagPreview) != 1 || m.message != "" {
+			t.Errorf("rename to %s: %d changes, message %q", to, len(m.tagPreview), m.message)
+		}
+	}
+
+	m = model{tasks: taggedTasks([]string{"backend"}, []string{"backend"})}
+	m = m.openTags()
+	m.tagAction, m.tagInput = "rename", "backend"
+	if m = m.previewTagAction(); m.tagPreview != nil || m.message == "" {
+		t.Errorf("a rename that changes nothing was previewed: %v", m.tagPreview)
+	}
+}
@@ -0,0 +1,349 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// taskwarriorSource is the source field value of tasks imported from
+// Taskwarrior
+const taskwarriorSource = "taskwarrior"
+
+// twTimeFormat is how Taskwarrior writes dates in its JSON
+const twTimeFormat = "20060102T150405Z"
+
+// twTask is a task in Taskwarrior's JSON format (task export / import)
+type twTask struct {
+	UUID        string         `json:"uuid"`
+	Description string         `json:"description"`
+	Status      string         `json:"status"` // pending, completed, deleted, waiting or recurring
+	Entry       string         `json:"entry,omitempty"`
+	Start       string         `json:"start,omitempty"` // Set while the task is active
+	End         string         `json:"end,omitempty"`
+	Due         string         `json:"due,omitempty"`
+	Priority    string         `json:"priority,omitempty"` // H, M or L
+	Project     string         `json:"project,omitempty"`
+	Tags        []string       `json:"tags,omitempty"`
+	Annotations []twAnnotation `json:"annotations,omitempty"`
+	Depends     twDepends      `json:"depends,omitempty"`
+
+	// Our ID, kept by Taskwarrior as an orphaned UDA so exported tasks
+	// can be matched again when they come back
+	TaskManagerID string `json:"taskmanager_id,omitempty"`
+}
+
+// twAnnotation is a timestamped note on a Taskwarrior task
+type twAnnotation struct {
+	Entry       string `json:"entry"`
+	Description string `json:"description"`
+}
+
+// twDepends is the UUIDs a task depends on. Taskwarrior 2.6 and later
+// write a list; older versions a comma-separated string.
+type twDepends []string
+
+// UnmarshalJSON accepts either form of depends
+func (d *twDepends) UnmarshalJSON(data []byte) error {
+	var list []string
+	if err := json.Unmarshal(data, &list); err == nil {
+		*d = list
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("depends must be a list or a string: %w", err)
+	}
+	for _, uuid := range strings.Split(s, ",") {
+		if uuid = strings.TrimSpace(uuid); uuid != "" {
+			*d = append(*d, uuid)
+		}
+	}
+	return nil
+}
+
+// annotationLine matches an annotation in a task's Annotations section
+var annotationLine = regexp.MustCompile(`^- (\d{4}-\d{2}-\d{2} \d{2}:\d{2}) (.*)$`)
+
+// annotationsHeading starts the body section annotations are kept in
+const annotationsHeading = "## Annotations"
+
+// twUUID returns the UUID a task is exported with: the original UUID for
+// tasks imported from Taskwarrior, otherwise one derived from the task's
+// ID (or filename), so repeated exports agree
+func twUUID(task taskFile) string {
+	fields := task.metadata.Fields
+	if fields["source"] == taskwarriorSource && fields["source_id"] != "" {
+		return fields["source_id"]
+	}
+	key := task.metadata.ID
+	if key == "" {
+		key = "file:" + task.name
+	}
+	sum := sha1.Sum([]byte("taskmanager:" + key))
+	sum[6] = sum[6]&0x0f | 0x50 // Version 5 (name-based, SHA-1)
+	sum[8] = sum[8]&0x3f | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
+
+// importTaskwarrior reads the output of task export. It takes the JSON
+// array written by current versions or one object per line as written by
+// old ones. Recurring templates are skipped (their instances are
+// imported). Dependencies are resolved to task IDs using the existing
+// tasks and the other imported ones.
+func importTaskwarrior(data []byte, tasks []taskFile, projects []ProjectConfig, defaultStatus string) ([]importedTask, error) {
+	var exported []twTask
+	if trimmed := bytes.TrimSpace(data); bytes.HasPrefix(trimmed, []byte("[")) {
+		if err := json.Unmarshal(trimmed, &exported); err != nil {
+			return nil, fmt.Errorf("invalid Taskwarrior JSON: %w", err)
+		}
+	} else {
+		scanner := bufio.NewScanner(bytes.NewReader(trimmed))
+		scanner.Buffer(nil, 1<<20)
+		for n := 1; scanner.Scan(); n++ {
+			line := strings.TrimSuffix(strings.TrimSpace(scanner.Text()), ",")
+			if line == "" {
+				continue
+			}
+			var task twTask
+			if err := json.Unmarshal([]byte(line), &task); err != nil {
+				return nil, fmt.Errorf("invalid Taskwarrior JSON on line %d: %w", n, err)
+			}
+			exported = append(exported, task)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Our ID for every UUID, to turn dependencies into task IDs, and the
+	// UUIDs exported from here, which aren't given a source
+	ids := map[string]string{}
+	ours := map[string]bool{}
+	for _, task := range tasks {
+		uuid := twUUID(task)
+		ids[uuid] = task.metadata.ID
+		ours[uuid] = task.metadata.Fields["source"] != taskwarriorSource
+	}
+
+	var items []importedTask
+	var depends []twDepends
+	for _, tw := range exported {
+		if tw.Status == "recurring" {
+			continue
+		}
+		if tw.UUID == "" {
+			return nil, fmt.Errorf("task %q has no uuid", tw.Description)
+		}
+		item, err := tw.toImported(projects, defaultStatus)
+		if err != nil {
+			return nil, err
+		}
+		if ours[tw.UUID] {
+			delete(item.meta.Fields, "source")
+			delete(item.meta.Fields, "source_id")
+		}
+		if _, exists := ids[tw.UUID]; !exists {
+			// A new task; give it its ID now so others can depend on it
+			if item.meta.ID == "" {
+				item.meta.ID = newTaskID()
+			}
+			ids[tw.UUID] = item.meta.ID
+		}
+		items = append(items, item)
+		depends = append(depends, tw.Depends)
+	}
+
+	// Dependencies on tasks that aren't here keep their UUID
+	for i := range items {
+		var refs []string
+		for _, uuid := range depends[i] {
+			if id := ids[uuid]; id != "" {
+				refs = append(refs, id)
+			} else {
+				refs = append(refs, uuid)
+			}
+		}
+		if len(refs) > 0 {
+			items[i].meta.Fields["depends"] = strings.Join(refs, ", ")
+		}
+	}
+	return items, nil
+}
+
+// toImported maps a Taskwarrior task onto task metadata and a body
+func (tw twTask) toImported(projects []ProjectConfig, defaultStatus string) (importedTask, error) {
+	item := importedTask{ref: tw.UUID, carried: standardFields}
+	meta := &item.meta
+	meta.Fields = map[string]string{}
+	if tw.TaskManagerID != "" {
+		meta.ID = tw.TaskManagerID
+	} else {
+		meta.Fields["source"] = taskwarriorSource
+		meta.Fields["source_id"] = tw.UUID
+	}
+
+	meta.Title = tw.Description
+	switch tw.Status {
+	case "completed":
+		meta.Status = "done"
+	case "deleted":
+		meta.Status = "cancelled"
+	case "waiting":
+		meta.Status = "waiting"
+	default:
+		meta.Status = defaultStatus
+		if tw.Start != "" {
+			meta.Status = "in-progress"
+		}
+	}
+	meta.Priority = map[string]string{"H": "high", "M": "medium", "L": "low"}[tw.Priority]
+	meta.Tags = tw.Tags
+
+	for _, field := range []struct {
+		value string
+		dest  *time.Time
+	}{{tw.Due, &meta.DueDate}, {tw.Entry, &meta.Created}, {tw.End, &meta.Completed}} {
+		if field.value == "" {
+			continue
+		}
+		t, err := time.Parse(twTimeFormat, field.value)
+		if err != nil {
+			return item, fmt.Errorf("task %q: invalid date %q", tw.Description, field.value)
+		}
+		*field.dest = t.Local()
+	}
+	if !isDoneStatus(meta.Status) {
+		// Deleted tasks have an end date too, but weren't completed
+		meta.Completed = time.Time{}
+	}
+
+	placeInProject(&item, projects, tw.Project)
+
+	item.body = "# " + tw.Description + "\n"
+	if len(tw.Annotations) > 0 {
+		item.body += "\n" + annotationsHeading + "\n\n"
+		for _, a := range tw.Annotations {
+			entry := a.Entry
+			if t, err := time.Parse(twTimeFormat, a.Entry); err == nil {
+				entry = t.Local().Format("2006-01-02 15:04")
+			}
+			item.body += fmt.Sprintf("- %s %s\n", entry, a.Description)
+		}
+	}
+	return item, nil
+}
+
+// exportTaskwarrior writes tasks as JSON that task import accepts
+func exportTaskwarrior(tasks []taskFile) ([]byte, error) {
+	uuids := map[string]string{}
+	for _, task := range tasks {
+		if task.metadata.ID != "" {
+			uuids[task.metadata.ID] = twUUID(task)
+		}
+	}
+
+	exported := []twTask{}
+	for _, task := range tasks {
+		meta := task.metadata
+		tw := twTask{
+			UUID:          twUUID(task),
+			Description:   displayTitle(task),
+			Status:        "pending",
+			Entry:         formatTWTime(meta.Created),
+			Due:           formatTWTime(meta.DueDate),
+			Priority:      map[string]string{"high": "H", "medium": "M", "low": "L"}[strings.ToLower(meta.Priority)],
+			Project:       task.project,
+			Tags:          meta.Tags,
+			TaskManagerID: meta.ID,
+		}
+		if tw.Entry == "" {
+			tw.Entry = formatTWTime(task.modTime)
+		}
+		if tw.Project == "" {
+			tw.Project = meta.Fields["project"]
+		}
+
+		switch {
+		case isDoneStatus(meta.Status):
+			tw.Status = "completed"
+			tw.End = formatTWTime(meta.Completed)
+			if tw.End == "" {
+				tw.End = formatTWTime(task.modTime)
+			}
+		case meta.Status == "cancelled" || meta.Status == "canceled":
+			tw.Status = "deleted"
+			tw.End = formatTWTime(task.modTime)
+		case meta.Status == "waiting":
+			// Waiting needs a date to wait until; plain pending is closest
+		case isInProgressStatus(meta.Status):
+			tw.Start = formatTWTime(startedAt(meta, task.modTime))
+		}
+
+		for _, id := range splitValues(meta.Fields["depends"]) {
+			if uuid, ok := uuids[id]; ok {
+				tw.Depends = append(tw.Depends, uuid)
+			} else if len(id) == 36 {
+				tw.Depends = append(tw.Depends, id)
+			}
+		}
+		tw.Annotations = bodyAnnotations(taskBody(task))
+		exported = append(exported, tw)
+	}
+
+	data, err := json.MarshalIndent(exported, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode tasks: %w", err)
+	}
+	return append(data, '\n'), nil
+}
+
+// startedAt returns when a task last moved to its current status, from
+// its status history, or fallback
+func startedAt(meta TaskMetadata, fallback time.Time) time.Time {
+	for i := len(meta.StatusHistory) - 1; i >= 0; i-- {
+		if change := meta.StatusHistory[i]; change.Status == meta.Status {
+			return change.At
+		}
+	}
+	return fallback
+}
+
+// bodyAnnotations reads the annotations back out of a task body's
+// Annotations section
+func bodyAnnotations(body string) []twAnnotation {
+	_, section, found := strings.Cut(body, annotationsHeading+"\n")
+	if !found {
+		return nil
+	}
+
+	var annotations []twAnnotation
+	for _, line := range strings.Split(section, "\n") {
+		if strings.HasPrefix(line, "#") {
+			break
+		}
+		match := annotationLine.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		entry, err := time.ParseInLocation("2006-01-02 15:04", match[1], time.Local)
+		if err != nil {
+			continue
+		}
+		annotations = append(annotations, twAnnotation{Entry: formatTWTime(entry), Description: match[2]})
+	}
+	return annotations
+}
+
+// formatTWTime formats a time for Taskwarrior, or "" for a zero time
+func formatTWTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(twTimeFormat)
+}
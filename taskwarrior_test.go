@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestTWDependsForms(t *testing.T) {
+	for _, test := range []struct {
+		json string
+		want twDepends
+	}{
+		{`{"depends": ["a", "b"]}`, twDepends{"a", "b"}},
+		{`{"depends": "a,b"}`, twDepends{"a", "b"}},
+		{`{"depends": " a , ,b "}`, twDepends{"a", "b"}},
+		{`{"depends": ""}`, nil},
+		{`{}`, nil},
+	} {
+		var task twTask
+		if err := json.Unmarshal([]byte(test.json), &task); err != nil {
+			t.Errorf("%s: %v", test.json, err)
+			continue
+		}
+		if !reflect.DeepEqual(task.Depends, test.want) {
+			t.Errorf("%s: depends = %q, want %q", test.json, task.Depends, test.want)
+		}
+	}
+
+	var task twTask
+	if err := json.Unmarshal([]byte(`{"depends": 3}`), &task); err == nil {
+		t.Error("a numeric depends was accepted")
+	}
+}
+
+func TestImportTaskwarriorDepends(t *testing.T) {
+	const (
+		first   = "11111111-1111-4111-8111-111111111111"
+		second  = "22222222-2222-4222-8222-222222222222"
+		unknown = "99999999-9999-4999-8999-999999999999"
+	)
+	existing := linkedTask(t, "k3x9p2", "todo")
+	ours := twUUID(existing[0])
+
+	for name, data := range map[string]string{
+		"a list": `[
+			{"uuid": "` + first + `", "description": "First", "status": "pending", "depends": ["` + second + `", "` + ours + `", "` + unknown + `"]},
+			{"uuid": "` + second + `", "description": "Second", "status": "pending"}
+		]`,
+		"a comma string, one task per line": `{"uuid": "` + first + `", "description": "First", "status": "pending", "depends": "` + second + `,` + ours + `,` + unknown + `"},
+{"uuid": "` + second + `", "description": "Second", "status": "pending"}`,
+	} {
+		items, err := importTaskwarrior([]byte(data), existing, nil, "todo")
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(items) != 2 || items[1].meta.ID == "" {
+			t.Fatalf("%s: imported %+v", name, items)
+		}
+
+		// Tasks in the same import and existing ones are referred to by
+		// ID, others keep their UUID
+		want := items[1].meta.ID + ", k3x9p2, " + unknown
+		if got := items[0].meta.Fields["depends"]; got != want {
+			t.Errorf("%s: depends = %q, want %q", name, got, want)
+		}
+		if _, ok := items[1].meta.Fields["depends"]; ok {
+			t.Errorf("%s: a task without dependencies got %q", name, items[1].meta.Fields["depends"])
+		}
+	}
+}
+
+func TestTWUUID(t *testing.T) {
+	task := linkedTask(t, "k3x9p2", "todo")[0]
+	uuid := twUUID(task)
+	if !regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-5[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(uuid) {
+		t.Errorf("twUUID = %q, not a version 5 UUID", uuid)
+	}
+
+	// Stable across exports and independent of everything but the ID
+	moved := task
+	moved.name, moved.metadata.Title = "renamed.md", "Renamed"
+	if twUUID(task) != uuid || twUUID(moved) != uuid {
+		t.Error("the UUID changed between exports")
+	}
+	other := linkedTask(t, "r7m2q4", "todo")[0]
+	if twUUID(other) == uuid {
+		t.Error("different IDs gave the same UUID")
+	}
+
+	// Tasks without an ID use their filename
+	noID := taskFile{name: "a.md"}
+	if twUUID(noID) != twUUID(taskFile{name: "a.md"}) || twUUID(noID) == twUUID(taskFile{name: "b.md"}) {
+		t.Error("tasks without an ID don't get a UUID from their filename")
+	}
+
+	// Imported tasks keep their original UUID
+	imported := taskFile{metadata: TaskMetadata{ID: "k3x9p2", Fields: map[string]string{"source": "taskwarrior", "source_id": "abc"}}}
+	if got := twUUID(imported); got != "abc" {
+		t.Errorf("twUUID of an imported task = %q, want its source_id", got)
+	}
+}
+
+func TestTaskwarriorAnnotationsRoundTrip(t *testing.T) {
+	tw := twTask{
+		UUID:        "11111111-1111-4111-8111-111111111111",
+		Description: "Pay bills",
+		Status:      "pending",
+		Annotations: []twAnnotation{
+			{Entry: "20250102T093000Z", Description: "Called the bank"},
+			{Entry: "20250103T170500Z", Description: "Waiting on a reply - maybe Monday"},
+		},
+	}
+	item, err := tw.toImported(nil, "todo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := bodyAnnotations(item.body); !reflect.DeepEqual(got, tw.Annotations) {
+		t.Errorf("annotations came back as %+v from\n%s", got, item.body)
+	}
+
+	// Notes added after the section are left out, and a body without one
+	// has no annotations
+	body := item.body + "\n## Notes\n\n- 2025-01-04 10:00 Not an annotation\n"
+	if got := bodyAnnotations(body); len(got) != 2 {
+		t.Errorf("read %d annotations, want 2", len(got))
+	}
+	if got := bodyAnnotations("# Pay bills\n\n- 2025-01-04 10:00 Just a list\n"); got != nil {
+		t.Errorf("annotations without a section: %+v", got)
+	}
+}
+
+func TestTaskwarriorReimportMatchesTasks(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "ours.md"), "---\nid: k3x9p2\ntitle: Fix login\nstatus: in-progress\npriority: high\ncreated: 2025-01-02T09:30:00Z\ndue_date: 2025-01-10\ntags:\n  - work\n---\n\n# Fix login\n\n"+annotationsHeading+"\n\n- 2025-01-02 10:00 Reproduced it\n")
+	writeFile(t, filepath.Join(dir, "theirs.md"), "---\ntitle: Dentist\nstatus: done\ncreated: 2025-01-01T08:00:00Z\ncompleted: 2025-01-05T12:00:00Z\nsource: taskwarrior\nsource_id: 22222222-2222-4222-8222-222222222222\ndepends: k3x9p2\n---\n\n# Dentist\n")
+	tasks, err := loadTasksFromDirectories([]string{dir}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := exportTaskwarrior(tasks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	items, err := importTaskwarrior(data, tasks, nil, "todo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, item := range items {
+		if item.ref == twUUID(taskFile{metadata: TaskMetadata{ID: "k3x9p2"}}) && item.meta.ID != "k3x9p2" {
+			t.Errorf("our task came back with ID %q", item.meta.ID)
+		}
+	}
+
+	steps := planImport(items, tasks, twUUID, "todo")
+	if len(steps) != 2 {
+		t.Fatalf("planned %d steps, want 2", len(steps))
+	}
+	for _, step := range steps {
+		if step.task == nil || len(step.changed) != 0 {
+			t.Errorf("re-import: %s", step.describe())
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// defaultTemplate is used when no "default" template has been defined.
+// It matches the template new tasks have always been created with,
+// plus a stable ID.
+const defaultTemplate = `---
+id: {{id}}
+title: "New Task"
+status: todo
+priority: medium
+created: {{datetime}}
+---
+
+# New Task
+
+Write your task description here...
+`
+
+// templateDirName is the folder inside a task directory that holds
+// templates which only apply to that directory
+const templateDirName = ".templates"
+
+// taskTemplate is a named template for new task files
+type taskTemplate struct {
+	name    string // Template name (the filename without .md)
+	source  string // Where it came from: "built-in", "global" or a task directory
+	content string // Raw template text with {{variables}}
+}
+
+// getTemplatesDir returns the global templates directory, which sits
+// next to the config file
+func getTemplatesDir() (string, error) {
+	configFile, err := getConfigPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(configFile), "templates"), nil
+}
+
+// loadTemplates returns the templates available when creating a task in
+// dir. Templates in dir/.templates override global ones with the same
+// name, which in turn override the built-in default.
+func loadTemplates(dir string) ([]taskTemplate, error) {
+	byName := map[string]taskTemplate{
+		"default": {name: "default", source: "built-in", content: defaultTemplate},
+	}
+
+	// Global templates first, so per-directory ones can override them
+	if globalDir, err := getTemplatesDir(); err == nil {
+		if err := readTemplatesFrom(globalDir, "global", byName); err != nil {
+			return nil, err
+		}
+	}
+
+	expandedDir, err := expandPath(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := readTemplatesFrom(filepath.Join(expandedDir, templateDirName), dir, byName); err != nil {
+		return nil, err
+	}
+
+	// Default first, then alphabetical
+	templates := make([]taskTemplate, 0, len(byName))
+	for _, t := range byName {
+		templates = append(templates, t)
+	}
+	sort.Slice(templates, func(i, j int) bool {
+		if templates[i].name == "default" || templates[j].name == "default" {
+			return templates[i].name == "default"
+		}
+		return templates[i].name < templates[j].name
+	})
+
+	return templates, nil
+}
+
+// readTemplatesFrom adds every .md file in templateDir to byName.
+// A missing directory simply means there are no templates there.
+func readTemplatesFrom(templateDir, source string, byName map[string]taskTemplate) error {
+	entries, err := os.ReadDir(templateDir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("couldn't read templates in %s: %w", templateDir, err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".md" {
+			continue
+		}
+
+		content, err := os.ReadFile(filepath.Join(templateDir, entry.Name()))
+		if err != nil {
+			return fmt.Errorf("couldn't read template %s: %w", entry.Name(), err)
+		}
+
+		name := strings.TrimSuffix(entry.Name(), ".md")
+		byName[name] = taskTemplate{name: name, source: source, content: string(content)}
+	}
+
+	return nil
+}
+
+// templateVars holds the values available to a template
+type templateVars struct {
+	id      string            // {{id}}, the new task's ID
+	title   string            // {{title}}
+	dir     string            // {{dir}}, the configured task directory
+	now     time.Time         // {{date}}, {{time}}, {{datetime}}, {{weekday}}
+	answers map[string]string // {{prompt:Name}}, answered by the user
+}
+
+// lookup resolves a single variable name. Unknown names report false
+// and are left in the output untouched.
+func (v templateVars) lookup(name string) (string, bool) {
+	switch name {
+	case "id":
+		return v.id, true
+	case "title":
+		return v.title, true
+	case "dir":
+		return v.dir, true
+	case "date":
+		return v.now.Format("2006-01-02"), true
+	case "time":
+		return v.now.Format("15:04"), true
+	case "datetime":
+		return v.now.Format(time.RFC3339), true
+	case "weekday":
+		return v.now.Weekday().String(), true
+	}
+
+	if key, ok := strings.CutPrefix(name, "env:"); ok {
+		return os.Getenv(key), true
+	}
+	if key, ok := strings.CutPrefix(name, "prompt:"); ok {
+		answer, ok := v.answers[key]
+		return answer, ok
+	}
+
+	return "", false
+}
+
+// prompts lists the fields the user has to fill in for this template,
+// in the order they first appear: "title" (if used), then each
+// {{prompt:Name}}
+func (t taskTemplate) prompts() []string {
+	var fields []string
+	hasTitle := false
+	seen := make(map[string]bool)
+
+	forEachTemplateVar(t.content, func(name string) {
+		if name == "title" {
+			hasTitle = true
+			return
+		}
+		if key, ok := strings.CutPrefix(name, "prompt:"); ok && !seen[key] {
+			seen[key] = true
+			fields = append(fields, key)
+		}
+	})
+
+	// Always ask for the title first
+	if hasTitle {
+		fields = append([]string{"title"}, fields...)
+	}
+
+	return fields
+}
+
+// forEachTemplateVar calls fn with the name of every {{variable}} in s
+func forEachTemplateVar(s string, fn func(name string)) {
+	for {
+		start := strings.Index(s, "{{")
+		if start < 0 {
+			return
+		}
+		end := strings.Index(s[start:], "}}")
+		if end < 0 {
+			return
+		}
+		fn(strings.TrimSpace(s[start+2 : start+end]))
+		s = s[start+end+2:]
+	}
+}
+
+// expand fills in the template's variables. Inside the frontmatter,
+// values are escaped so that they always produce valid YAML.
+func (t taskTemplate) expand(vars templateVars) string {
+	lines := strings.SplitAfter(t.content, "\n")
+	inFrontmatter := false
+
+	for i, line := range lines {
+		trimmed := strings.TrimRight(line, "\r\n")
+		if trimmed == "---" && (i == 0 || inFrontmatter) {
+			inFrontmatter = i == 0
+			continue
+		}
+		lines[i] = expandLine(line, vars, inFrontmatter)
+	}
+
+	return strings.Join(lines, "")
+}
+
+// expandLine replaces the variables on a single line
+func expandLine(line string, vars templateVars, yamlContext bool) string {
+	var out strings.Builder
+	quotes := 0 // Double quotes seen so far in the template text itself
+
+	for {
+		start := strings.Index(line, "{{")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(line[start:], "}}")
+		if end < 0 {
+			break
+		}
+
+		name := strings.TrimSpace(line[start+2 : start+end])
+		value, ok := vars.lookup(name)
+
+		out.WriteString(line[:start])
+		quotes += strings.Count(line[:start], `"`)
+
+		switch {
+		case !ok:
+			// Leave unknown variables as they were
+			out.WriteString(line[start : start+end+2])
+		case !yamlContext || !isUserValue(name):
+			out.WriteString(value)
+		case quotes%2 == 1:
+			// Inside a double-quoted YAML string
+			out.WriteString(strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value))
+		default:
+			out.WriteString(yamlScalar(value))
+		}
+
+		line = line[start+end+2:]
+	}
+
+	out.WriteString(line)
+	return out.String()
+}
+
+// isUserValue reports whether a variable holds free text (typed by the
+// user or taken from the environment) that may need escaping in YAML
+func isUserValue(name string) bool {
+	return name == "title" || name == "dir" ||
+		strings.HasPrefix(name, "prompt:") || strings.HasPrefix(name, "env:")
+}
+
+// yamlScalar returns value as a YAML scalar, quoted only when needed
+func yamlScalar(value string) string {
+	out, err := yaml.Marshal(value)
+	if err != nil {
+		return value
+	}
+	return strings.TrimSuffix(string(out), "\n")
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+// ThemeConfig holds color settings
+type ThemeConfig struct {
+	Name   string            `toml:"name"`   // auto (default), dark, light, high-contrast or solarized
+	Colors string            `toml:"colors"` // auto (default), truecolor, 256, 16 or none
+	Styles map[string]string `toml:"styles"` // Colors for individual elements, e.g. cursor = "#ffaf00"
+}
+
+// themeColors maps each themable element to a color
+type themeColors map[string]string
+
+// Built-in themes. dark matches the original colors.
+var builtinThemes = map[string]themeColors{
+	"dark": {
+		"border":             "250",
+		"box_title":          "250",
+		"search_prefix":      "15",
+		"title":              "15",
+		"header":             "250",
+		"cursor":             "226",
+		"status_todo":        "245",
+		"status_in_progress": "214",
+		"status_done":        "78",
+		"priority_high":      "196",
+		"priority_medium":    "214",
+		"priority_low":       "245",
+		"error":              "196",
+		"help_key":           "252",
+		"help_desc":          "250",
+		"footer":             "243",
+		"dim":                "240",
+		"diff_add":           "78",
+		"diff_remove":        "196",
+		"diff_hunk":          "39",
+	},
+	"light": {
+		"border":             "244",
+		"box_title":          "240",
+		"search_prefix":      "0",
+		"title":              "0",
+		"header":             "238",
+		"cursor":             "166",
+		"status_todo":        "243",
+		"status_in_progress": "166",
+		"status_done":        "28",
+		"priority_high":      "160",
+		"priority_medium":    "166",
+		"priority_low":       "243",
+		"error":              "160",
+		"help_key":           "235",
+		"help_desc":          "238",
+		"footer":             "242",
+		"dim":                "246",
+		"diff_add":           "28",
+		"diff_remove":        "160",
+		"diff_hunk":          "25",
+	},
+	"high-contrast": {
+		"border":             "15",
+		"box_title":          "15",
+		"search_prefix":      "15",
+		"title":              "15",
+		"header":             "15",
+		"cursor":             "11",
+		"status_todo":        "15",
+		"status_in_progress": "11",
+		"status_done":        "10",
+		"priority_high":      "9",
+		"priority_medium":    "11",
+		"priority_low":       "15",
+		"error":              "9",
+		"help_key":           "15",
+		"help_desc":          "15",
+		"footer":             "15",
+		"dim":                "7",
+		"diff_add":           "10",
+		"diff_remove":        "9",
+		"diff_hunk":          "14",
+	},
+	"solarized": {
+		"border":             "#586e75",
+		"box_title":          "#93a1a1",
+		"search_prefix":      "#93a1a1",
+		"title":              "#93a1a1",
+		"header":             "#839496",
+		"cursor":             "#b58900",
+		"status_todo":        "#839496",
+		"status_in_progress": "#cb4b16",
+		"status_done":        "#859900",
+		"priority_high":      "#dc322f",
+		"priority_medium":    "#cb4b16",
+		"priority_low":       "#586e75",
+		"error":              "#dc322f",
+		"help_key":           "#93a1a1",
+		"help_desc":          "#839496",
+		"footer":             "#586e75",
+		"dim":                "#586e75",
+		"diff_add":           "#859900",
+		"diff_remove":        "#dc322f",
+		"diff_hunk":          "#268bd2",
+	},
+}
+
+// Color profiles that can be forced with colors
+var colorProfiles = map[string]termenv.Profile{
+	"truecolor": termenv.TrueColor,
+	"256":       termenv.ANSI256,
+	"16":        termenv.ANSI,
+	"none":      termenv.Ascii,
+}
+
+// resolveTheme returns the colors of the configured theme with the
+// per-element overrides applied. The auto theme is dark or light
+// depending on the terminal's background.
+func resolveTheme(cfg ThemeConfig, darkBackground bool) (themeColors, error) {
+	name := strings.ToLower(cfg.Name)
+	if name == "" || name == "auto" {
+		name = "light"
+		if darkBackground {
+			name = "dark"
+		}
+	}
+
+	base, ok := builtinThemes[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown theme %q (use auto, %s)", cfg.Name, strings.Join(themeNames(), ", "))
+	}
+
+	colors := themeColors{}
+	for element, color := range base {
+		colors[element] = color
+	}
+	for element, color := range cfg.Styles {
+		if _, ok := base[element]; !ok {
+			return nil, fmt.Errorf("unknown theme element %q", element)
+		}
+		colors[element] = color
+	}
+	return colors, nil
+}
+
+// themeNames lists the built-in themes alphabetically
+func themeNames() []string {
+	var names []string
+	for name := range builtinThemes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// applyTheme sets the color profile and restyles every UI element from
+// the theme. NO_COLOR turns colors off whatever the config says. On an
+// error the original colors are kept.
+func applyTheme(cfg ThemeConfig) error {
+	if os.Getenv("NO_COLOR") != "" {
+		lipgloss.SetColorProfile(termenv.Ascii)
+	} else if cfg.Colors != "" && cfg.Colors != "auto" {
+		profile, ok := colorProfiles[strings.ToLower(cfg.Colors)]
+		if !ok {
+			return fmt.Errorf("unknown colors %q (use auto, truecolor, 256, 16 or none)", cfg.Colors)
+		}
+		lipgloss.SetColorProfile(profile)
+	}
+
+	// Only ask the terminal for its background when the theme depends on it
+	dark := true
+	if name := strings.ToLower(cfg.Name); name == "" || name == "auto" {
+		dark = lipgloss.HasDarkBackground()
+	}
+	colors, err := resolveTheme(cfg, dark)
+	if err != nil {
+		return err
+	}
+
+	c := func(element string) lipgloss.Color { return lipgloss.Color(colors[element]) }
+	mainBoxStyle = mainBoxStyle.BorderForeground(c("border"))
+	dirBoxStyle = dirBoxStyle.BorderForeground(c("border"))
+	searchBoxStyle = searchBoxStyle.BorderForeground(c("border"))
+	boxTitleStyle = boxTitleStyle.Foreground(c("box_title"))
+	searchPrefixStyle = searchPrefixStyle.Foreground(c("search_prefix"))
+	titleStyle = titleStyle.Foreground(c("title"))
+	headerStyle = headerStyle.Foreground(c("header"))
+	cursorStyle = cursorStyle.Foreground(c("cursor"))
+	statusTodoStyle = statusTodoStyle.Foreground(c("status_todo"))
+	statusInProgressStyle = statusInProgressStyle.Foreground(c("status_in_progress"))
+	statusDoneStyle = statusDoneStyle.Foreground(c("status_done"))
+	priorityHighStyle = priorityHighStyle.Foreground(c("priority_high"))
+	priorityMediumStyle = priorityMediumStyle.Foreground(c("priority_medium"))
+	priorityLowStyle = priorityLowStyle.Foreground(c("priority_low"))
+	errorStyle = errorStyle.Foreground(c("error"))
+	helpKeyStyle = helpKeyStyle.Foreground(c("help_key"))
+	helpDescStyle = helpDescStyle.Foreground(c("help_desc"))
+	footerStyle = footerStyle.Foreground(c("footer"))
+	dimStyle = dimStyle.Foreground(c("dim"))
+	diffAddStyle = diffAddStyle.Foreground(c("diff_add"))
+	diffRemoveStyle = diffRemoveStyle.Foreground(c("diff_remove"))
+	diffHunkStyle = diffHunkStyle.Foreground(c("diff_hunk"))
+	return nil
+}
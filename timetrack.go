@@ -0,0 +1,260 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// timeEntry is one stretch of time spent on a task, stored in the
+// task's time_log frontmatter field
+type timeEntry struct {
+	Start time.Time `yaml:"start"`
+	End   time.Time `yaml:"end"`
+}
+
+// activeTimer is the running timer, kept in the state file so it
+// survives restarts. Only one task can be timed at once.
+type activeTimer struct {
+	Path  string    `json:"path"`         // Task file being timed
+	ID    string    `json:"id,omitempty"` // Its ID, to find it again if it was moved
+	Title string    `json:"title"`        // Its title, for the indicator
+	Start time.Time `json:"start"`        // When the timer was started
+}
+
+// Time report groupings
+var timeReportGroups = []string{"task", "tag", "dir", "day"}
+
+// timeSpent returns the total time logged on a task
+func (meta TaskMetadata) timeSpent() time.Duration {
+	var total time.Duration
+	for _, entry := range meta.TimeLog {
+		total += entry.End.Sub(entry.Start)
+	}
+	return total
+}
+
+// startTimer starts timing a task, stopping any other running timer
+// first (its time is logged as usual). It returns the timer that was
+// stopped, if any.
+func startTimer(taskPath string, dirs []string) (*activeTimer, error) {
+	state := loadState()
+	if state.Timer != nil && state.Timer.Path == taskPath {
+		return nil, fmt.Errorf("the timer is already running for this task")
+	}
+
+	meta, err := parseFrontmatter(taskPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var stopped *activeTimer
+	if state.Timer != nil {
+		previous, _, err := stopTimer(dirs)
+		if err != nil {
+			return nil, err
+		}
+		stopped = &previous
+	}
+
+	title := meta.Title
+	if title == "" {
+		title = strings.TrimSuffix(filepath.Base(taskPath), ".md")
+	}
+
+	state = loadState()
+	state.Timer = &activeTimer{
+		Path:  taskPath,
+		ID:    meta.ID,
+		Title: title,
+		Start: time.Now().Truncate(time.Second),
+	}
+	return stopped, saveState(state)
+}
+
+// stopTimer stops the running timer and appends the time to its task.
+// It returns the stopped timer and the path the entry was written to.
+func stopTimer(dirs []string) (activeTimer, string, error) {
+	state := loadState()
+	if state.Timer == nil {
+		return activeTimer{}, "", fmt.Errorf("no timer is running")
+	}
+	timer := *state.Timer
+
+	taskPath, err := findTimedTask(timer, dirs)
+	if err != nil {
+		// The task is gone, so there's nowhere to log the time
+		state.Timer = nil
+		_ = saveState(state)
+		return timer, "", err
+	}
+
+	entry := timeEntry{Start: timer.Start, End: time.Now().Truncate(time.Second)}
+	if err := appendTimeEntry(taskPath, entry); err != nil {
+		return timer, "", err
+	}
+
+	state.Timer = nil
+	return timer, taskPath, saveState(state)
+}
+
+// discardTimer stops the running timer without logging its time, for
+// when its task is deleted
+func discardTimer() error {
+	state := loadState()
+	state.Timer = nil
+	return saveState(state)
+}
+
+// findTimedTask locates the file a timer belongs to. If the task was
+// moved or renamed since the timer started, it's found by its ID.
+func findTimedTask(timer activeTimer, dirs []string) (string, error) {
+	if _, err := os.Stat(timer.Path); err == nil {
+		return timer.Path, nil
+	}
+
+	if timer.ID != "" {
+		for _, dir := range dirs {
+			expandedDir, err := expandPath(dir)
+			if err != nil {
+				continue
+			}
+			entries, err := os.ReadDir(expandedDir)
+			if err != nil {
+				continue
+			}
+			for _, entry := range entries {
+				if entry.IsDir() || filepath.Ext(entry.Name()) != ".md" {
+					continue
+				}
+				fullPath := filepath.Join(expandedDir, entry.Name())
+				if meta, _ := parseFrontmatter(fullPath); meta.ID == timer.ID {
+					return fullPath, nil
+				}
+			}
+		}
+	}
+
+	return "", fmt.Errorf("couldn't find the timed task %q anymore", timer.Title)
+}
+
+// appendTimeEntry adds an entry to a task's time_log
+func appendTimeEntry(taskPath string, entry timeEntry) error {
+	return updateFrontmatter(taskPath, func(fields *yaml.MapSlice) {
+		var log []interface{}
+		if existing, ok := getField(*fields, "time_log"); ok {
+			log, _ = existing.([]interface{})
+		}
+		log = append(log, yaml.MapSlice{
+			{Key: "start", Value: entry.Start},
+			{Key: "end", Value: entry.End},
+		})
+		setField(fields, "time_log", log)
+	})
+}
+
+// timeReportRow is one line of a time report
+type timeReportRow struct {
+	label string
+	total time.Duration
+}
+
+// timeReport totals the time logged on tasks since the given time
+// (zero for all time), grouped by task, tag, dir or day. Days are listed
+// in date order, everything else by most time first. Time on a task
+// with several tags counts towards each of them.
+func timeReport(tasks []taskFile, by string, since time.Time) ([]timeReportRow, error) {
+	totals := make(map[string]time.Duration)
+
+	for _, task := range tasks {
+		for _, entry := range task.metadata.TimeLog {
+			if entry.Start.Before(since) {
+				continue
+			}
+			spent := entry.End.Sub(entry.Start)
+
+			switch by {
+			case "task":
+				label := task.metadata.Title
+				if label == "" {
+					label = task.name
+				}
+				totals[label] += spent
+			case "tag":
+				if len(task.metadata.Tags) == 0 {
+					totals["(untagged)"] += spent
+				}
+				for _, tag := range task.metadata.Tags {
+					totals["#"+tag] += spent
+				}
+			case "dir":
+				totals[task.sourceDir] += spent
+			case "day":
+				totals[entry.Start.Local().Format("2006-01-02 Mon")] += spent
+			default:
+				return nil, fmt.Errorf("unknown grouping %q (use %s)", by, strings.Join(timeReportGroups, ", "))
+			}
+		}
+	}
+
+	rows := make([]timeReportRow, 0, len(totals))
+	for label, total := range totals {
+		rows = append(rows, timeReportRow{label: label, total: total})
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if by == "day" || rows[i].total == rows[j].total {
+			return rows[i].label < rows[j].label
+		}
+		return rows[i].total > rows[j].total
+	})
+	return rows, nil
+}
+
+// parseSince understands the --since values of the time report:
+// today, week (since Monday), month, a number of days back like 7d,
+// or a date like 2006-01-02
+func parseSince(s string, now time.Time) (time.Time, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	switch strings.ToLower(s) {
+	case "":
+		return time.Time{}, nil
+	case "today":
+		return today, nil
+	case "week":
+		return today.AddDate(0, 0, -(int(today.Weekday())+6)%7), nil
+	case "month":
+		return today.AddDate(0, 0, 1-today.Day()), nil
+	}
+
+	if days, ok := strings.CutSuffix(strings.ToLower(s), "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return today.AddDate(0, 0, -n), nil
+		}
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, now.Location()); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("can't understand %q (use today, week, month, 7d or 2006-01-02)", s)
+}
+
+// formatDuration formats a duration as hours and minutes, e.g. "1h05m"
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Minute)
+	if d < time.Hour {
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	}
+	return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+}
+
+// formatElapsed formats a running timer's elapsed time, e.g. "1:05:09"
+func formatElapsed(d time.Duration) string {
+	d = d.Truncate(time.Second)
+	return fmt.Sprintf("%d:%02d:%02d", int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60)
+}
@@ -0,0 +1,356 @@
+package main
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// todoPriority matches a todo.txt priority such as "(A)"
+var todoPriority = regexp.MustCompile(`^\(([A-Z])\)$`)
+
+// todoLine is one task in a todo.txt file
+type todoLine struct {
+	meta    TaskMetadata
+	project string // Code of the configured project named with +project
+}
+
+// parseTodoLine reads a todo.txt line. @contexts become tags, +project
+// sets the project if one is configured with that code or name, and the
+// due:, id:, status: and pri: (priority of a done task) keys are read.
+// Other +projects and key:value pairs stay in the title.
+func parseTodoLine(line string, projects []ProjectConfig, defaultStatus string) todoLine {
+	var t todoLine
+	meta := &t.meta
+	words := strings.Fields(line)
+
+	// x [completed] [created] for done tasks, (A) [created] for others
+	if len(words) > 0 && words[0] == "x" {
+		meta.Status = "done"
+		words = words[1:]
+		if date, ok := parseTodoDate(words); ok {
+			meta.Completed, words = date, words[1:]
+		}
+	} else if len(words) > 0 && todoPriority.MatchString(words[0]) {
+		meta.Priority = taskPriorityFromTodo(words[0][1])
+		words = words[1:]
+	}
+	if date, ok := parseTodoDate(words); ok {
+		meta.Created, words = date, words[1:]
+	}
+
+	var title []string
+	for _, word := range words {
+		key, value, isKey := strings.Cut(word, ":")
+		switch {
+		case strings.HasPrefix(word, "@") && len(word) > 1:
+			if tag := word[1:]; !containsFold(meta.Tags, tag) {
+				meta.Tags = append(meta.Tags, tag)
+			}
+			continue
+		case strings.HasPrefix(word, "+") && len(word) > 1:
+			if project, ok := findProject(projects, word[1:]); ok && t.project == "" {
+				t.project = project.GetCode()
+				continue
+			}
+		case isKey && key == "due":
+			if due, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+				meta.DueDate = due
+				continue
+			}
+		case isKey && key == "id" && value != "":
+			meta.ID = value
+			continue
+		case isKey && key == "status" && value != "" && meta.Status == "":
+			meta.Status = value
+			continue
+		case isKey && key == "pri" && len(value) == 1:
+			meta.Priority = taskPriorityFromTodo(value[0])
+			continue
+		}
+		title = append(title, word)
+	}
+	meta.Title = strings.Join(title, " ")
+	if meta.Status == "" {
+		meta.Status = defaultStatus
+	}
+	return t
+}
+
+// parseTodoDate reads a leading YYYY-MM-DD date from words
+func parseTodoDate(words []string) (time.Time, bool) {
+	if len(words) == 0 {
+		return time.Time{}, false
+	}
+	date, err := time.ParseInLocation("2006-01-02", words[0], time.Local)
+	return date, err == nil
+}
+
+// formatTodoLine writes a task as a todo.txt line
+func formatTodoLine(meta TaskMetadata, project, defaultStatus string) string {
+	var words []string
+	done := isDoneStatus(meta.Status)
+	if done {
+		words = append(words, "x")
+		// A creation date alone would be read as the completion date
+		if !meta.Completed.IsZero() {
+			words = append(words, meta.Completed.Format("2006-01-02"))
+			if !meta.Created.IsZero() {
+				words = append(words, meta.Created.Format("2006-01-02"))
+			}
+		}
+	} else {
+		if priority := todoPriorityLetter(meta.Priority); priority != "" {
+			words = append(words, "("+priority+")")
+		}
+		if !meta.Created.IsZero() {
+			words = append(words, meta.Created.Format("2006-01-02"))
+		}
+	}
+
+	words = append(words, strings.Fields(meta.Title)...)
+	if project != "" {
+		words = append(words, "+"+project)
+	}
+	for _, tag := range meta.Tags {
+		words = append(words, "@"+tag)
+	}
+	if !meta.DueDate.IsZero() {
+		words = append(words, "due:"+meta.DueDate.Format("2006-01-02"))
+	}
+	if status := meta.Status; !done && status != "" && status != defaultStatus {
+		words = append(words, "status:"+status)
+	}
+	if priority := todoPriorityLetter(meta.Priority); done && priority != "" {
+		words = append(words, "pri:"+priority)
+	}
+	if meta.ID != "" {
+		words = append(words, "id:"+meta.ID)
+	}
+	return strings.Join(words, " ")
+}
+
+// todoPriorityLetter maps a task priority to a todo.txt priority
+func todoPriorityLetter(priority string) string {
+	switch strings.ToLower(priority) {
+	case "high":
+		return "A"
+	case "medium":
+		return "B"
+	case "low":
+		return "C"
+	}
+	return ""
+}
+
+// taskPriorityFromTodo maps a todo.txt priority to a task priority;
+// anything below C is low
+func taskPriorityFromTodo(letter byte) string {
+	switch letter {
+	case 'A':
+		return "high"
+	case 'B':
+		return "medium"
+	}
+	return "low"
+}
+
+// todoLineID returns a stable ID for a line without an id: key, built
+// from its title, so importing the same file twice matches it again
+func todoLineID(title string) string {
+	sum := sha1.Sum([]byte(strings.ToLower(title)))
+	id := new(big.Int).SetBytes(sum[:]).Text(36)
+	return id[:6]
+}
+
+// exportTodoTxt writes tasks as a todo.txt file
+func exportTodoTxt(tasks []taskFile, defaultStatus string) []byte {
+	var b strings.Builder
+	for _, task := range tasks {
+		meta := task.metadata
+		meta.Title = displayTitle(task)
+		b.WriteString(formatTodoLine(meta, task.project, defaultStatus) + "\n")
+	}
+	return []byte(b.String())
+}
+
+// importTodoTxt reads a todo.txt file as tasks, matched to existing tasks
+// by their id: keys. New tasks in a project go into its directory.
+func importTodoTxt(data []byte, projects []ProjectConfig, defaultStatus string) []importedTask {
+	var items []importedTask
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		items = append(items, todoImportItem(parseTodoLine(line, projects, defaultStatus), projects))
+	}
+	return items
+}
+
+// todoImportItem turns a parsed line into a task to import
+func todoImportItem(t todoLine, projects []ProjectConfig) importedTask {
+	if t.meta.ID == "" {
+		t.meta.ID = todoLineID(t.meta.Title)
+	}
+	item := importedTask{ref: t.meta.ID, meta: t.meta, body: "# " + t.meta.Title + "\n", carried: standardFields}
+	if project, ok := findProject(projects, t.project); ok {
+		item.dir = project.Path
+	}
+	return item
+}
+
+// todoSync is the plan for reconciling a todo.txt file with the tasks
+type todoSync struct {
+	lines     []string          // New contents of the file
+	base      map[string]string // Lines as of this sync, by task ID
+	steps     []importStep      // Tasks to create or update from the file
+	newIDs    map[string]string // IDs to write into tasks that have none, by path
+	report    []string          // What the sync does, one line per change
+	conflicts []string          // Tasks changed on both sides, left alone
+}
+
+// planTodoSync reconciles a todo.txt file with the tasks, line by line,
+// using base (the lines written by the last sync, by task ID) to tell
+// which side changed. Changes on one side are copied to the other; tasks
+// changed on both sides are reported as conflicts and left alone. Open
+// tasks new on either side are added to the other, done tasks are only
+// synced once they're in the file, and lines of tasks deleted here are
+// removed. Tasks are never deleted.
+func planTodoSync(content string, tasks []taskFile, base map[string]string, projects []ProjectConfig, defaultStatus string) todoSync {
+	plan := todoSync{base: map[string]string{}, newIDs: map[string]string{}}
+
+	byID := map[string]*taskFile{}
+	for i := range tasks {
+		if id := tasks[i].metadata.ID; id != "" {
+			byID[id] = &tasks[i]
+		}
+	}
+	local := func(task *taskFile) string {
+		meta := task.metadata
+		meta.Title = displayTitle(*task)
+		return formatTodoLine(meta, task.project, defaultStatus)
+	}
+
+	// Lines in the file, in order
+	seen := map[string]bool{}
+	for _, raw := range strings.Split(content, "\n") {
+		if strings.TrimSpace(raw) == "" {
+			continue
+		}
+		parsed := parseTodoLine(raw, projects, defaultStatus)
+		if parsed.meta.ID == "" || seen[parsed.meta.ID] {
+			parsed.meta.ID = newTaskID()
+			raw = formatTodoLine(parsed.meta, parsed.project, defaultStatus)
+		}
+		id := parsed.meta.ID
+		seen[id] = true
+		line := formatTodoLine(parsed.meta, parsed.project, defaultStatus)
+		before, synced := base[id]
+		task := byID[id]
+
+		switch {
+		case task == nil && synced && line == before:
+			plan.report = append(plan.report, "remove  "+parsed.meta.Title+" (deleted here)")
+
+		case task == nil:
+			// Date the new task now, so its line doesn't change next time
+			if parsed.meta.Created.IsZero() {
+				now := time.Now()
+				parsed.meta.Created = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+				raw = formatTodoLine(parsed.meta, parsed.project, defaultStatus)
+				line = raw
+			}
+			plan.steps = append(plan.steps, importStep{item: todoImportItem(parsed, projects)})
+			plan.report = append(plan.report, "create  "+parsed.meta.Title)
+			plan.lines = append(plan.lines, raw)
+			plan.base[id] = line
+
+		case line == local(task):
+			plan.lines = append(plan.lines, raw)
+			plan.base[id] = line
+
+		case synced && local(task) == before:
+			// Changed in the file only
+			current := task.metadata
+			if current.Status == "" {
+				current.Status = defaultStatus
+			}
+			step := importStep{item: todoImportItem(parsed, projects), task: task}
+			step.changed = changedFields(current, step.item)
+			plan.steps = append(plan.steps, step)
+			plan.report = append(plan.report, step.describe())
+			plan.lines = append(plan.lines, raw)
+			plan.base[id] = line
+
+		case synced && line == before:
+			// Changed here only
+			plan.lines = append(plan.lines, local(task))
+			plan.report = append(plan.report, "write   "+parsed.meta.Title)
+			plan.base[id] = local(task)
+
+		default:
+			plan.conflicts = append(plan.conflicts, fmt.Sprintf("%s: todo.txt has %q, %s has %q", parsed.meta.Title, line, task.name, local(task)))
+			plan.lines = append(plan.lines, raw)
+			if synced {
+				plan.base[id] = before
+			}
+		}
+	}
+
+	// Tasks that aren't in the file
+	for i := range tasks {
+		task := &tasks[i]
+		id := task.metadata.ID
+		if seen[id] && id != "" {
+			continue
+		}
+		before, synced := base[id]
+		switch {
+		case id != "" && synced && local(task) == before:
+			if !isDoneStatus(task.metadata.Status) {
+				plan.conflicts = append(plan.conflicts, fmt.Sprintf("%s: removed from todo.txt but still open in %s", displayTitle(*task), task.name))
+				plan.base[id] = before
+			}
+		case id != "" && synced:
+			plan.conflicts = append(plan.conflicts, fmt.Sprintf("%s: removed from todo.txt but changed in %s", displayTitle(*task), task.name))
+			plan.base[id] = before
+		case isDoneStatus(task.metadata.Status):
+			// Done before it was ever synced
+		default:
+			if id == "" {
+				id = newTaskID()
+				task.metadata.ID = id
+				plan.newIDs[task.fullPath] = id
+			}
+			plan.lines = append(plan.lines, local(task))
+			plan.report = append(plan.report, "add     "+displayTitle(*task))
+			plan.base[id] = local(task)
+		}
+	}
+	return plan
+}
+
+// applyTodoSync makes the changes of a sync plan to the tasks. New tasks
+// go into dir. It returns the paths of every task file written; the
+// todo.txt file itself is written by the caller.
+func applyTodoSync(plan todoSync, dir, pattern string) ([]string, error) {
+	var paths []string
+	for _, path := range sortedKeys(plan.newIDs) {
+		id := plan.newIDs[path]
+		err := updateFrontmatter(path, func(fields *yaml.MapSlice) {
+			setField(fields, "id", id)
+		})
+		if err != nil {
+			return paths, fmt.Errorf("failed to add an id to %s: %w", path, err)
+		}
+		paths = append(paths, path)
+	}
+
+	written, err := applyImport(plan.steps, dir, pattern)
+	return append(paths, written...), err
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var todoProjects = []ProjectConfig{{Name: "Website", Code: "WEB", Path: "~/proj/web"}}
+
+func TestTodoLineRoundTrip(t *testing.T) {
+	for _, line := range []string{
+		"Buy milk",
+		"(A) 2025-01-02 Call the bank +WEB @phone due:2025-01-10 id:k3x9p2",
+		"(C) Write report +other key:value @work status:in-progress",
+		"x 2025-01-05 2025-01-02 Pay bills @home due:2025-01-04 pri:A id:abc123",
+		"x 2025-01-05 Ship it pri:B",
+		"x Done without dates",
+	} {
+		parsed := parseTodoLine(line, todoProjects, "todo")
+		if got := formatTodoLine(parsed.meta, parsed.project, "todo"); got != line {
+			t.Errorf("round trip of %q gave %q", line, got)
+		}
+	}
+}
+
+func TestParseTodoLine(t *testing.T) {
+	date := func(s string) time.Time {
+		d, _ := time.ParseInLocation("2006-01-02", s, time.Local)
+		return d
+	}
+	for _, test := range []struct {
+		line    string
+		want    TaskMetadata
+		project string
+	}{
+		{
+			line:    "(A) 2025-01-02 Call the bank +website @phone @phone due:2025-01-10 id:k3x9p2",
+			want:    TaskMetadata{Title: "Call the bank", Status: "todo", Priority: "high", Created: date("2025-01-02"), DueDate: date("2025-01-10"), Tags: []string{"phone"}, ID: "k3x9p2"},
+			project: "WEB",
+		},
+		{
+			line: "x 2025-01-05 2025-01-02 Pay bills pri:B",
+			want: TaskMetadata{Title: "Pay bills", Status: "done", Priority: "medium", Completed: date("2025-01-05"), Created: date("2025-01-02")},
+		},
+		{
+			// A done task's own status: key doesn't override done, and an
+			// unknown +project and bad due date stay in the title
+			line: "x Old +nowhere due:someday status:todo",
+			want: TaskMetadata{Title: "Old +nowhere due:someday status:todo", Status: "done"},
+		},
+		{
+			line: "(E) Someday maybe status:waiting",
+			want: TaskMetadata{Title: "Someday maybe", Status: "waiting", Priority: "low"},
+		},
+	} {
+		got := parseTodoLine(test.line, todoProjects, "todo")
+		if !reflect.DeepEqual(got.meta, test.want) || got.project != test.project {
+			t.Errorf("parseTodoLine(%q) = %+v +%s, want %+v +%s", test.line, got.meta, got.project, test.want, test.project)
+		}
+	}
+}
+
+// todoTask returns a task parsed from a todo.txt line, as if read from
+// a file named after its ID
+func todoTask(line string) taskFile {
+	parsed := parseTodoLine(line, todoProjects, "todo")
+	return taskFile{name: parsed.meta.ID + ".md", fullPath: "/tasks/" + parsed.meta.ID + ".md", metadata: parsed.meta, project: parsed.project}
+}
+
+func TestPlanTodoSync(t *testing.T) {
+	const (
+		synced  = "(B) 2025-01-02 Pay bills @home id:bills1"
+		renamed = "(B) 2025-01-02 Pay all bills @home id:bills1"
+		urgent  = "(A) 2025-01-02 Pay bills @home id:bills1"
+		tagged  = "(B) 2025-01-02 Pay bills @home @money id:bills1"
+	)
+	for _, test := range []struct {
+		name      string
+		file      []string // Lines of todo.txt
+		tasks     []string // Tasks, written as their todo.txt lines
+		base      []string // Lines as of the last sync
+		lines     []string // New contents of todo.txt
+		steps     []string // What's done to the tasks, as described
+		report    []string // Other changes, by their first word
+		conflicts []string // Parts of the expected conflicts
+	}{
+		{
+			name:  "unchanged",
+			file:  []string{synced},
+			tasks: []string{synced},
+			base:  []string{synced},
+			lines: []string{synced},
+		},
+		{
+			name:  "changed in the file",
+			file:  []string{tagged},
+			tasks: []string{synced},
+			base:  []string{synced},
+			lines: []string{tagged},
+			steps: []string{"update  bills1.md (tags)"},
+		},
+		{
+			name:   "changed here",
+			file:   []string{synced},
+			tasks:  []string{urgent},
+			base:   []string{synced},
+			lines:  []string{urgent},
+			report: []string{"write"},
+		},
+		{
+			name:  "changed the same way on both sides",
+			file:  []string{urgent},
+			tasks: []string{urgent},
+			base:  []string{synced},
+			lines: []string{urgent},
+		},
+		{
+			name:      "changed on both sides",
+			file:      []string{renamed},
+			tasks:     []string{urgent},
+			base:      []string{synced},
+			lines:     []string{renamed},
+			conflicts: []string{`todo.txt has "` + renamed + `"`},
+		},
+		{
+			name:      "the same field changed differently on both sides",
+			file:      []string{"(C) 2025-01-02 Pay bills @home id:bills1"},
+			tasks:     []string{urgent},
+			base:      []string{synced},
+			lines:     []string{"(C) 2025-01-02 Pay bills @home id:bills1"},
+			conflicts: []string{"Pay bills: todo.txt has"},
+		},
+		{
+			name:      "removed from the file, changed here",
+			tasks:     []string{urgent},
+			base:      []string{synced},
+			conflicts: []string{"removed from todo.txt but changed in bills1.md"},
+		},
+		{
+			name:      "removed from the file, still open here",
+			tasks:     []string{synced},
+			base:      []string{synced},
+			conflicts: []string{"removed from todo.txt but still open"},
+		},
+		{
+			name:  "removed from the file, done here",
+			tasks: []string{"x 2025-01-05 2025-01-02 Pay bills @home pri:B id:bills1"},
+			base:  []string{"x 2025-01-05 2025-01-02 Pay bills @home pri:B id:bills1"},
+		},
+		{
+			name:   "deleted here",
+			file:   []string{synced},
+			base:   []string{synced},
+			report: []string{"remove"},
+		},
+		{
+			name:  "deleted here, changed in the file",
+			file:  []string{renamed},
+			base:  []string{synced},
+			lines: []string{renamed},
+			steps: []string{"create  Pay all bills"},
+		},
+		{
+			name:   "new here",
+			tasks:  []string{"(A) Call mom id:mom123", "x 2025-01-05 Old news id:old123"},
+			lines:  []string{"(A) Call mom id:mom123"},
+			report: []string{"add"},
+		},
+	} {
+		var tasks []taskFile
+		for _, line := range test.tasks {
+			tasks = append(tasks, todoTask(line))
+		}
+		base := map[string]string{}
+		for _, line := range test.base {
+			base[parseTodoLine(line, nil, "todo").meta.ID] = line
+		}
+
+		plan := planTodoSync(strings.Join(test.file, "\n"), tasks, base, todoProjects, "todo")
+
+		if !reflect.DeepEqual(plan.lines, test.lines) {
+			t.Errorf("%s: lines = %q, want %q", test.name, plan.lines, test.lines)
+		}
+		var steps, report []string
+		for _, step := range plan.steps {
+			steps = append(steps, step.describe())
+		}
+		for _, line := range plan.report {
+			if word, _, _ := strings.Cut(line, " "); word != "update" && word != "create" && word != "same" {
+				report = append(report, word)
+			}
+		}
+		if !reflect.DeepEqual(steps, test.steps) || !reflect.DeepEqual(report, test.report) {
+			t.Errorf("%s: steps %q and report %q, want %q and %q", test.name, steps, report, test.steps, test.report)
+		}
+		if len(plan.conflicts) != len(test.conflicts) {
+			t.Errorf("%s: conflicts = %q, want %q", test.name, plan.conflicts, test.conflicts)
+		}
+		for i := range test.conflicts {
+			if i < len(plan.conflicts) && !strings.Contains(plan.conflicts[i], test.conflicts[i]) {
+				t.Errorf("%s: conflict %q doesn't mention %q", test.name, plan.conflicts[i], test.conflicts[i])
+			}
+		}
+
+		// Conflicts keep their base, so they're reported again next time
+		if len(test.conflicts) > 0 {
+			if got := plan.base["bills1"]; got != synced {
+				t.Errorf("%s: base = %q, want the old line kept", test.name, got)
+			}
+		}
+	}
+}
+
+func TestPlanTodoSyncAssignsIDs(t *testing.T) {
+	tasks := []taskFile{{name: "call.md", fullPath: "/tasks/call.md", metadata: TaskMetadata{Title: "Call mom", Status: "todo"}}}
+	plan := planTodoSync("Buy milk\nBuy milk\n", tasks, nil, nil, "todo")
+
+	// Both copies of the line become tasks with their own IDs, and the
+	// task from here is given one
+	if len(plan.steps) != 2 || len(plan.lines) != 3 || len(plan.newIDs) != 1 {
+		t.Fatalf("steps %d, lines %q, new IDs %v", len(plan.steps), plan.lines, plan.newIDs)
+	}
+	if plan.steps[0].item.meta.ID == plan.steps[1].item.meta.ID {
+		t.Error("duplicate lines were given the same ID")
+	}
+	for i, line := range plan.lines {
+		id := parseTodoLine(line, nil, "todo").meta.ID
+		if id == "" || plan.base[id] != line {
+			t.Errorf("line %d %q has no ID or base", i, line)
+		}
+	}
+	if !strings.HasSuffix(plan.lines[2], "id:"+plan.newIDs["/tasks/call.md"]) {
+		t.Errorf("line %q doesn't have the new ID %s", plan.lines[2], plan.newIDs["/tasks/call.md"])
+	}
+}
+
+func TestApplyTodoSync(t *testing.T) {
+	dir := t.TempDir()
+	bills := filepath.Join(dir, "bills.md")
+	call := filepath.Join(dir, "call.md")
+	writeFile(t, bills, "---\nid: bills1\ntitle: Pay bills\nstatus: todo\npriority: medium\ncreated: 2025-01-02\ntags:\n  - home\n---\n\n# Pay bills\n\nNotes stay.\n")
+	writeFile(t, call, "---\ntitle: Call mom\nstatus: todo\n---\n\n# Call mom\n")
+	tasks, err := loadTasksFromDirectories([]string{dir}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := map[string]string{"bills1": "(B) 2025-01-02 Pay bills @home id:bills1"}
+	file := "x 2025-01-06 2025-01-02 Pay bills @home pri:B id:bills1\n(C) Buy milk\n"
+	plan := planTodoSync(file, tasks, base, nil, "todo")
+	paths, err := applyTodoSync(plan, dir, "{{slug}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 3 {
+		t.Errorf("wrote %v, want the ID, the update and the new task", paths)
+	}
+
+	meta, _ := parseFrontmatter(bills)
+	if meta.Status != "done" || meta.Completed.Format("2006-01-02") != "2025-01-06" || meta.Priority != "medium" {
+		t.Errorf("bills = %+v, want done on 2025-01-06", meta)
+	}
+	if content, _ := os.ReadFile(bills); !strings.Contains(string(content), "Notes stay.") {
+		t.Errorf("the body was lost:\n%s", content)
+	}
+	if meta, _ := parseFrontmatter(call); meta.ID == "" || meta.ID != plan.newIDs[call] {
+		t.Errorf("call ID = %q, want %q", meta.ID, plan.newIDs[call])
+	}
+	if meta, err := parseFrontmatter(filepath.Join(dir, "buy-milk.md")); err != nil || meta.Priority != "low" {
+		t.Errorf("new task = %+v, %v", meta, err)
+	}
+}
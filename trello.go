@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// trelloSource is the source field value of tasks imported from Trello
+const trelloSource = "trello"
+
+// trelloBoard is a Trello board as exported with Menu > Print and export >
+// Export as JSON
+type trelloBoard struct {
+	Name       string            `json:"name"`
+	Lists      []trelloList      `json:"lists"`
+	Cards      []trelloCard      `json:"cards"`
+	Checklists []trelloChecklist `json:"checklists"`
+}
+
+// trelloList is a column of a board
+type trelloList struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Closed bool   `json:"closed"` // Archived
+}
+
+// trelloCard is a card on a board
+type trelloCard struct {
+	ID          string        `json:"id"`
+	Name        string        `json:"name"`
+	Desc        string        `json:"desc"`
+	IDList      string        `json:"idList"`
+	Closed      bool          `json:"closed"` // Archived
+	Due         string        `json:"due"`
+	DueComplete bool          `json:"dueComplete"`
+	Labels      []trelloLabel `json:"labels"`
+	ShortURL    string        `json:"shortUrl"`
+}
+
+// trelloLabel is a card label; labels may have only a color
+type trelloLabel struct {
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+// trelloChecklist is a checklist on a card
+type trelloChecklist struct {
+	IDCard     string  `json:"idCard"`
+	Name       string  `json:"name"`
+	Pos        float64 `json:"pos"`
+	CheckItems []struct {
+		Name  string  `json:"name"`
+		State string  `json:"state"` // complete or incomplete
+		Pos   float64 `json:"pos"`
+	} `json:"checkItems"`
+}
+
+// importTrello reads the cards of a Trello board export. A card's list
+// decides its status (lists named like Done or Doing map to done and
+// in-progress) and is kept in a list field, and the board is its
+// project. Labels become tags, or set the priority for labels such as
+// "priority: high". Descriptions and checklists make up the body.
+// Archived cards and lists are skipped.
+func importTrello(data []byte, projects []ProjectConfig, defaultStatus string) ([]importedTask, error) {
+	var board trelloBoard
+	if err := json.Unmarshal(data, &board); err != nil {
+		return nil, fmt.Errorf("invalid Trello JSON: %w", err)
+	}
+
+	lists := map[string]trelloList{}
+	for _, list := range board.Lists {
+		lists[list.ID] = list
+	}
+	checklists := map[string][]trelloChecklist{}
+	for _, checklist := range board.Checklists {
+		checklists[checklist.IDCard] = append(checklists[checklist.IDCard], checklist)
+	}
+
+	var items []importedTask
+	for _, card := range board.Cards {
+		list := lists[card.IDList]
+		if card.Closed || list.Closed {
+			continue
+		}
+
+		// Priorities only come from labels, and cards have no completion date
+		item := importedTask{ref: card.ID, carried: []string{"title", "status", "due_date", "tags", "url", "list"}}
+		meta := &item.meta
+		meta.Title = strings.TrimSpace(card.Name)
+		meta.Fields = map[string]string{"source": trelloSource, "source_id": card.ID, "url": card.ShortURL, "list": list.Name}
+		meta.Status = statusFromName(list.Name, defaultStatus)
+		if card.DueComplete {
+			meta.Status = "done"
+		}
+		if card.Due != "" {
+			due, err := time.Parse(time.RFC3339, card.Due)
+			if err != nil {
+				return nil, fmt.Errorf("card %q: invalid due date %q", card.Name, card.Due)
+			}
+			meta.DueDate = due.Local()
+		}
+		meta.Created = trelloCreated(card.ID)
+
+		var labels []string
+		for _, label := range card.Labels {
+			if label.Name != "" {
+				labels = append(labels, label.Name)
+			} else {
+				labels = append(labels, label.Color)
+			}
+		}
+		addImportLabels(meta, labels)
+		placeInProject(&item, projects, board.Name)
+
+		item.body = "# " + meta.Title + "\n"
+		if desc := strings.TrimSpace(card.Desc); desc != "" {
+			item.body += "\n" + desc + "\n"
+		}
+		cardChecklists := checklists[card.ID]
+		sort.SliceStable(cardChecklists, func(i, j int) bool { return cardChecklists[i].Pos < cardChecklists[j].Pos })
+		for _, checklist := range cardChecklists {
+			item.body += "\n## " + checklist.Name + "\n\n"
+			checkItems := checklist.CheckItems
+			sort.SliceStable(checkItems, func(i, j int) bool { return checkItems[i].Pos < checkItems[j].Pos })
+			for _, checkItem := range checkItems {
+				box := "[ ]"
+				if checkItem.State == "complete" {
+					box = "[x]"
+				}
+				item.body += fmt.Sprintf("- %s %s\n", box, checkItem.Name)
+			}
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// trelloCreated returns when a card was created, which Trello IDs start
+// with as a hex Unix timestamp
+func trelloCreated(id string) time.Time {
+	if len(id) < 8 {
+		return time.Time{}
+	}
+	seconds, err := strconv.ParseInt(id[:8], 16, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(seconds, 0)
+}
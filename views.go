@@ -0,0 +1,402 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Ways the task list can be grouped
+var groupKeys = []string{"status", "priority", "dir", "project", "tag", "due"}
+
+// Due date buckets, in the order they are listed
+var dueBuckets = []string{"overdue", "today", "this week", "later", "earlier", "no due date"}
+
+// Columns a view can show
+var columnNames = []string{"status", "priority", "title", "git", "modified", "created", "dir", "project", "due", "tags", "id", "progress"}
+
+// taskGroup is a run of tasks shown under one heading. Ungrouped lists
+// are a single group without a name.
+type taskGroup struct {
+	name  string
+	tasks []taskFile
+}
+
+// validateView checks that a view's query, sort, grouping and columns
+// are all understood
+func validateView(view ViewConfig) error {
+	err := checkView(view)
+	if err != nil && view.Name != "" {
+		return fmt.Errorf("view %q: %w", view.Name, err)
+	}
+	return err
+}
+
+// checkView does the work of validateView
+func checkView(view ViewConfig) error {
+	if _, err := parseQuery(view.Query); err != nil {
+		return err
+	}
+	if err := sortTasks(nil, view.Sort); err != nil {
+		return err
+	}
+	if view.Group != "" && !containsFold(groupKeys, view.Group) {
+		return fmt.Errorf("unknown group %q (use %s)", view.Group, strings.Join(groupKeys, ", "))
+	}
+	for _, column := range view.Columns {
+		if err := checkColumn(column); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyView filters, sorts and groups tasks as a view describes. The
+// tasks slice is not modified.
+func applyView(tasks []taskFile, view ViewConfig, defaultStatus string, now time.Time) ([]taskGroup, error) {
+	filter, err := parseQuery(view.Query)
+	if err != nil {
+		return nil, err
+	}
+	matched := append([]taskFile{}, filter.apply(tasks, now)...)
+	if err := sortTasks(matched, view.Sort); err != nil {
+		return nil, err
+	}
+	if view.Group == "" {
+		return []taskGroup{{tasks: matched}}, nil
+	}
+	return groupTasks(matched, strings.ToLower(view.Group), defaultStatus, now), nil
+}
+
+// groupTasks splits tasks into groups by status, priority, directory,
+// first tag or due date bucket, keeping their order within each group
+func groupTasks(tasks []taskFile, by, defaultStatus string, now time.Time) []taskGroup {
+	var groups []taskGroup
+	index := map[string]int{}
+	for _, task := range tasks {
+		name := groupName(task, by, defaultStatus, now)
+		i, ok := index[name]
+		if !ok {
+			i = len(groups)
+			index[name] = i
+			groups = append(groups, taskGroup{name: name})
+		}
+		groups[i].tasks = append(groups[i].tasks, task)
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		a, b := groups[i].name, groups[j].name
+		switch by {
+		case "status":
+			if statusRank(a) != statusRank(b) {
+				return statusRank(a) < statusRank(b)
+			}
+		case "priority":
+			return priorityRank(a) < priorityRank(b)
+		case "due":
+			return indexOf(dueBuckets, a) < indexOf(dueBuckets, b)
+		case "tag":
+			if (a == "untagged") != (b == "untagged") {
+				return b == "untagged"
+			}
+		}
+		return a < b
+	})
+	return groups
+}
+
+// groupName returns the heading a task is listed under
+func groupName(task taskFile, by, defaultStatus string, now time.Time) string {
+	meta := task.metadata
+	switch by {
+	case "status":
+		if meta.Status == "" {
+			return defaultStatus
+		}
+		return meta.Status
+	case "priority":
+		if meta.Priority == "" {
+			return "none"
+		}
+		return meta.Priority
+	case "dir":
+		return task.sourceDir
+	case "project":
+		if task.project == "" {
+			return "no project"
+		}
+		return task.project
+	case "tag":
+		if len(meta.Tags) == 0 {
+			return "untagged"
+		}
+		return meta.Tags[0]
+	case "due":
+		return dueBucket(meta, now)
+	}
+	return ""
+}
+
+// dueBucket sorts a task into one of dueBuckets
+func dueBucket(meta TaskMetadata, now time.Time) string {
+	switch {
+	case meta.DueDate.IsZero():
+		return "no due date"
+	case dueMatches(meta, "overdue", now):
+		return "overdue"
+	case dueMatches(meta, "today", now):
+		return "today"
+	case dueMatches(meta, "week", now):
+		return "this week"
+	case meta.DueDate.After(now):
+		return "later"
+	}
+	return "earlier"
+}
+
+// indexOf returns the position of s in list, or len(list) if missing
+func indexOf(list []string, s string) int {
+	for i, item := range list {
+		if item == s {
+			return i
+		}
+	}
+	return len(list)
+}
+
+// flattenGroups returns the tasks of every group in order
+func flattenGroups(groups []taskGroup) []taskFile {
+	tasks := []taskFile{}
+	for _, group := range groups {
+		tasks = append(tasks, group.tasks...)
+	}
+	return tasks
+}
+
+// defaultColumns returns the columns shown when a view doesn't choose
+// its own: [display] columns if set, or else the project code when
+// projects are configured, git state only for git-backed directories and
+// the directory only when there is more than one and no projects name them
+func (m model) defaultColumns() []string {
+	if len(m.config.Columns) > 0 {
+		return m.config.Columns
+	}
+
+	columns := []string{"status", "priority"}
+	if len(m.projects) > 0 {
+		columns = append(columns, "project")
+	}
+	columns = append(columns, "title")
+	if len(m.gitRoots) > 0 {
+		columns = append(columns, "git")
+	}
+	columns = append(columns, "modified")
+	if m.showDirInfo && len(m.projects) == 0 {
+		columns = append(columns, "dir")
+	}
+	return columns
+}
+
+// renderTaskRow renders one task as a list row laid out by columnLayout
+func (m model) renderTaskRow(task taskFile, selected bool, layout []columnSpec) string {
+	cursor := " "
+	if selected {
+		cursor = cursorStyle.Render(">")
+	}
+
+	cells := []string{cursor}
+	for _, spec := range layout {
+		cells = append(cells, fitCell(m.renderCell(task, spec.name), spec))
+	}
+	return strings.Join(cells, " ")
+}
+
+// renderCell renders one column of a task's row, unpadded
+func (m model) renderCell(task taskFile, column string) string {
+	meta := task.metadata
+
+	switch column {
+	case "status":
+		// Get status, using default if not set
+		status := meta.Status
+		if status == "" {
+			status = m.config.GetDefaultStatus()
+		}
+		indicator := m.config.GetStatusIndicator(status)
+		switch strings.ToLower(status) {
+		case "done", "completed":
+			return statusDoneStyle.Render(indicator)
+		case "in-progress", "doing":
+			return statusInProgressStyle.Render(indicator)
+		}
+		return statusTodoStyle.Render(indicator)
+
+	case "priority":
+		priorityEmoji := getPriorityEmoji(meta.Priority)
+		switch strings.ToLower(meta.Priority) {
+		case "high":
+			return priorityHighStyle.Render(priorityEmoji)
+		case "medium":
+			return priorityMediumStyle.Render(priorityEmoji)
+		case "low":
+			return priorityLowStyle.Render(priorityEmoji)
+		}
+		return priorityEmoji
+
+	case "title":
+		return displayTitle(task)
+
+	case "git":
+		// Mark tasks with uncommitted changes in git-backed directories
+		switch m.gitStates[task.fullPath] {
+		case gitModified:
+			return statusInProgressStyle.Render("M")
+		case gitUntracked:
+			return statusTodoStyle.Render("?")
+		}
+		return " "
+
+	case "modified":
+		return dimStyle.Render(task.modTime.Format("2006-01-02 15:04"))
+
+	case "created":
+		if meta.Created.IsZero() {
+			return ""
+		}
+		return dimStyle.Render(meta.Created.Format("2006-01-02"))
+
+	case "dir":
+		return fmt.Sprintf("[%s]", task.sourceDir)
+
+	case "project":
+		if project, ok := findProject(m.projects, task.project); ok {
+			return project.style().Render(task.project)
+		}
+		return task.project
+
+	case "due":
+		if meta.DueDate.IsZero() {
+			return ""
+		}
+		due := meta.DueDate.Format("2006-01-02")
+		if dueMatches(meta, "overdue", time.Now()) {
+			return priorityHighStyle.Render(due)
+		}
+		return due
+
+	case "tags":
+		return m.config.renderTags(meta.Tags)
+
+	case "id":
+		return dimStyle.Render(meta.ID)
+
+	case "progress":
+		// Ticked checkboxes in the task's body
+		if meta.Checkboxes == 0 {
+			return ""
+		}
+		progress := fmt.Sprintf("%d/%d", meta.Checked, meta.Checkboxes)
+		if meta.Checked == meta.Checkboxes {
+			return statusDoneStyle.Render(progress)
+		}
+		return progress
+	}
+
+	if field, ok := strings.CutPrefix(column, fieldColumn); ok {
+		return meta.Fields[field]
+	}
+	return ""
+}
+
+// listRow is one line of the list the cursor can stop on: a task, or the
+// heading of a collapsed group. Headings of expanded groups are skipped.
+type listRow struct {
+	group  string   // Name of the group the row belongs to
+	task   taskFile // The task, unless this is a heading
+	header bool     // Whether this is a collapsed group's heading
+}
+
+// listRows returns the rows the cursor moves through, in display order
+func (m model) listRows() []listRow {
+	var rows []listRow
+	for _, group := range m.taskGroups() {
+		if group.name != "" && m.collapsed[group.name] {
+			rows = append(rows, listRow{group: group.name, header: true})
+			continue
+		}
+		for _, task := range group.tasks {
+			rows = append(rows, listRow{group: group.name, task: task})
+		}
+	}
+	return rows
+}
+
+// toggleGroup collapses the group under the cursor, or expands it if
+// it's collapsed, keeping the cursor on the group
+func (m model) toggleGroup() model {
+	rows := m.listRows()
+	if m.cursor >= len(rows) || rows[m.cursor].group == "" {
+		return m
+	}
+
+	name := rows[m.cursor].group
+	if m.collapsed == nil {
+		m.collapsed = map[string]bool{}
+	}
+	m.collapsed[name] = !m.collapsed[name]
+	m.cursor = m.groupRow(name)
+	return m
+}
+
+// toggleAllGroups collapses every group, or expands them all if they're
+// already collapsed
+func (m model) toggleAllGroups() model {
+	rows := m.listRows()
+	var current string
+	if m.cursor < len(rows) {
+		current = rows[m.cursor].group
+	}
+
+	groups := m.taskGroups()
+	collapse := false
+	for _, group := range groups {
+		if group.name != "" && !m.collapsed[group.name] {
+			collapse = true
+		}
+	}
+	m.collapsed = map[string]bool{}
+	if collapse {
+		for _, group := range groups {
+			m.collapsed[group.name] = true
+		}
+	}
+	m.cursor = m.groupRow(current)
+	return m
+}
+
+// groupRow returns the first row of a group, or 0 if it isn't listed
+func (m model) groupRow(name string) int {
+	for i, row := range m.listRows() {
+		if row.group == name {
+			return i
+		}
+	}
+	return 0
+}
+
+// cycleGrouping switches to the next way of grouping the list, ending
+// with no grouping
+func (m model) cycleGrouping() model {
+	next := 0
+	if m.groupBy != "" {
+		next = indexOf(groupKeys, m.groupBy) + 1
+	}
+	m.groupBy = ""
+	if next < len(groupKeys) {
+		m.groupBy = groupKeys[next]
+	}
+	m.collapsed = nil
+	m.cursor = 0
+	return m
+}